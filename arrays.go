@@ -0,0 +1,56 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// setArray parses a value into an array, which must receive exactly as many elements as its length. The bytes of
+// the value populate a byte array, such as a key of a fixed size. A byte array also accepts a JSON array of numbers,
+// as it did before arrays were supported, when the value does not have the length of the array. Other arrays are
+// populated from a JSON array or, if the value does not start with "[", from a comma-separated list whose elements
+// are parsed like field values.
+func setArray(rval reflect.Value, value string) error {
+	n := rval.Len()
+	elems := reflect.New(reflect.SliceOf(rval.Type().Elem())).Elem()
+	if rval.Type().Elem().Kind() == reflect.Uint8 {
+		if len(value) == n {
+			reflect.Copy(rval, reflect.ValueOf([]byte(value)))
+			return nil
+		}
+		if !strings.HasPrefix(strings.TrimSpace(value), "[") || json.Unmarshal([]byte(value), elems.Addr().Interface()) != nil {
+			return fmt.Errorf("the value has %v bytes, but the array holds %v", len(value), n)
+		}
+	} else if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		if err := json.Unmarshal([]byte(value), elems.Addr().Interface()); err != nil {
+			return err
+		}
+	} else {
+		var items []string
+		if strings.TrimSpace(value) != "" {
+			items = strings.Split(value, ",")
+		}
+		elems.Set(reflect.MakeSlice(elems.Type(), len(items), len(items)))
+		for i, item := range items {
+			if err := setValue(elems.Index(i), strings.TrimSpace(item)); err != nil {
+				return fmt.Errorf("element %v: %w", i, err)
+			}
+		}
+	}
+	if elems.Len() != n {
+		return fmt.Errorf("the value has %v elements, but the array holds %v", elems.Len(), n)
+	}
+	reflect.Copy(rval, elems)
+	return nil
+}
+
+// isByteArray returns whether a type is an array of bytes.
+func isByteArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8
+}
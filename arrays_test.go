@@ -0,0 +1,140 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/hex"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setArray(t *testing.T) {
+	var (
+		names [3]string
+		ports [2]int
+		id    [4]byte
+	)
+	tests := []struct {
+		tag      string
+		rval     reflect.Value
+		value    string
+		expected interface{}
+		err      string
+	}{
+		{"t1", reflect.ValueOf(&names), "a, b,c", [3]string{"a", "b", "c"}, ""},
+		{"t2", reflect.ValueOf(&names), `["a", "b,c", "d"]`, [3]string{"a", "b,c", "d"}, ""},
+		{"t3", reflect.ValueOf(&names), "a,b", nil, "the value has 2 elements, but the array holds 3"},
+		{"t4", reflect.ValueOf(&names), `["a","b","c","d"]`, nil, "the value has 4 elements, but the array holds 3"},
+		{"t5", reflect.ValueOf(&names), "", nil, "the value has 0 elements, but the array holds 3"},
+		{"t6", reflect.ValueOf(&ports), "80, 0x1bb", [2]int{80, 443}, ""},
+		{"t7", reflect.ValueOf(&ports), "80,x", nil, `element 1: strconv.ParseInt: parsing "x": invalid syntax`},
+		{"t8", reflect.ValueOf(&id), "abcd", [4]byte{'a', 'b', 'c', 'd'}, ""},
+		{"t9", reflect.ValueOf(&id), "abc", nil, "the value has 3 bytes, but the array holds 4"},
+		{"t10", reflect.ValueOf(&id), "[1, 2, 3, 4]", [4]byte{1, 2, 3, 4}, ""},
+		{"t11", reflect.ValueOf(&id), "[1,2,3]", nil, "the value has 3 elements, but the array holds 4"},
+		{"t12", reflect.ValueOf(&id), "[1,2,3,256]", nil, "the value has 11 bytes, but the array holds 4"},
+		{"t13", reflect.ValueOf(&id), "[12]", [4]byte{'[', '1', '2', ']'}, ""},
+	}
+	for _, test := range tests {
+		err := setValue(test.rval, test.value)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.tag)
+		} else if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, test.expected, test.rval.Elem().Interface(), test.tag)
+		}
+	}
+}
+
+func TestLoader_Load_array(t *testing.T) {
+	type config struct {
+		Key   [32]byte  `env:",hex"`
+		Token *[8]byte  `env:",base64"`
+		Hosts [2]string `default:"a,b"`
+	}
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_KEY":   hex.EncodeToString(key),
+		"APP_TOKEN": "AAECAwQFBgc=",
+	}), nil)
+	var cfg config
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, key, cfg.Key[:])
+		assert.Equal(t, &[8]byte{0, 1, 2, 3, 4, 5, 6, 7}, cfg.Token)
+		assert.Equal(t, [2]string{"a", "b"}, cfg.Hosts)
+	}
+
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{
+			"APP_KEY":   hex.EncodeToString(key),
+			"APP_TOKEN": "AAECAwQFBgc=",
+			"APP_HOSTS": `["a","b"]`,
+		}, vars)
+		var loaded config
+		assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&loaded))
+		assert.Equal(t, cfg, loaded)
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_KEY": "abcd"}), nil)
+	err = l.Load(&cfg)
+	assert.EqualError(t, err, "$APP_KEY: the value has 2 bytes, but the array holds 32")
+	assert.ErrorIs(t, err, ErrParse)
+
+	g := &codeGenerator{imports: map[string]string{}}
+	assert.Equal(t, "v := []byte(value)\nif len(v) != 32 {\nvar elems []byte\n"+
+		"if !strings.HasPrefix(strings.TrimSpace(value), \"[\") || json.Unmarshal([]byte(value), &elems) != nil {\n"+
+		"return fmt.Errorf(\"the value has %v bytes, but the array holds 32\", len(value))\n}\n"+
+		"if len(elems) != 32 {\nreturn fmt.Errorf(\"the value has %v elements, but the array holds 32\", len(elems))\n}\nv = elems\n}\ncopy((c.Key)[:], v)\n",
+		g.parse("c.Key", reflect.TypeOf(cfg.Key)))
+	assert.Equal(t, "var v []string\nif err := json.Unmarshal([]byte(value), &v); err != nil {\nreturn err\n}\n"+
+		"if len(v) != 2 {\nreturn fmt.Errorf(\"the value has %v elements, but the array holds 2\", len(v))\n}\ncopy((c.Hosts)[:], v)\n",
+		g.parse("c.Hosts", reflect.TypeOf(cfg.Hosts)))
+}
+
+func TestManifest_array(t *testing.T) {
+	var cfg struct {
+		Key   [4]byte
+		Salt  *[2]byte `env:",hex"`
+		Ports [2]int
+		URLs  [2]*url.URL
+	}
+	m, err := NewWithLookup("APP_", MapLookup(nil), nil).ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []ManifestVar{
+			{Name: "APP_KEY", Field: "Key", Type: "bytes", Length: 4},
+			{Name: "APP_SALT", Field: "Salt", Type: "hex", Length: 2},
+			{Name: "APP_PORTS", Field: "Ports", Type: "array", Elem: "int", Length: 2},
+			{Name: "APP_UR_LS", Field: "URLs", Type: "array", Elem: "url", Length: 2},
+		}, m.Vars)
+	}
+
+	assert.Nil(t, m.Validate(map[string]string{
+		"APP_KEY":   "abcd",
+		"APP_SALT":  "0a0b",
+		"APP_PORTS": "80, 443",
+		"APP_UR_LS": `["https://a.com", "https://b.com"]`,
+	}))
+	assert.Nil(t, m.Validate(map[string]string{"APP_KEY": "[1,2,3,4]", "APP_PORTS": "[80,443]", "APP_UR_LS": "https://a.com,https://b.com"}))
+
+	err = m.Validate(map[string]string{
+		"APP_KEY":   "abc",
+		"APP_SALT":  "0a",
+		"APP_PORTS": "80,x",
+		"APP_UR_LS": `["https://a.com"]`,
+	})
+	if assert.NotNil(t, err) {
+		msg := err.Error()
+		assert.Contains(t, msg, "$APP_KEY: the value has 3 bytes, but the array holds 4")
+		assert.Contains(t, msg, "$APP_SALT: the value has 1 bytes, but the array holds 2")
+		assert.Contains(t, msg, `$APP_PORTS: element 1: strconv.ParseInt: parsing "x": invalid syntax`)
+		assert.Contains(t, msg, "$APP_UR_LS: the value has 1 elements, but the array holds 2")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type (
+	// AzureKeyVaultConfig configures an Azure Key Vault lookup.
+	AzureKeyVaultConfig struct {
+		// VaultURL is the URL of the key vault (e.g. "https://myvault.vault.azure.net"). Defaults to $AZURE_KEYVAULT_URL.
+		VaultURL string
+		// Version is the secret version to read. Defaults to the current version.
+		Version string
+		// Name maps a variable name to a secret name. Defaults to AzureSecretName.
+		// Key Vault secret names may only contain alphanumeric characters and dashes.
+		Name func(name string) string
+		// Token returns the access token used to call the API. Defaults to AzureDefaultToken().
+		Token TokenFunc
+		// Client is the HTTP client used to call the API. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// AzureKeyVault looks up values from secrets stored in Azure Key Vault.
+	AzureKeyVault struct {
+		config AzureKeyVaultConfig
+		errs   lookupErrors
+	}
+)
+
+const (
+	// azureKeyVaultScope is the OAuth 2.0 scope for accessing Azure Key Vault.
+	azureKeyVaultScope = "https://vault.azure.net/.default"
+	// azureKeyVaultAPIVersion is the version of the Key Vault REST API used.
+	azureKeyVaultAPIVersion = "7.4"
+)
+
+// azureIMDSEndpoint is the token endpoint of the Azure Instance Metadata Service.
+var azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// NewAzureKeyVault creates an Azure Key Vault lookup.
+// It obtains an access token so that credential problems are reported immediately.
+//
+// Use the Lookup method as a LookupFunc, for example:
+//
+//	kv, err := env.NewAzureKeyVault(env.AzureKeyVaultConfig{VaultURL: "https://myvault.vault.azure.net"})
+//	...
+//	loader := env.New("APP_", log.Printf, env.WithSecretLookup(kv.Lookup))
+func NewAzureKeyVault(config AzureKeyVaultConfig) (*AzureKeyVault, error) {
+	if config.VaultURL == "" {
+		config.VaultURL = os.Getenv("AZURE_KEYVAULT_URL")
+	}
+	if config.VaultURL == "" {
+		return nil, errors.New("azure: vault URL is not specified")
+	}
+	config.VaultURL = strings.TrimSuffix(config.VaultURL, "/")
+	if config.Name == nil {
+		config.Name = AzureSecretName
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Token == nil {
+		config.Token = AzureDefaultToken()
+	}
+	if _, err := config.Token(context.Background()); err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+	return &AzureKeyVault{config: config}, nil
+}
+
+// AzureSecretName turns a variable name into a valid Key Vault secret name by replacing underscores with dashes.
+// For example, "APP_DB_PASSWORD" becomes "APP-DB-PASSWORD". Note that secret names are case-insensitive.
+func AzureSecretName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// Lookup returns the value of the secret corresponding to the given variable name.
+// It can be used as a LookupFunc. A secret that cannot be read is treated as not found,
+// and the failure is reported by Err.
+func (kv *AzureKeyVault) Lookup(name string) (string, bool) {
+	value, ok, err := kv.LookupContext(context.Background(), name)
+	kv.errs.record(name, err)
+	return value, ok
+}
+
+// LookupContext returns the value of the secret corresponding to the given variable name.
+// Unlike Lookup, it returns an error if the secret cannot be read for reasons other than not existing.
+func (kv *AzureKeyVault) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	secret := kv.config.Name(name)
+	token, err := kv.config.Token(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("azure: %w", err)
+	}
+
+	u := kv.config.VaultURL + "/secrets/" + url.PathEscape(secret)
+	if kv.config.Version != "" {
+		u += "/" + url.PathEscape(kv.config.Version)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?api-version="+azureKeyVaultAPIVersion, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("azure: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := kv.config.Client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("azure: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	var result struct {
+		Value string `json:"value"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil && res.StatusCode == http.StatusOK {
+		return "", false, fmt.Errorf("azure: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if result.Error.Message != "" {
+			return "", false, fmt.Errorf("azure: secret %v: %v", secret, result.Error.Message)
+		}
+		return "", false, fmt.Errorf("azure: secret %v: %v", secret, res.Status)
+	}
+	return result.Value, true, nil
+}
+
+// Err returns the failures of the most recent Lookup calls for names that could not be read,
+// or nil if every name was read successfully.
+func (kv *AzureKeyVault) Err() error {
+	return kv.errs.err()
+}
+
+// LookupStats returns the number of Lookup calls that failed to read a secret, and no cache hits, so that the
+// failures are counted in the statistics of a loader (see WithStatsReporters).
+func (kv *AzureKeyVault) LookupStats() (cacheHits, errors uint64) {
+	return kv.errs.stats()
+}
+
+// AzureDefaultToken returns a TokenFunc for Azure Key Vault that mirrors the DefaultAzureCredential chain
+// of the Azure SDK for the credentials that do not require external tools. It uses, in order:
+//   - a client secret, if $AZURE_TENANT_ID, $AZURE_CLIENT_ID and $AZURE_CLIENT_SECRET are set;
+//   - workload identity, if $AZURE_TENANT_ID, $AZURE_CLIENT_ID and $AZURE_FEDERATED_TOKEN_FILE are set;
+//   - managed identity through the Instance Metadata Service otherwise ($AZURE_CLIENT_ID selects a
+//     user-assigned identity).
+//
+// The authority host can be customized with $AZURE_AUTHORITY_HOST. Tokens are cached until they are about to expire.
+func AzureDefaultToken() TokenFunc {
+	return newCachedToken(func(ctx context.Context) (string, time.Duration, error) {
+		tenantID, clientID := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+		authority := os.Getenv("AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = "https://login.microsoftonline.com"
+		}
+		tokenURL := strings.TrimSuffix(authority, "/") + "/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token"
+
+		if secret := os.Getenv("AZURE_CLIENT_SECRET"); tenantID != "" && clientID != "" && secret != "" {
+			return requestToken(ctx, http.DefaultClient, tokenURL, url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {clientID},
+				"client_secret": {secret},
+				"scope":         {azureKeyVaultScope},
+			}, nil)
+		}
+
+		if file := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tenantID != "" && clientID != "" && file != "" {
+			assertion, err := os.ReadFile(file)
+			if err != nil {
+				return "", 0, err
+			}
+			return requestToken(ctx, http.DefaultClient, tokenURL, url.Values{
+				"grant_type":            {"client_credentials"},
+				"client_id":             {clientID},
+				"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+				"client_assertion":      {strings.TrimSpace(string(assertion))},
+				"scope":                 {azureKeyVaultScope},
+			}, nil)
+		}
+
+		query := url.Values{
+			"api-version": {"2018-02-01"},
+			"resource":    {strings.TrimSuffix(azureKeyVaultScope, "/.default")},
+		}
+		if clientID != "" {
+			query.Set("client_id", clientID)
+		}
+		return requestToken(ctx, http.DefaultClient, azureIMDSEndpoint+"?"+query.Encode(), nil,
+			http.Header{"Metadata": {"true"}})
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAzureKeyVault_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "7.4", r.URL.Query().Get("api-version"))
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/secrets/APP-PASSWORD":
+			_, _ = w.Write([]byte(`{"value":"xyz"}`))
+		case "/secrets/APP-PASSWORD/v2":
+			_, _ = w.Write([]byte(`{"value":"abc"}`))
+		case "/secrets/APP-DENIED":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":"Forbidden","message":"access denied"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kv, err := NewAzureKeyVault(AzureKeyVaultConfig{VaultURL: server.URL, Token: staticToken("t1")})
+	if !assert.Nil(t, err) {
+		return
+	}
+	value, ok := kv.Lookup("APP_PASSWORD")
+	assert.True(t, ok)
+	assert.Equal(t, "xyz", value)
+	_, ok = kv.Lookup("APP_HOST")
+	assert.False(t, ok)
+	assert.Nil(t, kv.Err())
+
+	_, ok = kv.Lookup("APP_DENIED")
+	assert.False(t, ok)
+	assert.EqualError(t, kv.Err(), "azure: secret APP-DENIED: access denied")
+	_, failures := kv.LookupStats()
+	assert.Equal(t, uint64(1), failures)
+
+	kv, _ = NewAzureKeyVault(AzureKeyVaultConfig{
+		VaultURL: server.URL,
+		Version:  "v2",
+		Token:    staticToken("t1"),
+		Name:     func(name string) string { return "APP-" + name },
+	})
+	value, _ = kv.Lookup("PASSWORD")
+	assert.Equal(t, "abc", value)
+
+	kv, _ = NewAzureKeyVault(AzureKeyVaultConfig{VaultURL: server.URL, Token: staticToken("t2")})
+	_, _, err = kv.LookupContext(context.Background(), "APP_PASSWORD")
+	assert.EqualError(t, err, "azure: secret APP-PASSWORD: 401 Unauthorized")
+
+	_, err = NewAzureKeyVault(AzureKeyVaultConfig{VaultURL: server.URL, Token: func(context.Context) (string, error) {
+		return "", errors.New("no credentials")
+	}})
+	assert.EqualError(t, err, "azure: no credentials")
+	t.Setenv("AZURE_KEYVAULT_URL", "")
+	_, err = NewAzureKeyVault(AzureKeyVaultConfig{Token: staticToken("t1")})
+	assert.NotNil(t, err)
+}
+
+func TestAzureSecretName(t *testing.T) {
+	assert.Equal(t, "APP-DB-PASSWORD", AzureSecretName("APP_DB_PASSWORD"))
+	assert.Equal(t, "HOST", AzureSecretName("HOST"))
+}
+
+func TestAzureDefaultToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/imds":
+			assert.Equal(t, "true", r.Header.Get("Metadata"))
+			assert.Equal(t, "https://vault.azure.net", r.URL.Query().Get("resource"))
+			_, _ = w.Write([]byte(`{"access_token":"mi-` + r.URL.Query().Get("client_id") + `","expires_in":"86399"}`))
+		case r.URL.Path == "/tenant1/oauth2/v2.0/token" && r.FormValue("client_secret") == "s1":
+			assert.Equal(t, azureKeyVaultScope, r.FormValue("scope"))
+			_, _ = w.Write([]byte(`{"access_token":"secret","expires_in":3599}`))
+		case r.URL.Path == "/tenant1/oauth2/v2.0/token" && r.FormValue("client_assertion") == "jwt1":
+			_, _ = w.Write([]byte(`{"access_token":"workload","expires_in":3599}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`))
+		}
+	}))
+	defer server.Close()
+
+	oldEndpoint := azureIMDSEndpoint
+	azureIMDSEndpoint = server.URL + "/imds"
+	defer func() { azureIMDSEndpoint = oldEndpoint }()
+
+	t.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	t.Setenv("AZURE_TENANT_ID", "")
+	t.Setenv("AZURE_CLIENT_ID", "")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+	token, err := AzureDefaultToken()(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "mi-", token)
+
+	t.Setenv("AZURE_CLIENT_ID", "c1")
+	token, _ = AzureDefaultToken()(context.Background())
+	assert.Equal(t, "mi-c1", token)
+
+	t.Setenv("AZURE_TENANT_ID", "tenant1")
+	t.Setenv("AZURE_CLIENT_SECRET", "s1")
+	token, err = AzureDefaultToken()(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", token)
+
+	t.Setenv("AZURE_CLIENT_SECRET", "s2")
+	_, err = AzureDefaultToken()(context.Background())
+	assert.EqualError(t, err, "token request failed: invalid_client: bad credentials")
+
+	file := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(file, []byte("jwt1\n"), 0600))
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", file)
+	token, err = AzureDefaultToken()(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "workload", token)
+
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", file+"x")
+	_, err = AzureDefaultToken()(context.Background())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BindFlags populates a struct and registers a command-line flag per field using the package-level loader.
+// For more details, please refer to Loader.BindFlags().
+func BindFlags(fs *flag.FlagSet, structPtr interface{}) error {
+	return loader.BindFlags(fs, structPtr)
+}
+
+// BindFlags populates the given struct like Load, then registers in fs a flag for every field, so that a small
+// command can be configured with both variables and flags from a single struct definition:
+//
+//	var cfg Config
+//	if err := env.BindFlags(flag.CommandLine, &cfg); err != nil {
+//		log.Fatal(err)
+//	}
+//	flag.Parse()
+//
+// Parsing the flags sets the fields they are given for, so that flags take precedence over variables, which take
+// precedence over defaults. The name of a flag is derived from the name of the variable without the loader prefix,
+// in lower case and with hyphens instead of underscores (e.g. -db-host for $APP_DB_HOST). Its usage is the
+// description of the field (see the "desc" tag) followed by the variable name, and its default is the value loaded
+// from the variable or the "default" tag, except for secret fields. Flags of bool fields can be given without
+// a value (e.g. -debug). Flag values are transformed and parsed like variable values.
+//
+// As the struct is loaded before the flags are parsed, required fields must be set by their variables even if
+// the corresponding flags are given. Map fields tagged with the "prefix" option, fields of unsupported kinds and
+// fields that are not loaded in the loader's environment have no flags. The nil pointers to nested structs left by
+// a loader created with WithOptionalPointers are allocated only when a flag of their fields is given. An error is
+// returned if the struct cannot be loaded, or if a flag is already defined in fs.
+func (l *Loader) BindFlags(fs *flag.FlagSet, structPtr interface{}) error {
+	if err := l.Load(structPtr); err != nil {
+		return err
+	}
+	value := reflect.ValueOf(structPtr).Elem()
+	fields, err := l.getFields(value.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if !l.active(f) || f.collect || !supportedKind(elemKind(f.typ)) {
+			continue
+		}
+		name := flagName(f.name)
+		if fs.Lookup(name) != nil {
+			return fmt.Errorf("%v: flag -%v is already defined", f.path, name)
+		}
+		usage := fmt.Sprintf("($%v)", l.varName(f))
+		if f.desc != "" {
+			usage = f.desc + " " + usage
+		}
+		fs.Var(&fieldFlag{l: l, f: f, root: value}, name, usage)
+	}
+	return nil
+}
+
+// flagName returns the name of the flag of a variable, in lower case and with hyphens instead of underscores.
+func flagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// fieldFlag is a flag.Value setting a struct field. The field is looked up in the root struct when the flag is used,
+// so that the nil pointers to nested structs left by the loader (see WithOptionalPointers) are only allocated
+// when a flag of their fields is given.
+type fieldFlag struct {
+	l    *Loader
+	f    *fieldInfo
+	root reflect.Value
+}
+
+// String returns the current value of the field, formatted like a variable value, or an empty string if the field
+// is secret. It is used by the flag package as the default value of the flag.
+func (v *fieldFlag) String() string {
+	if v == nil || v.f == nil || v.f.secret {
+		return ""
+	}
+	field, ok := existingField(v.root, v.f.index)
+	if !ok {
+		return ""
+	}
+	rv := reflect.Indirect(field)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr {
+		return ""
+	}
+	s, err := formatVar(rv)
+	if err != nil {
+		return ""
+	}
+	return v.f.encode(s)
+}
+
+// Set transforms and parses a flag value into the field, allocating the nil pointers to the structs enclosing it.
+func (v *fieldFlag) Set(value string) error {
+	value, err := v.l.transform(v.f, value)
+	if err == nil {
+		value, err = v.f.decode(value)
+	}
+	if err != nil {
+		return err
+	}
+	field := fieldByIndex(v.root, v.f.index)
+	err = v.l.setValue(field, v.f, value)
+	if err == nil && v.f.cons != nil {
+		err = v.f.cons.check(field)
+	}
+	return err
+}
+
+// IsBoolFlag returns whether the field is a bool, in which case the flag can be given without a value.
+func (v *fieldFlag) IsBoolFlag() bool {
+	return elemKind(v.f.typ) == reflect.Bool
+}
+
+// IsSecret returns whether the field is tagged as secret, in which case flag packages supporting hidden flags
+// should not list the flag in usage messages.
+func (v *fieldFlag) IsSecret() bool {
+	return v.f.secret
+}
+
+// Type returns the type of the value of the flag, as listed in manifests (see ManifestVar.Type). It makes the flag
+// a pflag.Value, for use with github.com/spf13/pflag.
+func (v *fieldFlag) Type() string {
+	return v.l.manifestType(v.f)
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bindConfig struct {
+	Host    string `default:"localhost" desc:"The server host."`
+	Port    int    `default:"80"`
+	Debug   bool
+	Token   string   `env:",secret" default:"s3cret"`
+	Key     []byte   `env:",base64"`
+	Nested  Embedded `prefix:"NESTED_"`
+	Ignored string   `env:"-"`
+}
+
+func TestLoader_BindFlags(t *testing.T) {
+	var cfg bindConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "8080", "APP_NESTED_PORT": "1"}), nil)
+	if !assert.Nil(t, l.BindFlags(fs, &cfg)) {
+		return
+	}
+	assert.Equal(t, bindConfig{Host: "localhost", Port: 8080, Token: "s3cret", Nested: Embedded{Port: 1}}, cfg)
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	assert.Contains(t, usage.String(), "-host value\n    \tThe server host. ($APP_HOST) (default localhost)")
+	assert.Contains(t, usage.String(), "-port value\n    \t($APP_PORT) (default 8080)")
+	assert.Contains(t, usage.String(), "-nested-url value")
+	assert.NotContains(t, usage.String(), "s3cret")
+	assert.Nil(t, fs.Lookup("ignored"))
+
+	err := fs.Parse([]string{"-port", "9090", "-debug", "-key", "YWJj", "-nested-url", "http://x"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, bindConfig{
+			Host:   "localhost",
+			Port:   9090,
+			Debug:  true,
+			Token:  "s3cret",
+			Key:    []byte("abc"),
+			Nested: Embedded{URL: "http://x", Port: 1},
+		}, cfg)
+	}
+
+	assert.NotNil(t, fs.Parse([]string{"-port", "x"}))
+
+	err = l.BindFlags(fs, &cfg)
+	assert.EqualError(t, err, "Host: flag -host is already defined")
+
+	var invalid bindConfig
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "x"}), nil)
+	assert.NotNil(t, l.BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), &invalid))
+	assert.Equal(t, ErrStructPointer, BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), invalid))
+}
+
+func TestLoader_BindFlags_optionalPointers(t *testing.T) {
+	type config struct {
+		Host  string
+		DB    *Embedded `prefix:"DB_"`
+		Cache *Embedded `prefix:"CACHE_"`
+	}
+	var cfg config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "localhost"}), nil, WithOptionalPointers())
+	if !assert.Nil(t, l.BindFlags(fs, &cfg)) {
+		return
+	}
+	// registering the flags does not allocate the sections left nil by the loader
+	assert.Nil(t, cfg.DB)
+	assert.Nil(t, cfg.Cache)
+	assert.NotNil(t, fs.Lookup("db-port"))
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	assert.Nil(t, cfg.DB)
+
+	if assert.Nil(t, fs.Parse([]string{"-db-port", "5432"})) {
+		assert.Equal(t, &Embedded{Port: 5432}, cfg.DB)
+		assert.Nil(t, cfg.Cache)
+	}
+}
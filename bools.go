@@ -0,0 +1,38 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "strings"
+
+// extendedBools maps the lower-case boolean spellings accepted with WithExtendedBools to the values understood by
+// strconv.ParseBool.
+var extendedBools = map[string]string{
+	"yes":      "true",
+	"no":       "false",
+	"on":       "true",
+	"off":      "false",
+	"enabled":  "true",
+	"disabled": "false",
+}
+
+// WithExtendedBools makes the loader accept "yes", "on" and "enabled" as true, and "no", "off" and "disabled" as
+// false, in any case, in addition to the values accepted by strconv.ParseBool, as these spellings are common in
+// the output of ops tooling. This applies to bool fields and to feature flags (see LoadFlags).
+//
+// The code generated by WriteLoadFunc only accepts the values of strconv.ParseBool.
+func WithExtendedBools() Option {
+	return func(l *Loader) {
+		l.extendedBools = true
+	}
+}
+
+// normalizeBool returns the value understood by strconv.ParseBool for an extended boolean spelling, or the value
+// as is if it is not one.
+func normalizeBool(value string) string {
+	if v, ok := extendedBools[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return v
+	}
+	return value
+}
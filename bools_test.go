@@ -0,0 +1,47 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExtendedBools(t *testing.T) {
+	type config struct {
+		Debug   bool
+		Verbose *bool
+		Cache   bool `default:"enabled"`
+		Color   bool
+	}
+	vars := MapLookup(map[string]string{
+		"APP_DEBUG":   "Yes",
+		"APP_VERBOSE": "off",
+		"APP_COLOR":   "1",
+		"APP_FF_NEW":  "ON",
+	})
+
+	var cfg config
+	l := NewWithLookup("APP_", vars, nil, WithExtendedBools())
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.True(t, cfg.Debug)
+		assert.False(t, *cfg.Verbose)
+		assert.True(t, cfg.Cache)
+		assert.True(t, cfg.Color)
+	}
+	flags, err := l.LoadFlags(map[string]bool{"NEW": false})
+	if assert.Nil(t, err) {
+		assert.True(t, flags.IsEnabled("NEW"))
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_DEBUG": "maybe"}), nil, WithExtendedBools())
+	assert.NotNil(t, l.Load(&cfg))
+
+	l = NewWithLookup("APP_", vars, nil)
+	assert.NotNil(t, l.Load(&cfg))
+	_, err = l.LoadFlags(map[string]bool{"NEW": false})
+	assert.NotNil(t, err)
+}
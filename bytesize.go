@@ -0,0 +1,82 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// byteUnits maps the lower-case units of byte sizes to their numbers of bytes. Decimal units such as "KB" are powers
+// of 1000, while binary units such as "KiB" are powers of 1024.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"tib": 1 << 40,
+	"p":   1e15,
+	"pb":  1e15,
+	"pib": 1 << 50,
+}
+
+// parseByteSize converts a byte size such as "512KB", "10MiB" or "1.5 GB" to a number of bytes. Units are case
+// insensitive, and a number without a unit is a number of bytes. Sizes whose number of bytes is not a whole number
+// are rounded to the nearest one.
+func parseByteSize(value string) (string, error) {
+	s := strings.TrimSpace(value)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	mult, ok := byteUnits[unit]
+	if !ok || number == "" {
+		return "", fmt.Errorf("invalid byte size %q", value)
+	}
+	if mult == 1 && !strings.Contains(number, ".") {
+		// whole numbers of bytes are kept as is, so that they can exceed the range of an int64 for uint64 fields
+		if _, err := strconv.ParseInt(number, 10, 64); err == nil {
+			return number, nil
+		}
+		if _, err := strconv.ParseUint(number, 10, 64); err == nil {
+			return number, nil
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid byte size %q", value)
+	}
+	n = math.Round(n * mult)
+	if n >= math.MaxInt64 || n < math.MinInt64 {
+		return "", errors.New("byte size out of range")
+	}
+	return strconv.FormatInt(int64(n), 10), nil
+}
+
+// isInteger returns whether t is a signed or unsigned integer type.
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseByteSize(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value string
+		size  string
+		err   bool
+	}{
+		{"t1", "1024", "1024", false},
+		{"t2", "512KB", "512000", false},
+		{"t3", "10MiB", "10485760", false},
+		{"t4", "1.5GB", "1500000000", false},
+		{"t5", " 2 gib ", "2147483648", false},
+		{"t6", "1k", "1000", false},
+		{"t7", "-1", "-1", false},
+		{"t8", "18446744073709551615", "18446744073709551615", false},
+		{"t9", "0.5B", "1", false},
+		{"t10", "10XB", "", true},
+		{"t11", "MB", "", true},
+		{"t12", "1.2.3KB", "", true},
+		{"t13", "10000PB", "", true},
+	}
+	for _, test := range tests {
+		size, err := parseByteSize(test.value)
+		assert.Equal(t, test.err, err != nil, test.tag)
+		assert.Equal(t, test.size, size, test.tag)
+	}
+}
+
+func TestLoader_Load_bytes(t *testing.T) {
+	var cfg struct {
+		MaxBody  int64   `env:"MAX_BODY,bytes"`
+		Buffer   *uint32 `env:",bytes" default:"64KiB"`
+		MaxFiles int
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_MAX_BODY": "10MiB"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, int64(10<<20), cfg.MaxBody)
+		assert.Equal(t, uint32(64<<10), *cfg.Buffer)
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_BUFFER": "8GB"}), nil)
+	assert.NotNil(t, l.Load(&cfg))
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_MAX_BODY": "lots"}), nil)
+	assert.EqualError(t, l.Load(&cfg), `$APP_MAX_BODY: invalid byte size "lots"`)
+
+	var invalid struct {
+		Size string `env:",bytes"`
+	}
+	assert.EqualError(t, l.Load(&invalid), "Size: the bytes option requires an integer field")
+
+	m, err := l.ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "bytesize", m.Vars[0].Type)
+		assert.Nil(t, m.Validate(map[string]string{"APP_MAX_BODY": "1.5GB"}))
+		assert.NotNil(t, m.Validate(map[string]string{"APP_MAX_BODY": "1.5XB"}))
+	}
+}
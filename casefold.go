@@ -0,0 +1,42 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"strings"
+)
+
+// WithCaseInsensitive makes the loader fall back to matching variable names case-insensitively when a variable is
+// not found by its lookup function. This helps on platforms and with tools that change the case of variable names
+// (e.g. $app_port instead of $APP_PORT). An exact match always takes precedence. If several variables only differ
+// in case, which one is used is unspecified.
+//
+// The fallback matches against a snapshot of the process environment taken when the option is applied, so variables
+// set afterwards are only found by an exact match. The snapshot is used regardless of the loader's lookup function.
+func WithCaseInsensitive() Option {
+	return func(l *Loader) {
+		l.foldedEnv = foldEnviron(os.Environ())
+	}
+}
+
+// foldEnviron returns a map of the given "name=value" pairs keyed by the upper case names.
+func foldEnviron(environ []string) map[string]string {
+	folded := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		folded[strings.ToUpper(name)] = value
+	}
+	return folded
+}
+
+// lookupFolded looks up a variable in the case-insensitive snapshot of the process environment, if any.
+func (l *Loader) lookupFolded(name string) (string, bool) {
+	if l.foldedEnv == nil {
+		return "", false
+	}
+	value, ok := l.foldedEnv[strings.ToUpper(name)]
+	return value, ok
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCaseInsensitive(t *testing.T) {
+	t.Setenv("app_host", "lower")
+	t.Setenv("App_Port", "8080")
+	t.Setenv("APP_MODE", "exact")
+	t.Setenv("app_mode", "lower")
+
+	var cfg struct {
+		Host string
+		Port int
+		Mode string
+	}
+	if assert.Nil(t, New("APP_", nil, WithCaseInsensitive()).Load(&cfg)) {
+		assert.Equal(t, "lower", cfg.Host)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, "exact", cfg.Mode)
+	}
+
+	// without the option, only exact matches are found
+	cfg.Host, cfg.Port = "", 0
+	if assert.Nil(t, New("APP_", nil).Load(&cfg)) {
+		assert.Equal(t, "", cfg.Host)
+		assert.Equal(t, 0, cfg.Port)
+	}
+
+	// variables matched case-insensitively are not unknown in strict mode
+	t.Setenv("app_prot", "8080")
+	err := New("APP_", nil, WithCaseInsensitive(), WithStrict(true)).Load(&cfg)
+	assert.EqualError(t, err, "unknown variables: $app_prot")
+}
+
+func Test_foldEnviron(t *testing.T) {
+	assert.Equal(t, map[string]string{"APP_HOST": "localhost", "PATH": "/bin=/usr/bin"},
+		foldEnviron([]string{"app_Host=localhost", "PATH=/bin=/usr/bin"}))
+}
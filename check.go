@@ -0,0 +1,85 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"text/tabwriter"
+)
+
+// Check verifies that a struct can be loaded by the package-level Load function and prints a report to
+// the standard output. For more details, please refer to Loader.Check().
+func Check(structPtr interface{}) error {
+	return loader.Check(os.Stdout, structPtr)
+}
+
+// Check performs a dry run of Load: it loads a copy of the given struct, validating every field, and writes
+// a report to w listing each field with its variable, value and status. The struct must be specified as a pointer.
+// It is only used as a template and is not modified. The values of fields tagged as secret are masked.
+// The copy is loaded like Load does, so the problems reported also include the errors of the AfterLoad hooks
+// and, in strict mode, the unknown variables.
+//
+// Unlike Load, Check does not stop at the first problem. All problems found are returned together as a joined error.
+// Check is meant to be wired to a command line flag, so that the configuration can be verified before a release:
+//
+//	if *checkConfig {
+//		if err := env.Check(&cfg); err != nil {
+//			os.Exit(1)
+//		}
+//		os.Exit(0)
+//	}
+func (l *Loader) Check(w io.Writer, structPtr interface{}) error {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrStructPointer
+	}
+	if _, err := l.getFields(value.Elem().Type()); err != nil {
+		fmt.Fprintf(w, "invalid configuration struct: %v\n", err)
+		return err
+	}
+
+	// the copy is loaded like Load does, including the Defaults and AfterLoad hooks and the strict mode,
+	// while collecting all the problems
+	target := reflect.New(value.Elem().Type())
+	target.Elem().Set(cloneValue(value.Elem()))
+	quiet := *l
+	quiet.log = nil
+	quiet.lenientErrors = true
+	report, err := quiet.loadReport(target.Interface())
+	if report == nil {
+		return err
+	}
+
+	var errs []error
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tVARIABLE\tVALUE\tSTATUS")
+	for _, fr := range report.Fields {
+		status := "default"
+		if fr.Overridden {
+			status = "set"
+		}
+		if fr.Err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", fr.Field, fr.Err))
+			status = "error: " + fr.Err.Error()
+		}
+		fmt.Fprintf(tw, "%v\t$%v\t%v\t%v\n", fr.Field, fr.Var, fr.Value, status)
+	}
+	_ = tw.Flush()
+	for _, err := range report.problems {
+		fmt.Fprintf(w, "error: %v\n", err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		fmt.Fprintf(w, "\n%v problem(s) found\n", len(errs))
+	} else {
+		fmt.Fprintln(w, "\nconfiguration is valid")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Check(t *testing.T) {
+	type config struct {
+		Host     string
+		Port     int    `default:"80"`
+		Password string `env:",secret"`
+		Debug    bool
+		URL      string `required:"true"`
+	}
+	cfg := config{Debug: true}
+
+	var buf bytes.Buffer
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "localhost", "APP_PASSWORD": "xyz", "APP_URL": "x"}), nil)
+	assert.Nil(t, l.Check(&buf, &cfg))
+	assert.Equal(t, `FIELD     VARIABLE       VALUE      STATUS
+Host      $APP_HOST      localhost  set
+Port      $APP_PORT      80         default
+Password  $APP_PASSWORD  ***        set
+Debug     $APP_DEBUG     true       default
+URL       $APP_URL       x          set
+
+configuration is valid
+`, buf.String())
+	assert.Equal(t, config{Debug: true}, cfg)
+
+	buf.Reset()
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "x", "APP_DEBUG": "maybe"}), nil)
+	err := l.Check(&buf, &cfg)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `Port: $APP_PORT: strconv.ParseInt: parsing "x": invalid syntax`)
+		assert.Contains(t, err.Error(), `Debug: $APP_DEBUG: strconv.ParseBool: parsing "maybe": invalid syntax`)
+		assert.Contains(t, err.Error(), `URL: $APP_URL: required variable is not set`)
+	}
+	assert.Contains(t, buf.String(), "\n3 problem(s) found\n")
+
+	assert.Equal(t, ErrStructPointer, l.Check(&buf, cfg))
+	var invalid struct {
+		Host string `env:",secrt"`
+	}
+	buf.Reset()
+	assert.NotNil(t, l.Check(&buf, &invalid))
+	assert.Contains(t, buf.String(), "invalid configuration struct")
+}
+
+func TestLoader_Check_hooks(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_DB_HOST": "DB", "APP_DB_PORT": "4"}), nil, WithOptionalPointers())
+	cfg := hookConfig{}
+	err := l.Check(&buf, &cfg)
+	assert.EqualError(t, err, "name is not set")
+	assert.Contains(t, buf.String(), "error: name is not set\n\n1 problem(s) found\n")
+	assert.Equal(t, hookConfig{}, cfg)
+
+	// the Defaults hooks are called before the fields are populated
+	buf.Reset()
+	assert.Nil(t, l.Check(&buf, &defaultsConfig{}))
+	assert.Contains(t, buf.String(), "DB.Host     $APP_DB_HOST     DB")
+	assert.Contains(t, buf.String(), "Servers     $APP_SERVERS     [a b]")
+
+	// unknown variables are problems in strict mode
+	buf.Reset()
+	t.Setenv("APP_UNKNOWN", "1")
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_NAME": "app", "APP_DB_PORT": "4"}), nil, WithOptionalPointers(), WithStrict(true))
+	assert.EqualError(t, l.Check(&buf, &hookConfig{}), "unknown variables: $APP_UNKNOWN")
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command envcheck prints the variables read by a configuration struct and validates the current environment
+// against them. It exits with a non-zero status if a variable is invalid or a required variable is missing,
+// which makes it suitable for preflight checks in CI pipelines.
+//
+// Usage:
+//
+//	envcheck [flags] <package> <struct>
+//
+// For example, the following command validates the environment against the Config struct of the
+// ./internal/config package, with variable names prefixed by "APP_":
+//
+//	envcheck -prefix APP_ ./internal/config Config
+//
+// The flags are:
+//
+//	-prefix string
+//		the prefix of the variable names (default "APP_")
+//	-env string
+//		the environment name used to select the fields with an "envs" tag
+//	-file string
+//		validate the variables in the given .env file instead of the environment
+//	-doc
+//		print the Markdown documentation of the variables and exit
+//
+// envcheck must be run within the module containing the package, as it builds a helper program that imports
+// the package to read the struct.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	env "github.com/garaekz/go-env"
+)
+
+// program is the helper program that exports the manifest of the struct.
+const program = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	env "github.com/garaekz/go-env"
+	target %q
+)
+
+func main() {
+	l := env.New(os.Args[1], nil, env.WithEnvironment(os.Args[2]))
+	m, err := l.ExportManifest(&target.%v{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(m)
+}
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("envcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	prefix := fs.String("prefix", "APP_", "the prefix of the variable names")
+	environment := fs.String("env", "", `the environment name used to select the fields with an "envs" tag`)
+	file := fs.String("file", "", "validate the variables in the given .env file instead of the environment")
+	doc := fs.Bool("doc", false, "print the Markdown documentation of the variables and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: envcheck [flags] <package> <struct>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	m, err := exportManifest(fs.Arg(0), fs.Arg(1), *prefix, *environment)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *doc {
+		fmt.Fprint(stdout, m.Markdown())
+		return 0
+	}
+
+	vars := environ()
+	if *file != "" {
+		if vars, err = readFile(*file); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	if err := check(stdout, m, vars); err != nil {
+		fmt.Fprintf(stderr, "\n%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// exportManifest returns the manifest of the given struct in the given package by running a helper program.
+func exportManifest(pkg, name, prefix, environment string) (*env.Manifest, error) {
+	out, err := command("go", "list", "-f", "{{.ImportPath}}", pkg)
+	if err != nil {
+		return nil, err
+	}
+	importPath := strings.TrimSpace(string(out))
+
+	dir, err := os.MkdirTemp(".", "envcheck")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf(program, importPath, name)), 0600); err != nil {
+		return nil, err
+	}
+	if out, err = command("go", "run", "./"+filepath.Base(dir), prefix, environment); err != nil {
+		return nil, err
+	}
+	return env.ReadManifest(bytes.NewReader(out))
+}
+
+// command runs a command and returns its standard output. The standard error is included in the returned error.
+func command(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %v", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// environ returns the variables of the process environment.
+func environ() map[string]string {
+	vars := map[string]string{}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		vars[name] = value
+	}
+	return vars
+}
+
+// readFile returns the variables defined in a .env file.
+func readFile(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return env.Parse(f)
+}
+
+// check prints the status of each variable in the manifest and validates the given variables against it.
+// Like the loader, a variable is looked up by its name, then by its aliases and its deprecated names, and the
+// variables of a collected map (named "PREFIX*") are those under its prefix. The status names the variable
+// supplying the value if it is not the variable itself.
+func check(w io.Writer, m *env.Manifest, vars map[string]string) error {
+	found := map[string]string{}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIABLE\tTYPE\tREQUIRED\tSTATUS")
+	for _, v := range m.Vars {
+		status := "missing"
+		if prefix, ok := strings.CutSuffix(v.Name, "*"); ok {
+			for name, value := range vars {
+				if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+					found[name] = value
+					status = "set"
+				}
+			}
+		} else {
+			for _, name := range append(append([]string{v.Name}, v.Aliases...), v.Deprecated...) {
+				if value, ok := vars[name]; ok {
+					found[name] = value
+					status = "set"
+					if name != v.Name {
+						status = "set ($" + name + ")"
+					}
+					break
+				}
+			}
+		}
+		if status == "missing" && v.Default != "" {
+			status = "default"
+		}
+		required := ""
+		if v.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", v.Name, v.Type, required, status)
+	}
+	_ = tw.Flush()
+	return m.Validate(found)
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	env "github.com/garaekz/go-env"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_check(t *testing.T) {
+	m := &env.Manifest{Vars: []env.ManifestVar{
+		{Name: "APP_HOST", Type: "string", Required: true},
+		{Name: "APP_PORT", Type: "int", Default: "80"},
+		{Name: "APP_DEBUG", Type: "bool"},
+	}}
+
+	var buf bytes.Buffer
+	assert.Nil(t, check(&buf, m, map[string]string{"APP_HOST": "localhost"}))
+	assert.Equal(t, `VARIABLE   TYPE    REQUIRED  STATUS
+APP_HOST   string  yes       set
+APP_PORT   int               default
+APP_DEBUG  bool              missing
+`, buf.String())
+
+	buf.Reset()
+	err := check(&buf, m, map[string]string{"APP_PORT": "x"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "$APP_HOST: required variable is not set")
+		assert.Contains(t, err.Error(), `$APP_PORT: strconv.ParseInt: parsing "x": invalid syntax`)
+	}
+}
+
+func Test_check_alternatives(t *testing.T) {
+	m := &env.Manifest{Vars: []env.ManifestVar{
+		{Name: "APP_HOST", Type: "string", Required: true, Aliases: []string{"APP_HOSTNAME"}, Deprecated: []string{"APP_SERVER"}},
+		{Name: "APP_PORT", Type: "int", Required: true, Deprecated: []string{"APP_LISTEN_PORT"}},
+		{Name: "APP_FEATURE_*", Type: "map", Required: true},
+	}}
+
+	var buf bytes.Buffer
+	vars := map[string]string{"APP_HOSTNAME": "localhost", "APP_SERVER": "old", "APP_LISTEN_PORT": "80", "APP_FEATURE_X": "1"}
+	assert.Nil(t, check(&buf, m, vars))
+	assert.Equal(t, `VARIABLE       TYPE    REQUIRED  STATUS
+APP_HOST       string  yes       set ($APP_HOSTNAME)
+APP_PORT       int     yes       set ($APP_LISTEN_PORT)
+APP_FEATURE_*  map     yes       set
+`, buf.String())
+
+	buf.Reset()
+	err := check(&buf, m, map[string]string{"APP_HOSTNAME": "localhost", "APP_LISTEN_PORT": "x"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `$APP_PORT: strconv.ParseInt: parsing "x": invalid syntax`)
+		assert.Contains(t, err.Error(), "$APP_FEATURE_*: required variables are not set")
+		assert.NotContains(t, err.Error(), "$APP_HOST:")
+	}
+}
+
+func Test_run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that builds a helper program")
+	}
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, &stdout, &stderr))
+
+	pkg := "./testdata/config"
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"-doc", "-env", "development", pkg, "Config"}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, "| Variable | Type | Default | Required | Description |\n"+
+		"|----------|------|---------|----------|-------------|\n"+
+		"| `APP_HOST` | string |  | yes | The host name. |\n"+
+		"| `APP_PORT` | int | `80` |  |  |\n"+
+		"| `APP_DEV` | bool |  |  |  |\n", stdout.String())
+
+	file := filepath.Join(t.TempDir(), ".env")
+	assert.Nil(t, os.WriteFile(file, []byte("MY_HOST=localhost\nMY_PORT=x\n"), 0600))
+	stdout.Reset()
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"-prefix", "MY_", "-file", file, pkg, "Config"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "MY_HOST")
+	assert.Contains(t, stderr.String(), `$MY_PORT: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	assert.Nil(t, os.WriteFile(file, []byte("MY_HOST=localhost\n"), 0600))
+	assert.Equal(t, 0, run([]string{"-prefix", "MY_", "-file", file, pkg, "Config"}, &stdout, &stderr))
+
+	assert.Equal(t, 1, run([]string{pkg, "Missing"}, &stdout, &stderr))
+}
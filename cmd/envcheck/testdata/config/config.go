@@ -0,0 +1,13 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package config is used to test envcheck.
+package config
+
+// Config is a configuration struct.
+type Config struct {
+	Host string `required:"true" desc:"The host name."`
+	Port int    `default:"80"`
+	Dev  bool   `envs:"development"`
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package cobraenv binds configuration structs loaded by go-env to the flags of cobra commands, so that a command
+// can be configured with both variables and flags from a single struct definition.
+package cobraenv
+
+import (
+	"flag"
+	"fmt"
+
+	env "github.com/garaekz/go-env"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Bind populates a struct using the package-level loader of go-env and registers a flag per field in the flag set
+// of the command. For more details, please refer to BindFlagSet().
+func Bind(cmd *cobra.Command, structPtr interface{}) error {
+	return BindFlagSet(cmd.Flags(), nil, structPtr)
+}
+
+// BindFlagSet populates a struct with the given loader, or with the package-level loader of go-env if it is nil,
+// and registers a flag per field in fs, as env.Loader.BindFlags does with the standard flag package:
+//
+//	var cfg Config
+//	cmd := &cobra.Command{Use: "serve", RunE: func(*cobra.Command, []string) error { return serve(cfg) }}
+//	if err := cobraenv.BindFlagSet(cmd.Flags(), env.New("APP_", nil), &cfg); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Flags given on the command line take precedence over variables, which take precedence over defaults. The help
+// text of a flag is the description of its field (see the "desc" tag) followed by the name of its variable. Flags
+// of fields tagged as secret are hidden from the help. An error is returned if the struct cannot be loaded, or if
+// a flag is already defined in fs.
+func BindFlagSet(fs *pflag.FlagSet, l *env.Loader, structPtr interface{}) error {
+	gfs := flag.NewFlagSet("", flag.ContinueOnError)
+	var err error
+	if l == nil {
+		err = env.BindFlags(gfs, structPtr)
+	} else {
+		err = l.BindFlags(gfs, structPtr)
+	}
+	if err != nil {
+		return err
+	}
+
+	gfs.VisitAll(func(f *flag.Flag) {
+		if err == nil && fs.Lookup(f.Name) != nil {
+			err = fmt.Errorf("flag --%v is already defined", f.Name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	gfs.VisitAll(func(f *flag.Flag) {
+		fs.AddGoFlag(f)
+		if s, ok := f.Value.(interface{ IsSecret() bool }); ok && s.IsSecret() {
+			_ = fs.MarkHidden(f.Name)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cobraenv
+
+import (
+	"bytes"
+	"testing"
+
+	env "github.com/garaekz/go-env"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type config struct {
+	Host  string `default:"localhost" desc:"The server host."`
+	Port  int    `default:"80"`
+	Debug bool
+	Token string `env:",secret"`
+}
+
+func TestBindFlagSet(t *testing.T) {
+	var cfg, ran config
+	cmd := &cobra.Command{
+		Use: "serve",
+		RunE: func(*cobra.Command, []string) error {
+			ran = cfg
+			return nil
+		},
+	}
+	l := env.NewWithLookup("APP_", env.MapLookup(map[string]string{"APP_PORT": "8080", "APP_TOKEN": "x"}), nil)
+	if !assert.Nil(t, BindFlagSet(cmd.Flags(), l, &cfg)) {
+		return
+	}
+
+	usage := cmd.Flags().FlagUsages()
+	assert.Contains(t, usage, "--host string   The server host. ($APP_HOST) (default \"localhost\")")
+	assert.Contains(t, usage, "--port int      ($APP_PORT) (default 8080)")
+	assert.Contains(t, usage, "--debug         ($APP_DEBUG)")
+	assert.NotContains(t, usage, "token")
+
+	cmd.SetArgs([]string{"--host", "example.com", "--debug", "--token", "y"})
+	cmd.SetOut(&bytes.Buffer{})
+	if assert.Nil(t, cmd.Execute()) {
+		assert.Equal(t, config{Host: "example.com", Port: 8080, Debug: true, Token: "y"}, ran)
+	}
+
+	err := BindFlagSet(cmd.Flags(), l, &cfg)
+	assert.EqualError(t, err, "flag --debug is already defined")
+
+	var other config
+	if assert.Nil(t, Bind(&cobra.Command{}, &other)) {
+		assert.Equal(t, "localhost", other.Host)
+	}
+	l = env.NewWithLookup("APP_", env.MapLookup(map[string]string{"APP_PORT": "x"}), nil)
+	assert.NotNil(t, BindFlagSet(cmd.Flags(), l, &other))
+}
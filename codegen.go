@@ -0,0 +1,363 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// envPkgPath is the import path of this package.
+const envPkgPath = "github.com/garaekz/go-env"
+
+// FieldSpec describes how a struct field is populated from a variable.
+// It is used by the code generated by WriteLoadFunc to resolve variables without reflection.
+type FieldSpec struct {
+	// Field is the name of the struct field, used in log messages.
+	Field string
+	// Name is the variable name without the loader prefix.
+	Name string
+	// Aliases lists the alternative variable names without the loader prefix, from the "alias" tag options.
+	Aliases []string
+	// Deprecated lists the former variable names without the loader prefix, from the "deprecated" tag.
+	Deprecated []string
+	// Path is the path of the Key of the variable, used to look it up in the loader's sources.
+	// If empty, it consists of Name only.
+	Path []string
+	// Secret indicates whether the field is tagged as secret.
+	Secret bool
+	// Default is the value of the "default" tag, which is used when HasDefault is true.
+	Default    string
+	HasDefault bool
+	// Required indicates whether the field is tagged as required.
+	Required bool
+	// Envs lists the environments in the "envs" tag.
+	Envs []string
+	// Transforms lists the names of the transformers in the "transform" tag.
+	Transforms []string
+	// Encoding is the "base64", "hex" or "bytes" option of the "env" tag, if any.
+	Encoding string
+	// Enum is the value of the "enum" tag, if any.
+	Enum string
+	// Expand indicates whether the field is tagged with the "expand" option.
+	Expand bool
+}
+
+// Resolve returns the value that Load would parse to populate the field described by spec: the transformed value
+// of its variable, or its default if the variable is not set. It returns false if the field should be left
+// unchanged, and an error if a transformer fails or the field is required but its variable is not set.
+func (l *Loader) Resolve(spec FieldSpec) (string, bool, error) {
+	f := &fieldInfo{
+		field:      spec.Field,
+		path:       spec.Field,
+		name:       spec.Name,
+		aliases:    spec.Aliases,
+		deprecated: spec.Deprecated,
+		keyPath:    spec.Path,
+		secret:     spec.Secret,
+		def:        spec.Default,
+		hasDefault: spec.HasDefault,
+		required:   spec.Required,
+		envs:       spec.Envs,
+		transforms: spec.Transforms,
+		encoding:   spec.Encoding,
+		expand:     spec.Expand,
+	}
+	if spec.Enum != "" {
+		var err error
+		if f.enum, err = parseEnum(spec.Enum); err != nil {
+			return "", false, err
+		}
+	}
+	if len(f.keyPath) == 0 {
+		f.keyPath = []string{spec.Name}
+	}
+	if !l.active(f) {
+		return "", false, nil
+	}
+	fullName, value, _, ok := l.find(f)
+	if ok {
+		v, err := l.prepare(f, fullName, value)
+		if err == nil {
+			v, err = f.decode(v)
+		}
+		return v, err == nil, l.fieldError(ErrParse, f, fullName, value, err)
+	}
+	if f.hasDefault {
+		def, err := l.defaultValue(f)
+		return def, err == nil, l.fieldError(ErrParse, f, fullName, f.def, err)
+	}
+	if f.required {
+		return "", false, l.fieldError(ErrMissingRequired, f, fullName, "", errors.New("required variable is not set"))
+	}
+	return "", false, nil
+}
+
+// ParseError returns the error that Load reports when the value returned by Resolve for the field described by spec
+// cannot be parsed: a *FieldError matching ErrParse, which names the variable and masks the value of secret fields.
+// It is called by the code generated by WriteLoadFunc.
+func (l *Loader) ParseError(spec FieldSpec, value string, err error) error {
+	f := &fieldInfo{field: spec.Field, path: spec.Field, name: spec.Name, secret: spec.Secret}
+	return l.fieldError(ErrParse, f, l.prefix+spec.Name, value, err)
+}
+
+// WriteLoadFunc writes the source code of a load function for the variables read by the package-level Load function.
+// For more details, please refer to Loader.WriteLoadFunc().
+func WriteLoadFunc(w io.Writer, structPtr interface{}) error {
+	return loader.WriteLoadFunc(w, structPtr)
+}
+
+// WriteLoadFunc writes to w the Go source code of a function that populates the given struct like Load does,
+// but with direct calls to parsing functions instead of reflection. This speeds up loading on hot paths and
+// makes it possible to load configurations where reflection is restricted. The struct must be specified as a
+// pointer, and the code is generated for the package declaring its type.
+//
+// For a struct type named Config, the generated function is:
+//
+//	func LoadConfig(l *env.Loader, c *Config) error
+//
+// Like Load, the generated function returns a *FieldError matching ErrParse for a value that cannot be parsed
+// (see Loader.ParseError).
+//
+// The generated code must be regenerated whenever the struct changes. WriteLoadFunc is typically called by a small
+// program run with go:generate, like WriteGetters.
+//
+// The variable names are determined when the code is generated, with the naming options of the loader, such as
+// WithTagName, WithTagFallback, WithLegacyNaming, WithNameMapper and WithAutoPrefix, and the types with parsers are
+// those registered globally or with WithParser. The generated function must therefore be called with a loader
+// created with the same options, or it reads other variables than Load.
+//
+// The generated code parses big.Float fields with their UnmarshalText method, which uses the precision of a float64
+// for fields without a precision, instead of one fitting the digits of the value. It does not check the constraints
+// declared by tags such as "oneof" and "min", and it reads arrays other than byte arrays from JSON arrays only.
+// It does not call the Defaults and AfterLoad hooks of the struct and its nested structs either, nor does it report
+// unknown variables in strict mode, so callers relying on them must call the hooks after the generated function.
+func (l *Loader) WriteLoadFunc(w io.Writer, structPtr interface{}) error {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrStructPointer
+	}
+	t := value.Elem().Type()
+	if t.Name() == "" {
+		return fmt.Errorf("a load function cannot be generated for the unnamed type %v", t)
+	}
+	fields, err := l.getFields(t)
+	if err != nil {
+		return err
+	}
+
+	g := &codeGenerator{pkgPath: t.PkgPath(), imports: map[string]string{}}
+	qualifier := ""
+	if t.PkgPath() != envPkgPath {
+		g.imports[envPkgPath] = "env"
+		qualifier = "env."
+	}
+
+	var body bytes.Buffer
+	allocated := map[string]bool{}
+	fmt.Fprintf(&body, "// Load%v populates c with the values of the variables read by l.\n", t.Name())
+	fmt.Fprintf(&body, "func Load%v(l *%vLoader, c *%v) error {\n", t.Name(), qualifier, t.Name())
+	for _, f := range fields {
+		if !supportedKind(elemKind(f.typ)) {
+			continue
+		}
+		if f.collect {
+			return fmt.Errorf("%v: fields tagged with the prefix option are not supported", f.path)
+		}
+		if p, _ := l.parser(f.typ); p != nil || f.parser != "" {
+			return fmt.Errorf("%v: fields of types with registered parsers are not supported", f.path)
+		}
+		body.WriteString(g.allocations(t, f.index, allocated))
+		spec := fmt.Sprintf("%vFieldSpec{%v}", qualifier, g.fieldSpec(f))
+		g.failure = "return l.ParseError(" + strings.ReplaceAll(spec, "%", "%%") + ", value, %v)"
+		fmt.Fprintf(&body, "if value, ok, err := l.Resolve(%v); err != nil {\nreturn err\n} else if ok {\n", spec)
+		body.WriteString(g.parse("c."+f.path, f.typ))
+		body.WriteString("}\n")
+	}
+	body.WriteString("return nil\n}\n")
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by env.WriteLoadFunc. DO NOT EDIT.\n\npackage %v\n\n", packageName(t))
+	src.WriteString(g.importDecl())
+	src.Write(body.Bytes())
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format the generated code: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// fieldSpec returns the elements of a FieldSpec literal describing the given field.
+func (g *codeGenerator) fieldSpec(f *fieldInfo) string {
+	elems := []string{fmt.Sprintf("Field: %q", f.field), fmt.Sprintf("Name: %q", f.name)}
+	if len(f.aliases) > 0 {
+		elems = append(elems, fmt.Sprintf("Aliases: %#v", f.aliases))
+	}
+	if len(f.deprecated) > 0 {
+		elems = append(elems, fmt.Sprintf("Deprecated: %#v", f.deprecated))
+	}
+	if len(f.keyPath) > 1 {
+		elems = append(elems, fmt.Sprintf("Path: %#v", f.keyPath))
+	}
+	if f.secret {
+		elems = append(elems, "Secret: true")
+	}
+	if f.hasDefault {
+		elems = append(elems, fmt.Sprintf("Default: %q", f.def), "HasDefault: true")
+	}
+	if f.required {
+		elems = append(elems, "Required: true")
+	}
+	if len(f.envs) > 0 {
+		elems = append(elems, fmt.Sprintf("Envs: %#v", f.envs))
+	}
+	if len(f.transforms) > 0 {
+		elems = append(elems, fmt.Sprintf("Transforms: %#v", f.transforms))
+	}
+	if f.encoding != "" {
+		elems = append(elems, fmt.Sprintf("Encoding: %q", f.encoding))
+	}
+	if len(f.enum) > 0 {
+		elems = append(elems, fmt.Sprintf("Enum: %q", enumTag(f.enum)))
+	}
+	if f.expand {
+		elems = append(elems, "Expand: true")
+	}
+	return strings.Join(elems, ", ")
+}
+
+// allocations returns the statements initializing the nil pointers to structs on the way to the field
+// with the given index, as Load does. Pointers in the allocated set are skipped, and those initialized
+// by the returned statements are added to it.
+func (g *codeGenerator) allocations(t reflect.Type, index []int, allocated map[string]bool) string {
+	var b strings.Builder
+	expr := "c"
+	for _, x := range index[:len(index)-1] {
+		sf := t.Field(x)
+		expr += "." + sf.Name
+		t = sf.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+			if !allocated[expr] {
+				allocated[expr] = true
+				fmt.Fprintf(&b, "if %v == nil {\n%v = new(%v)\n}\n", expr, expr, g.typeExpr(t))
+			}
+		}
+	}
+	return b.String()
+}
+
+// parse returns the statements parsing the string variable "value" into the target expression of the given type,
+// following the same rules as setValue.
+func (g *codeGenerator) parse(target string, t reflect.Type) string {
+	var b strings.Builder
+	// addr is the expression of a pointer to the target
+	addr := "&" + target
+	for t.Kind() == reflect.Ptr {
+		fmt.Fprintf(&b, "if %v == nil {\n%v = new(%v)\n}\n", target, target, g.typeExpr(t.Elem()))
+		addr, target = target, "*"+target
+		t = t.Elem()
+	}
+
+	recv := addr
+	if strings.HasPrefix(recv, "&") {
+		recv = "(" + recv + ")"
+	}
+	pt := reflect.PtrTo(t)
+	switch {
+	case pt.Implements(setterType):
+		fmt.Fprintf(&b, "if err := %v.Set(value); err != nil {\n%v\n}\n", recv, g.fail("err"))
+		return b.String()
+	case pt.Implements(textUnmarshalerType):
+		fmt.Fprintf(&b, "if err := %v.UnmarshalText([]byte(value)); err != nil {\n%v\n}\n", recv, g.fail("err"))
+		return b.String()
+	case pt.Implements(binaryUnmarshalerType):
+		fmt.Fprintf(&b, "if err := %v.UnmarshalBinary([]byte(value)); err != nil {\n%v\n}\n", recv, g.fail("err"))
+		return b.String()
+	}
+
+	if t == urlValuesType {
+		g.imports["net/url"] = "url"
+		fmt.Fprintf(&b, "v, err := url.ParseQuery(value)\nif err != nil {\n%v\n}\n%v = v\n", g.fail("err"), target)
+		return b.String()
+	}
+	if t == ipNetType {
+		g.imports["net"] = "net"
+		fmt.Fprintf(&b, "_, v, err := net.ParseCIDR(value)\nif err != nil {\n%v\n}\n%v = *v\n", g.fail("err"), target)
+		return b.String()
+	}
+
+	var parse, result string
+	switch t.Kind() {
+	case reflect.String:
+		fmt.Fprintf(&b, "%v = %v\n", target, g.convert("value", "string", t))
+		return b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parse, result = fmt.Sprintf("strconv.ParseInt(value, 0, %v)", t.Bits()), "int64"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parse, result = fmt.Sprintf("strconv.ParseUint(value, 0, %v)", t.Bits()), "uint64"
+	case reflect.Bool:
+		parse, result = "strconv.ParseBool(value)", "bool"
+	case reflect.Float32, reflect.Float64:
+		parse, result = fmt.Sprintf("strconv.ParseFloat(value, %v)", t.Bits()), "float64"
+	case reflect.Array:
+		g.imports["encoding/json"] = "json"
+		g.imports["fmt"] = "fmt"
+		if t.Elem().Kind() == reflect.Uint8 {
+			// like setArray, a JSON array of numbers is accepted if the value does not have the length of the array
+			g.imports["strings"] = "strings"
+			fmt.Fprintf(&b, "v := []byte(value)\nif len(v) != %v {\nvar elems []byte\n", t.Len())
+			b.WriteString("if !strings.HasPrefix(strings.TrimSpace(value), \"[\") || json.Unmarshal([]byte(value), &elems) != nil {\n")
+			fmt.Fprintf(&b, "%v\n}\n", g.fail(fmt.Sprintf("fmt.Errorf(\"the value has %%v bytes, but the array holds %v\", len(value))", t.Len())))
+			fmt.Fprintf(&b, "if len(elems) != %v {\n%v\n}\nv = elems\n}\n", t.Len(), g.fail(fmt.Sprintf("fmt.Errorf(\"the value has %%v elements, but the array holds %v\", len(elems))", t.Len())))
+			fmt.Fprintf(&b, "copy((%v)[:], v)\n", target)
+			return b.String()
+		}
+		fmt.Fprintf(&b, "var v []%v\nif err := json.Unmarshal([]byte(value), &v); err != nil {\n%v\n}\n", g.typeExpr(t.Elem()), g.fail("err"))
+		fmt.Fprintf(&b, "if len(v) != %v {\n%v\n}\n", t.Len(), g.fail(fmt.Sprintf("fmt.Errorf(\"the value has %%v elements, but the array holds %v\", len(v))", t.Len())))
+		fmt.Fprintf(&b, "copy((%v)[:], v)\n", target)
+		return b.String()
+	case reflect.Slice:
+		// json.RawMessage values are validated by json.Unmarshal
+		if t.Elem().Kind() == reflect.Uint8 && t != rawMessageType {
+			fmt.Fprintf(&b, "%v = %v\n", target, g.convert("value", "string", t))
+			return b.String()
+		}
+	}
+	if parse == "" {
+		g.imports["encoding/json"] = "json"
+		fmt.Fprintf(&b, "if err := json.Unmarshal([]byte(value), %v); err != nil {\n%v\n}\n", addr, g.fail("err"))
+		return b.String()
+	}
+	g.imports["strconv"] = "strconv"
+	fmt.Fprintf(&b, "v, err := %v\nif err != nil {\n%v\n}\n%v = %v\n", parse, g.fail("err"), target, g.convert("v", result, t))
+	return b.String()
+}
+
+// fail returns the statement returning the error of the given expression from the generated code (see failure).
+func (g *codeGenerator) fail(err string) string {
+	if g.failure == "" {
+		return "return " + err
+	}
+	return fmt.Sprintf(g.failure, err)
+}
+
+// convert returns the expression converting a variable of the given type name to the type t.
+func (g *codeGenerator) convert(name, from string, t reflect.Type) string {
+	to := g.typeExpr(t)
+	if to == from {
+		return name
+	}
+	if to == "[]uint8" {
+		to = "[]byte"
+	}
+	return to + "(" + name + ")"
+}
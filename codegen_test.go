@@ -0,0 +1,204 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type loadFuncConfig struct {
+	Host     string `required:"true" transform:"trim"`
+	Port     uint16 `default:"80" envs:"production"`
+	Debug    *bool
+	Level    myInt
+	Name     myString `env:"NAME,secret"`
+	Tags     []string
+	Server   *Embedded `prefix:"SERVER_"`
+	Callback func()
+}
+
+func TestWriteLoadFunc(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteLoadFunc(&buf, &loadFuncConfig{}))
+	assert.Equal(t, `// Code generated by env.WriteLoadFunc. DO NOT EDIT.
+
+package env
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// LoadloadFuncConfig populates c with the values of the variables read by l.
+func LoadloadFuncConfig(l *Loader, c *loadFuncConfig) error {
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Host", Name: "HOST", Required: true, Transforms: []string{"trim"}}); err != nil {
+		return err
+	} else if ok {
+		c.Host = value
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Port", Name: "PORT", Default: "80", HasDefault: true, Envs: []string{"production"}}); err != nil {
+		return err
+	} else if ok {
+		v, err := strconv.ParseUint(value, 0, 16)
+		if err != nil {
+			return l.ParseError(FieldSpec{Field: "Port", Name: "PORT", Default: "80", HasDefault: true, Envs: []string{"production"}}, value, err)
+		}
+		c.Port = uint16(v)
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Debug", Name: "DEBUG"}); err != nil {
+		return err
+	} else if ok {
+		if c.Debug == nil {
+			c.Debug = new(bool)
+		}
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return l.ParseError(FieldSpec{Field: "Debug", Name: "DEBUG"}, value, err)
+		}
+		*c.Debug = v
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Level", Name: "LEVEL"}); err != nil {
+		return err
+	} else if ok {
+		if err := (&c.Level).UnmarshalText([]byte(value)); err != nil {
+			return l.ParseError(FieldSpec{Field: "Level", Name: "LEVEL"}, value, err)
+		}
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Name", Name: "NAME", Secret: true}); err != nil {
+		return err
+	} else if ok {
+		if err := (&c.Name).UnmarshalBinary([]byte(value)); err != nil {
+			return l.ParseError(FieldSpec{Field: "Name", Name: "NAME", Secret: true}, value, err)
+		}
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Tags", Name: "TAGS"}); err != nil {
+		return err
+	} else if ok {
+		if err := json.Unmarshal([]byte(value), &c.Tags); err != nil {
+			return l.ParseError(FieldSpec{Field: "Tags", Name: "TAGS"}, value, err)
+		}
+	}
+	if c.Server == nil {
+		c.Server = new(Embedded)
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "URL", Name: "SERVER_URL", Path: []string{"SERVER", "URL"}}); err != nil {
+		return err
+	} else if ok {
+		c.Server.URL = value
+	}
+	if value, ok, err := l.Resolve(FieldSpec{Field: "Port", Name: "SERVER_PORT", Path: []string{"SERVER", "PORT"}}); err != nil {
+		return err
+	} else if ok {
+		v, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return l.ParseError(FieldSpec{Field: "Port", Name: "SERVER_PORT", Path: []string{"SERVER", "PORT"}}, value, err)
+		}
+		c.Server.Port = int(v)
+	}
+	return nil
+}
+`, buf.String())
+
+	assert.Equal(t, ErrStructPointer, WriteLoadFunc(&buf, loadFuncConfig{}))
+	assert.NotNil(t, WriteLoadFunc(&buf, &struct{ Host string }{}))
+}
+
+// loadFuncParsed is a struct with a field whose type may have a parser.
+type loadFuncParsed struct {
+	Price money
+}
+
+// loadFuncNamed is a struct whose variable names depend on the naming options of the loader.
+type loadFuncNamed struct {
+	APIKey  string `json:"key"`
+	MaxConn string `cfg:"MAX"`
+}
+
+func TestLoader_WriteLoadFunc(t *testing.T) {
+	tests := []struct {
+		tag   string
+		opts  []Option
+		names []string
+	}{
+		{"default", nil, []string{`Name: "API_KEY"`, `Name: "MAX_CONN"`}},
+		{"tag name", []Option{WithTagName("cfg")}, []string{`Name: "API_KEY"`, `Name: "MAX"`}},
+		{"tag fallback", []Option{WithTagFallback("json")}, []string{`Name: "KEY"`, `Name: "MAX_CONN"`}},
+		{"legacy naming", []Option{WithLegacyNaming()}, []string{`Name: "APIKEY"`, `Name: "MAX_CONN"`}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		l := NewWithLookup("APP_", MapLookup(nil), nil, test.opts...)
+		if assert.Nil(t, l.WriteLoadFunc(&buf, &loadFuncNamed{}), test.tag) {
+			for _, name := range test.names {
+				assert.Contains(t, buf.String(), name, test.tag)
+			}
+		}
+	}
+
+	// the parsers of the loader are not supported
+	assert.Nil(t, WriteLoadFunc(&bytes.Buffer{}, &loadFuncParsed{}))
+	l := NewWithLookup("APP_", MapLookup(nil), nil, WithParser(reflect.TypeOf(money{}), parseMoney))
+	assert.EqualError(t, l.WriteLoadFunc(&bytes.Buffer{}, &loadFuncParsed{}), "Price: fields of types with registered parsers are not supported")
+}
+
+func TestLoader_ParseError(t *testing.T) {
+	l := NewWithLookup("APP_", MapLookup(nil), nil)
+	_, err := strconv.ParseInt("x", 0, 64)
+	err = l.ParseError(FieldSpec{Field: "Port", Name: "PORT"}, "x", err)
+	assert.EqualError(t, err, `$APP_PORT: strconv.ParseInt: parsing "x": invalid syntax`)
+	assert.ErrorIs(t, err, ErrParse)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Port", fe.Field)
+		assert.Equal(t, "APP_PORT", fe.Var)
+		assert.Equal(t, "x", fe.Value)
+	}
+
+	_, err = strconv.ParseInt("s3cr3t", 0, 64)
+	err = l.ParseError(FieldSpec{Field: "Pin", Name: "PIN", Secret: true}, "s3cr3t", err)
+	assert.EqualError(t, err, `$APP_PIN: strconv.ParseInt: parsing "***": invalid syntax`)
+}
+
+func TestLoader_Resolve(t *testing.T) {
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_HOST": " localhost ",
+		"APP_PORT": "8080",
+	}), nil, WithEnvironment("development"))
+
+	tests := []struct {
+		tag   string
+		spec  FieldSpec
+		value string
+		ok    bool
+		err   string
+	}{
+		{"t1", FieldSpec{Field: "Host", Name: "HOST"}, " localhost ", true, ""},
+		{"t2", FieldSpec{Field: "Host", Name: "HOST", Transforms: []string{"trim"}}, "localhost", true, ""},
+		{"t3", FieldSpec{Field: "Port", Name: "PORT", Default: "80", HasDefault: true}, "8080", true, ""},
+		{"t4", FieldSpec{Field: "Debug", Name: "DEBUG", Default: "true", HasDefault: true}, "true", true, ""},
+		{"t5", FieldSpec{Field: "Debug", Name: "DEBUG"}, "", false, ""},
+		{"t6", FieldSpec{Field: "Debug", Name: "DEBUG", Required: true}, "", false, "$APP_DEBUG: required variable is not set"},
+		{"t7", FieldSpec{Field: "Port", Name: "PORT", Required: true, Envs: []string{"production"}}, "", false, ""},
+		{"t8", FieldSpec{Field: "Host", Name: "HOST", Transforms: []string{"xyz"}}, "", false, `$APP_HOST: unknown transformer "xyz"`},
+	}
+	for _, test := range tests {
+		value, ok, err := l.Resolve(test.spec)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.tag)
+			continue
+		}
+		if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, test.ok, ok, test.tag)
+			assert.Equal(t, test.value, value, test.tag)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// assignCollected populates a map field tagged with the "prefix" option with the variables under its prefix,
+// or with its default if there are none. It returns the report of the field like assignValue, with the variable
+// name returned by varName.
+func (l *Loader) assignCollected(field reflect.Value, f *fieldInfo) (FieldReport, error) {
+	fr := FieldReport{Field: f.path, Var: l.varName(f), Secret: f.secret}
+	prefix := l.prefix + f.name
+	names := l.collectNames(prefix)
+	if len(names) > 0 {
+		m := reflect.MakeMapWithSize(f.typ, len(names))
+		for _, name := range names {
+			value, ok := l.lookup(name)
+			if !ok {
+				continue
+			}
+			v, err := l.prepare(f, name, value)
+			if err != nil {
+				return fr, l.fieldError(ErrParse, f, name, value, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimPrefix(name, prefix)).Convert(f.typ.Key()), reflect.ValueOf(v).Convert(f.typ.Elem()))
+		}
+		if m.Len() > 0 {
+			field.Set(m)
+			fr.Overridden, fr.Source = true, l.lookupLabel
+			return fr, nil
+		}
+	}
+	if f.hasDefault {
+		fr.DefaultUsed, fr.Source = true, "default"
+		return fr, l.fieldError(ErrParse, f, fr.Var, f.def, setValue(field, l.expand(f, f.def)))
+	}
+	if f.required {
+		return fr, l.fieldError(ErrMissingRequired, f, fr.Var, "", errors.New("required variables are not set"))
+	}
+	l.clear(field)
+	return fr, nil
+}
+
+// environNames returns the names of the variables listed by the loader (see WithVariableNames), which are those of
+// the process environment by default.
+func (l *Loader) environNames() []string {
+	if l.names != nil {
+		return l.names()
+	}
+	names := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	return names
+}
+
+// collectNames returns the sorted names of the variables listed by the loader that start with the given prefix and
+// are longer than it.
+func (l *Loader) collectNames(prefix string) []string {
+	var names []string
+	for _, name := range l.environNames() {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectedVars adds to vars the entries of a map field tagged with the "prefix" option, as variables named
+// after the prefix followed by the map keys.
+func collectedVars(vars map[string]string, prefix string, m reflect.Value) {
+	iter := m.MapRange()
+	for iter.Next() {
+		vars[prefix+iter.Key().String()] = iter.Value().String()
+	}
+}
+
+// prefixNames returns the given variable names prefixed with the loader prefix.
+func (l *Loader) prefixNames(names []string) []string {
+	var prefixed []string
+	for _, name := range names {
+		prefixed = append(prefixed, l.prefix+name)
+	}
+	return prefixed
+}
+
+// varName returns the name of the variable of a field, including the loader prefix. For a map field tagged with
+// the "prefix" option, it is the prefix of the variables followed by "*" (e.g. "APP_FEATURE_*").
+func (l *Loader) varName(f *fieldInfo) string {
+	if f.collect {
+		return l.prefix + f.name + "*"
+	}
+	return l.prefix + f.name
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type collectConfig struct {
+	Host     string
+	Features map[string]string `env:",prefix=FEATURE_"`
+	HTTP     struct {
+		Headers map[string]string `env:",prefix=HEADER_" default:"{\"Accept\":\"*/*\"}"`
+	} `prefix:"HTTP_"`
+}
+
+func TestLoader_Load_collect(t *testing.T) {
+	t.Setenv("APP_HOST", "localhost")
+	t.Setenv("APP_FEATURE_DARK_MODE", "on")
+	t.Setenv("APP_FEATURE_BETA", "")
+	t.Setenv("APP_FEATURE_", "ignored")
+
+	var cfg collectConfig
+	report, err := NewWithLookup("APP_", os.LookupEnv, nil, WithStrict(true)).LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"DARK_MODE": "on", "BETA": ""}, cfg.Features)
+		assert.Equal(t, map[string]string{"Accept": "*/*"}, cfg.HTTP.Headers)
+		assert.Equal(t, FieldReport{Field: "Features", Var: "APP_FEATURE_*", Overridden: true, Source: "lookup", Value: "map[BETA: DARK_MODE:on]"}, report.Fields[1])
+		assert.Equal(t, FieldReport{Field: "HTTP.Headers", Var: "APP_HTTP_HEADER_*", DefaultUsed: true, Source: "default", Value: "map[Accept:*/*]"}, report.Fields[2])
+	}
+
+	// values are read with the lookup function
+	cfg = collectConfig{}
+	lookup := MapLookup(map[string]string{"APP_FEATURE_DARK_MODE": "off"})
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, map[string]string{"DARK_MODE": "off"}, cfg.Features)
+	}
+
+	var cfg2 struct {
+		Features map[string]string `env:",prefix=MISSING_" required:"true"`
+	}
+	assert.EqualError(t, New("APP_", nil).Load(&cfg2), "$APP_MISSING_*: required variables are not set")
+
+	var cfg3 struct {
+		Features map[string]int `env:",prefix=FEATURE_"`
+	}
+	assert.EqualError(t, New("APP_", nil).Load(&cfg3), "Features: the prefix option requires a map[string]string field")
+}
+
+func TestLoader_Dump_collect(t *testing.T) {
+	cfg := collectConfig{Host: "localhost", Features: map[string]string{"DARK_MODE": "on"}}
+	vars, err := New("APP_", nil).Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"APP_HOST": "localhost", "APP_FEATURE_DARK_MODE": "on"}, vars)
+	}
+
+	vars, err = New("APP_", nil).Random(&cfg, rand.New(rand.NewSource(1)))
+	if assert.Nil(t, err) {
+		for name, value := range vars {
+			if key, ok := strings.CutPrefix(name, "APP_FEATURE_"); ok {
+				assert.Equal(t, value, cfg.Features[key])
+			}
+		}
+	}
+
+	m, err := New("APP_", nil).ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "APP_FEATURE_*", m.Vars[1].Name)
+		m.Vars[1].Required = true
+		assert.Nil(t, m.Validate(map[string]string{"APP_FEATURE_X": "1"}))
+		assert.EqualError(t, m.Validate(map[string]string{}), "$APP_FEATURE_*: required variables are not set")
+	}
+
+	assert.EqualError(t, WriteLoadFunc(&bytes.Buffer{}, &cfg), "Features: fields tagged with the prefix option are not supported")
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// commandUsage describes the subcommands supported by RunCommand.
+const commandUsage = `Usage: config <command> [options]
+
+Commands:
+  check              load the configuration and report any problems
+  docs [--json]      print the documentation of the variables in Markdown or JSON format
+  example            print an example .env file
+  dump [--redacted]  print the loaded configuration as a .env file
+`
+
+// RunCommand runs a configuration management subcommand with the package-level loader, writing its output
+// to the standard output. For more details, please refer to Loader.RunCommand().
+func RunCommand(structPtr interface{}, args []string) error {
+	return loader.RunCommand(os.Stdout, structPtr, args)
+}
+
+// RunCommand runs the configuration management subcommand specified by args and writes its output to w.
+// It gives an application a "config" command line interface with a few lines of wiring, for example:
+//
+//	if len(os.Args) > 1 && os.Args[1] == "config" {
+//		if err := env.RunCommand(&cfg, os.Args[2:]); err != nil {
+//			os.Exit(1)
+//		}
+//		return
+//	}
+//
+// The struct must be specified as a pointer. It is used as a template and is not modified.
+// The following subcommands are supported:
+//   - check: performs a dry run of Load and reports problems (see Check)
+//   - docs: prints the documentation of the variables in Markdown format (see Doc).
+//     With the --json flag, the manifest of the variables is printed in JSON format instead (see ExportManifest).
+//   - example: prints an example .env file (see WriteExample)
+//   - dump: loads the struct and prints the resulting variables as a .env file (see Dump).
+//     With the --redacted flag, the values of secret fields are masked.
+func (l *Loader) RunCommand(w io.Writer, structPtr interface{}, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(w, commandUsage)
+		return errors.New("missing command")
+	}
+
+	switch args[0] {
+	case "check":
+		return l.Check(w, structPtr)
+	case "docs":
+		fs := flag.NewFlagSet("docs", flag.ContinueOnError)
+		fs.SetOutput(w)
+		asJSON := fs.Bool("json", false, "print the manifest in JSON format")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *asJSON {
+			m, err := l.ExportManifest(structPtr)
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(m)
+		}
+		doc, err := l.Doc(structPtr)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, doc)
+		return err
+	case "example":
+		return l.WriteExample(w, structPtr)
+	case "dump":
+		fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+		fs.SetOutput(w)
+		redacted := fs.Bool("redacted", false, "mask the values of secret fields")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return l.writeDump(w, structPtr, *redacted)
+	}
+	fmt.Fprint(w, commandUsage)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// writeDump loads a copy of the given struct and writes the resulting variables to w as a .env file,
+// sorted by name. If redacted is true, the values of secret fields are masked.
+func (l *Loader) writeDump(w io.Writer, structPtr interface{}, redacted bool) error {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrStructPointer
+	}
+	target := reflect.New(value.Elem().Type())
+	target.Elem().Set(cloneValue(value.Elem()))
+	quiet := *l
+	quiet.log = nil
+	if err := quiet.Load(target.Interface()); err != nil {
+		return err
+	}
+
+	vars, err := l.Dump(target.Interface())
+	if err != nil {
+		return err
+	}
+	if redacted {
+		fields, _ := l.getFields(value.Elem().Type())
+		for _, f := range fields {
+			if !f.secret {
+				continue
+			}
+			for name, value := range vars {
+				if name == l.prefix+f.name || f.collect && strings.HasPrefix(name, l.prefix+f.name) {
+					vars[name] = l.redact(name, value)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%v=%v\n", name, dotenvValue(vars[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_RunCommand(t *testing.T) {
+	type config struct {
+		Host     string
+		Port     int    `default:"80"`
+		Password string `env:",secret"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "my host", "APP_PASSWORD": "xyz"}), nil)
+
+	tests := []struct {
+		tag    string
+		args   []string
+		output string
+		err    string
+	}{
+		{"t1", []string{"dump"}, "APP_HOST=\"my host\"\nAPP_PASSWORD=xyz\nAPP_PORT=80\n", ""},
+		{"t2", []string{"dump", "--redacted"}, "APP_HOST=\"my host\"\nAPP_PASSWORD=***\nAPP_PORT=80\n", ""},
+		{"t3", []string{"example"}, "# Host (string)\nAPP_HOST=\n\n# Port (int)\nAPP_PORT=80\n\n# Password (string, secret)\nAPP_PASSWORD=\n", ""},
+		{"t4", nil, commandUsage, "missing command"},
+		{"t5", []string{"deploy"}, commandUsage, `unknown command "deploy"`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := l.RunCommand(&buf, &config{}, test.args)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.tag)
+		} else {
+			assert.Nil(t, err, test.tag)
+		}
+		assert.Equal(t, test.output, buf.String(), test.tag)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, l.RunCommand(&buf, &config{}, []string{"check"}))
+	assert.Contains(t, buf.String(), "configuration is valid")
+
+	buf.Reset()
+	assert.Nil(t, l.RunCommand(&buf, &config{}, []string{"docs"}))
+	assert.Contains(t, buf.String(), "| `APP_PORT` | int | `80` |  |  |\n")
+
+	buf.Reset()
+	assert.Nil(t, l.RunCommand(&buf, &config{}, []string{"docs", "--json"}))
+	m, err := ReadManifest(&buf)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "APP_HOST", m.Vars[0].Name)
+	}
+
+	assert.NotNil(t, l.RunCommand(&buf, &config{}, []string{"dump", "--unknown"}))
+	assert.Equal(t, ErrStructPointer, l.RunCommand(&buf, config{}, []string{"dump"}))
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "x"}), nil)
+	assert.NotNil(t, l.RunCommand(&buf, &config{}, []string{"dump"}))
+}
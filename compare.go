@@ -0,0 +1,146 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FieldDiff describes a struct field whose value differs between two configurations.
+type FieldDiff struct {
+	// Field is the dot-separated path of the struct field.
+	Field string
+	// Var is the name of the variable populating the field, including the loader prefix.
+	Var string
+	// Secret indicates whether the field is tagged as secret, in which case Left and Right are redacted.
+	Secret bool
+	// Left and Right are the formatted field values loaded from the left and right lookups.
+	Left, Right string
+}
+
+// String returns a human-readable description of the difference.
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%v ($%v): %q != %q", d.Field, d.Var, d.Left, d.Right)
+}
+
+// Compare loads the given struct via two lookups using the package-level loader and reports the fields
+// whose values differ. For more details, please refer to Loader.Compare().
+func Compare(structPtr interface{}, left, right LookupFunc) ([]FieldDiff, error) {
+	return loader.Compare(structPtr, left, right)
+}
+
+// Compare loads two fresh copies of the given struct, one with each lookup, and reports the fields whose loaded
+// values differ, in the order they are declared. It answers the question of what is different between
+// two environments, for example by comparing the lookups returned by FileLookup for a staging and a production file.
+//
+// The struct must be specified as a pointer. It is only used as a template and is not modified.
+// The loader's prefix, naming rules, environment and transformers are used, while its own lookups and sources
+// are not consulted.
+// The values of fields tagged as secret are redacted, though differences in them are still reported.
+func (l *Loader) Compare(structPtr interface{}, left, right LookupFunc) ([]FieldDiff, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+	t := value.Elem().Type()
+	fields, err := l.getFields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	load := func(lookup LookupFunc) (reflect.Value, error) {
+		target := reflect.New(t)
+		other := *l
+		other.lookup, other.secretLookup, other.sources, other.foldedEnv = lookup, nil, nil, nil
+		other.log, other.strict, other.layered = nil, false, false
+		_, err := other.loadReport(target.Interface())
+		return target.Elem(), err
+	}
+	a, err := load(left)
+	if err != nil {
+		return nil, fmt.Errorf("left: %w", err)
+	}
+	b, err := load(right)
+	if err != nil {
+		return nil, fmt.Errorf("right: %w", err)
+	}
+
+	return l.diff(fields, a, b), nil
+}
+
+// Diff reports the fields whose values differ between two loaded copies of a struct using the package-level loader.
+// For more details, please refer to Loader.Diff().
+func Diff(old, new interface{}) ([]FieldDiff, error) {
+	return loader.Diff(old, new)
+}
+
+// Diff reports the fields whose values differ between two copies of a struct, such as the configurations before
+// and after a reload, in the order they are declared. It powers reload logging and change auditing in long-running
+// services. Left holds the old value and Right the new one, and the values of fields tagged as secret are redacted,
+// though changes in them are still reported.
+//
+// Both structs must be specified as pointers to the same struct type, otherwise an error is returned.
+func (l *Loader) Diff(old, new interface{}) ([]FieldDiff, error) {
+	a, b := reflect.ValueOf(old), reflect.ValueOf(new)
+	for _, v := range []reflect.Value{a, b} {
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return nil, ErrStructPointer
+		}
+	}
+	if a.Type() != b.Type() {
+		return nil, fmt.Errorf("cannot compare %v with %v", a.Type(), b.Type())
+	}
+	fields, err := l.getFields(a.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+	return l.diff(fields, a.Elem(), b.Elem()), nil
+}
+
+// diff reports the fields whose values differ between two struct values of the type the fields belong to.
+func (l *Loader) diff(fields []*fieldInfo, a, b reflect.Value) []FieldDiff {
+	var diffs []FieldDiff
+	for _, f := range fields {
+		va, vb := fieldValue(a, f.index), fieldValue(b, f.index)
+		if reflect.DeepEqual(va, vb) {
+			continue
+		}
+		d := FieldDiff{Field: f.path, Var: l.varName(f), Secret: f.secret, Left: formatValue(va), Right: formatValue(vb)}
+		if f.secret {
+			d.Left, d.Right = l.redact(d.Var, d.Left), l.redact(d.Var, d.Right)
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// fieldValue returns the value of the nested field of a struct value corresponding to index.
+// Unlike fieldByIndex, it does not modify the struct, and returns nil if a pointer along the way is nil.
+func fieldValue(v reflect.Value, index []int) interface{} {
+	field, ok := existingField(v, index)
+	if !ok {
+		return nil
+	}
+	return field.Interface()
+}
+
+// formatValue formats a field value for display, dereferencing pointers.
+func formatValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "<nil>"
+	}
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "<nil>"
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+		return string(rv.Bytes())
+	}
+	return fmt.Sprint(rv.Interface())
+}
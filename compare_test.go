@@ -0,0 +1,84 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type compareConfig struct {
+	Host     string
+	Port     int    `default:"80"`
+	Password string `env:",secret"`
+	Key      []byte
+	Level    *myInt
+	Nested   Embedded `prefix:"NESTED_"`
+}
+
+func TestLoader_Compare(t *testing.T) {
+	dir := t.TempDir()
+	staging, prod := filepath.Join(dir, "staging.env"), filepath.Join(dir, "prod.env")
+	assert.Nil(t, os.WriteFile(staging, []byte("APP_HOST=stage.local\nAPP_PASSWORD=a\nAPP_KEY=k\nAPP_NESTED_URL=http://a\n"), 0600))
+	assert.Nil(t, os.WriteFile(prod, []byte("APP_HOST=prod.local\nAPP_PORT=80\nAPP_PASSWORD=b\nAPP_KEY=k\nAPP_LEVEL=3\nAPP_NESTED_URL=http://a\n"), 0600))
+	left, err := FileLookup(staging)
+	assert.Nil(t, err)
+	right, err := FileLookup(prod)
+	assert.Nil(t, err)
+
+	l := NewWithLookup("APP_", mockLookup, nil)
+	cfg := compareConfig{Host: "unchanged"}
+	diffs, err := l.Compare(&cfg, left, right)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []FieldDiff{
+			{Field: "Host", Var: "APP_HOST", Left: "stage.local", Right: "prod.local"},
+			{Field: "Password", Var: "APP_PASSWORD", Secret: true, Left: "***", Right: "***"},
+			{Field: "Level", Var: "APP_LEVEL", Left: "<nil>", Right: "3"},
+		}, diffs)
+		assert.Equal(t, `Host ($APP_HOST): "stage.local" != "prod.local"`, diffs[0].String())
+		assert.Equal(t, "unchanged", cfg.Host)
+	}
+
+	_, err = l.Compare(cfg, left, right)
+	assert.Equal(t, ErrStructPointer, err)
+	_, err = l.Compare(&cfg, MapLookup(map[string]string{"APP_PORT": "x"}), right)
+	assert.NotNil(t, err)
+	_, err = l.Compare(&cfg, left, MapLookup(map[string]string{"APP_PORT": "x"}))
+	assert.NotNil(t, err)
+
+	diffs, err = Compare(&cfg, left, left)
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+
+	_, err = FileLookup(filepath.Join(dir, "missing.env"))
+	assert.NotNil(t, err)
+}
+
+func TestLoader_Diff(t *testing.T) {
+	level := myInt(2)
+	old := compareConfig{Host: "a", Port: 80, Password: "x", Nested: Embedded{Port: 1}}
+	updated := compareConfig{Host: "b", Port: 80, Password: "y", Level: &level, Nested: Embedded{Port: 2}}
+	diffs, err := NewWithLookup("APP_", nil, nil).Diff(&old, &updated)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []FieldDiff{
+			{Field: "Host", Var: "APP_HOST", Left: "a", Right: "b"},
+			{Field: "Password", Var: "APP_PASSWORD", Secret: true, Left: "***", Right: "***"},
+			{Field: "Level", Var: "APP_LEVEL", Left: "<nil>", Right: "2"},
+			{Field: "Nested.Port", Var: "APP_NESTED_PORT", Left: "1", Right: "2"},
+		}, diffs)
+	}
+
+	diffs, err = Diff(&old, &old)
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+
+	_, err = Diff(old, &updated)
+	assert.Equal(t, ErrStructPointer, err)
+	_, err = Diff(&old, &Embedded{})
+	assert.EqualError(t, err, "cannot compare *env.compareConfig with *env.Embedded")
+}
@@ -0,0 +1,311 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// constraints holds the constraints on the value of a field, which are checked after the value is parsed.
+type constraints struct {
+	// oneof lists the allowed values from the "oneof" tag, formatted like the values of the field.
+	oneof []string
+	// min and max are the bounds from the "min" and "max" tags, parsed into the type of the values of the field,
+	// or invalid values if there are no such tags.
+	min, max reflect.Value
+	// minLen and maxLen are the lengths from the "minlen" and "maxlen" tags, or -1 if there are no such tags.
+	minLen, maxLen int
+	// match is the regular expression from the "match" tag, or nil if there is no such tag. It is compiled once with
+	// the plan of the struct.
+	match *regexp.Regexp
+}
+
+// parseConstraints returns the constraints declared by the tags of a struct field, or nil if there are none.
+// The values in the tags of a field tagged with the "bytes" option are byte sizes, like the values of the field.
+func parseConstraints(sf reflect.StructField, encoding string) (*constraints, error) {
+	c := constraints{minLen: -1, maxLen: -1}
+	t := constrainedType(sf.Type)
+	if encoding != "bytes" {
+		encoding = ""
+	}
+
+	oneof, err := splitTagList(sf.Tag.Get("oneof"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid oneof tag %q: %w", sf.Tag.Get("oneof"), err)
+	}
+	for _, value := range oneof {
+		v, err := decodeValue(encoding, value)
+		if err == nil {
+			v, err = normalizeValue(t, v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid oneof tag %q: %w", sf.Tag.Get("oneof"), err)
+		}
+		c.oneof = append(c.oneof, v)
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value *reflect.Value
+	}{{"min", &c.min}, {"max", &c.max}} {
+		tag, ok := sf.Tag.Lookup(bound.name)
+		if !ok {
+			continue
+		}
+		if !isNumber(t) {
+			return nil, fmt.Errorf("the %v tag requires a numeric field", bound.name)
+		}
+		v := reflect.New(t)
+		s, err := decodeValue(encoding, strings.TrimSpace(tag))
+		if err == nil {
+			err = setValue(v, s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v tag %q: %w", bound.name, tag, err)
+		}
+		*bound.value = v.Elem()
+	}
+	if c.min.IsValid() && c.max.IsValid() && compareNumbers(c.min, c.max) > 0 {
+		return nil, fmt.Errorf("the min tag %q is greater than the max tag %q", sf.Tag.Get("min"), sf.Tag.Get("max"))
+	}
+
+	for _, length := range []struct {
+		name  string
+		value *int
+	}{{"minlen", &c.minLen}, {"maxlen", &c.maxLen}} {
+		tag, ok := sf.Tag.Lookup(length.name)
+		if !ok {
+			continue
+		}
+		if k := indirectType(sf.Type).Kind(); k != reflect.String && k != reflect.Slice && k != reflect.Map {
+			return nil, fmt.Errorf("the %v tag requires a string, slice or map field", length.name)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(tag))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %v tag %q: must be a non-negative integer", length.name, tag)
+		}
+		*length.value = n
+	}
+	if c.minLen >= 0 && c.maxLen >= 0 && c.minLen > c.maxLen {
+		return nil, fmt.Errorf("the minlen tag %v is greater than the maxlen tag %v", c.minLen, c.maxLen)
+	}
+
+	if tag, ok := sf.Tag.Lookup("match"); ok {
+		if t.Kind() != reflect.String {
+			return nil, errors.New("the match tag requires a string field")
+		}
+		if c.match, err = regexp.Compile(tag); err != nil {
+			return nil, fmt.Errorf("invalid match tag %q: %w", tag, err)
+		}
+	}
+
+	if c.oneof == nil && !c.min.IsValid() && !c.max.IsValid() && c.minLen < 0 && c.maxLen < 0 && c.match == nil {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// constrainedType returns the type of the values checked against the constraints of a field of type t, which is
+// the type of its elements for a slice other than []byte, without pointers.
+func constrainedType(t reflect.Type) reflect.Type {
+	t = indirectType(t)
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = indirectType(t.Elem())
+	}
+	return t
+}
+
+// normalizeValue returns a value of a primary type as formatted after being parsed (e.g. "1" for "01" if t is an
+// integer type), so that it can be compared with the values of fields. Values of other types are returned as is.
+func normalizeValue(t reflect.Type, value string) (string, error) {
+	if valueFormat(t) != t.Kind().String() {
+		return value, nil
+	}
+	v := reflect.New(t)
+	if err := setValue(v, value); err != nil {
+		return "", err
+	}
+	return formatVar(v.Elem())
+}
+
+// isNumber returns whether t is an integer or floating-point type parsed as a number.
+func isNumber(t reflect.Type) bool {
+	return valueFormat(t) == t.Kind().String() && (isInteger(t) || t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64)
+}
+
+// compareNumbers returns -1, 0 or 1 depending on whether the number a is less than, equal to or greater than b,
+// which is of the same type.
+func compareNumbers(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	}
+	return cmp.Compare(a.Float(), b.Float())
+}
+
+// check checks the value of a field against the constraints, along with the elements of a slice other than []byte.
+// The errors describe the violated constraint, such as "must be one of a, b".
+func (c *constraints) check(field reflect.Value) error {
+	field = indirectValue(field)
+	if !field.IsValid() {
+		return nil
+	}
+	if c.minLen >= 0 || c.maxLen >= 0 {
+		n, unit := field.Len(), "elements"
+		switch {
+		case field.Kind() == reflect.String:
+			n, unit = utf8.RuneCountInString(field.String()), "characters"
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+			unit = "bytes"
+		}
+		if c.minLen >= 0 && n < c.minLen {
+			return fmt.Errorf("must have at least %v %v", c.minLen, unit)
+		}
+		if c.maxLen >= 0 && n > c.maxLen {
+			return fmt.Errorf("must have at most %v %v", c.maxLen, unit)
+		}
+	}
+	return c.checkValue(field)
+}
+
+// checkValue checks a value against the constraints on the values of a field, which apply to the elements of
+// a slice other than []byte.
+func (c *constraints) checkValue(v reflect.Value) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			if elem := indirectValue(v.Index(i)); elem.IsValid() {
+				if err := c.checkValue(elem); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if c.oneof != nil {
+		s, err := formatVar(v)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(c.oneof, s) {
+			return fmt.Errorf("must be one of %v", strings.Join(c.oneof, ", "))
+		}
+	}
+	if c.min.IsValid() && compareNumbers(v, c.min) < 0 {
+		return fmt.Errorf("must be at least %v", c.min)
+	}
+	if c.max.IsValid() && compareNumbers(v, c.max) > 0 {
+		return fmt.Errorf("must be at most %v", c.max)
+	}
+	if c.match != nil && !c.match.MatchString(v.String()) {
+		return fmt.Errorf("must match %v", c.match)
+	}
+	return nil
+}
+
+// indirectValue returns the value pointed to by v through any number of pointers, or an invalid value if one of
+// them is nil.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// generatable returns whether Random can generate values satisfying the constraints for a field of type t,
+// which it cannot do for slices with constraints, for lengths of values other than strings, nor for patterns
+// without a list of allowed values.
+// It is true if there are no constraints.
+func (c *constraints) generatable(t reflect.Type) bool {
+	if c == nil {
+		return true
+	}
+	if constrainedType(t) != indirectType(t) || (c.match != nil && c.oneof == nil) {
+		return false
+	}
+	return (c.minLen < 0 && c.maxLen < 0) || indirectType(t).Kind() == reflect.String
+}
+
+// random returns a random value of type t satisfying the constraints, formatted like a variable value, or false if
+// there are no constraints. The constraints must be generatable for t.
+func (c *constraints) random(t reflect.Type, r *rand.Rand) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	t = indirectType(t)
+	if c.oneof != nil {
+		return c.oneof[r.Intn(len(c.oneof))], true
+	}
+	if c.minLen >= 0 || c.maxLen >= 0 {
+		lo, hi := max(c.minLen, 0), c.maxLen
+		if hi < 0 {
+			hi = lo + 16
+		}
+		b := randomBytes(r)
+		for len(b) < hi {
+			b = append(b, randomBytes(r)...)
+		}
+		return string(b[:lo+r.Intn(hi-lo+1)]), true
+	}
+
+	// numbers are drawn within the bounds, or within 1000 of the only bound
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if c.min.IsValid() {
+		lo = toFloat(c.min)
+	}
+	if c.max.IsValid() {
+		hi = toFloat(c.max)
+	}
+	if math.IsInf(lo, -1) {
+		lo = hi - 1000
+	} else if math.IsInf(hi, 1) {
+		hi = lo + 1000
+	}
+	n := lo + r.Float64()*(hi-lo)
+	v := reflect.New(t).Elem()
+	switch {
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		v.SetFloat(n)
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		v.SetUint(uint64(math.Max(math.Ceil(n), 0)))
+	default:
+		v.SetInt(int64(math.Ceil(n)))
+	}
+	// values that are out of bounds due to rounding or overflows fall back to a bound
+	if c.checkValue(v) != nil {
+		if c.min.IsValid() {
+			v = c.min
+		} else {
+			v = c.max
+		}
+	}
+	s, _ := formatVar(v)
+	return s, true
+}
+
+// toFloat returns a number as a float64.
+func toFloat(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
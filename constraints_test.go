@@ -0,0 +1,130 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type constrainedConfig struct {
+	Level   string   `oneof:"debug, info,warn,error" default:"info"`
+	Workers int      `oneof:"1,2,4,8"`
+	Modes   []string `oneof:"r,w"`
+	Port    *int     `oneof:"80,443"`
+	Retries uint8    `min:"1" max:"10" default:"3"`
+	Ratio   float64  `min:"0" max:"1"`
+	Timeout int      `max:"60"`
+	Name    string   `minlen:"2" maxlen:"8"`
+	Tags    []string `maxlen:"2"`
+	Limits  []int    `min:"1"`
+	Body    int64    `env:",bytes" max:"1MiB"`
+	Slug    string   `match:"^[a-z0-9-]+$" default:"app"`
+	Hosts   []string `match:"^[a-z.]+$"`
+}
+
+func TestLoader_Load_constraints(t *testing.T) {
+	tests := []struct {
+		tag  string
+		vars map[string]string
+		err  string
+	}{
+		{"t1", map[string]string{"APP_LEVEL": "warn", "APP_WORKERS": "04", "APP_MODES": `["r","w"]`, "APP_PORT": "443"}, ""},
+		{"t2", map[string]string{"APP_LEVEL": "trace"}, "$APP_LEVEL: must be one of debug, info, warn, error"},
+		{"t3", map[string]string{"APP_WORKERS": "3"}, "$APP_WORKERS: must be one of 1, 2, 4, 8"},
+		{"t4", map[string]string{"APP_MODES": `["r","x"]`}, "$APP_MODES: must be one of r, w"},
+		{"t5", map[string]string{"APP_PORT": "8080"}, "$APP_PORT: must be one of 80, 443"},
+		{"t6", map[string]string{"APP_RETRIES": "0"}, "$APP_RETRIES: must be at least 1"},
+		{"t7", map[string]string{"APP_RETRIES": "11"}, "$APP_RETRIES: must be at most 10"},
+		{"t8", map[string]string{"APP_RATIO": "1.5"}, "$APP_RATIO: must be at most 1"},
+		{"t9", map[string]string{"APP_TIMEOUT": "-5"}, ""},
+		{"t10", map[string]string{"APP_NAME": "é"}, "$APP_NAME: must have at least 2 characters"},
+		{"t11", map[string]string{"APP_NAME": "éééééééé"}, ""},
+		{"t12", map[string]string{"APP_TAGS": `["a","b","c"]`}, "$APP_TAGS: must have at most 2 elements"},
+		{"t13", map[string]string{"APP_LIMITS": `[1,0]`}, "$APP_LIMITS: must be at least 1"},
+		{"t14", map[string]string{"APP_BODY": "2MB"}, "$APP_BODY: must be at most 1048576"},
+		{"t15", map[string]string{"APP_SLUG": "my-app-2"}, ""},
+		{"t16", map[string]string{"APP_SLUG": "My App"}, "$APP_SLUG: must match ^[a-z0-9-]+$"},
+		{"t17", map[string]string{"APP_HOSTS": `["a.com","b_c"]`}, "$APP_HOSTS: must match ^[a-z.]+$"},
+	}
+	for _, test := range tests {
+		var cfg constrainedConfig
+		err := NewWithLookup("APP_", MapLookup(test.vars), nil).Load(&cfg)
+		if test.err == "" {
+			assert.Nil(t, err, test.tag)
+		} else {
+			assert.EqualError(t, err, test.err, test.tag)
+		}
+	}
+
+	var fe *FieldError
+	var cfg constrainedConfig
+	err := NewWithLookup("APP_", MapLookup(map[string]string{"APP_LEVEL": "trace"}), nil).Load(&cfg)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Level", fe.Field)
+		assert.Equal(t, "trace", fe.Value)
+	}
+
+	var invalid struct {
+		Level string `oneof:"debug,info" default:"trace"`
+	}
+	assert.EqualError(t, NewWithLookup("APP_", MapLookup(nil), nil).Load(&invalid), "$APP_LEVEL: must be one of debug, info")
+
+	badTags := []struct {
+		tag string
+		cfg interface{}
+		err string
+	}{
+		{"t1", &struct {
+			Workers int `oneof:"1,many"`
+		}{}, `Workers: invalid oneof tag "1,many": strconv.ParseInt: parsing "many": invalid syntax`},
+		{"t2", &struct {
+			Name string `min:"1"`
+		}{}, "Name: the min tag requires a numeric field"},
+		{"t3", &struct {
+			Port int `min:"x"`
+		}{}, `Port: invalid min tag "x": strconv.ParseInt: parsing "x": invalid syntax`},
+		{"t4", &struct {
+			Port int `min:"10" max:"1"`
+		}{}, `Port: the min tag "10" is greater than the max tag "1"`},
+		{"t5", &struct {
+			Port int `maxlen:"1"`
+		}{}, "Port: the maxlen tag requires a string, slice or map field"},
+		{"t6", &struct {
+			Name string `minlen:"-1"`
+		}{}, `Name: invalid minlen tag "-1": must be a non-negative integer`},
+		{"t7", &struct {
+			Name string `minlen:"3" maxlen:"2"`
+		}{}, "Name: the minlen tag 3 is greater than the maxlen tag 2"},
+		{"t8", &struct {
+			Port int `match:"^1"`
+		}{}, "Port: the match tag requires a string field"},
+		{"t9", &struct {
+			Name string `match:"("`
+		}{}, "Name: invalid match tag \"(\": error parsing regexp: missing closing ): `(`"},
+	}
+	for _, test := range badTags {
+		err = NewWithLookup("APP_", MapLookup(nil), nil).Load(test.cfg)
+		assert.EqualError(t, err, test.err, test.tag)
+	}
+}
+
+func TestLoader_Random_constraints(t *testing.T) {
+	l := NewWithLookup("APP_", nil, nil)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		var cfg constrainedConfig
+		vars, err := l.Random(&cfg, r)
+		if !assert.Nil(t, err) {
+			return
+		}
+		var loaded constrainedConfig
+		assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&loaded))
+		assert.Equal(t, cfg, loaded)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+)
+
+type (
+	// ConsulConfig configures a Consul KV lookup.
+	ConsulConfig struct {
+		// Address is the address of the Consul agent. Defaults to $CONSUL_HTTP_ADDR or "http://127.0.0.1:8500".
+		Address string
+		// Datacenter is the datacenter to query. Defaults to the datacenter of the agent.
+		Datacenter string
+		// Token is the ACL token. Defaults to $CONSUL_HTTP_TOKEN.
+		Token string
+		// KeyTemplate is a text/template that produces the key path of a variable. The template is executed with
+		// a value whose Name field holds the variable name, and may use the "lower", "upper" and "replace"
+		// (strings.ReplaceAll) functions. For example, "myapp/{{.Name | lower}}" reads $APP_PORT from the
+		// "myapp/app_port" key. Defaults to "{{.Name}}".
+		KeyTemplate string
+		// Client is the HTTP client used to talk to Consul. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// Consul looks up values from the Consul KV store.
+	Consul struct {
+		config ConsulConfig
+		key    *template.Template
+		errs   lookupErrors
+	}
+)
+
+// consulFuncs are the functions available to a Consul key template.
+var consulFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": strings.ReplaceAll,
+}
+
+// NewConsul creates a Consul KV lookup. It checks that the agent is reachable,
+// so that any configuration or connectivity problem is reported immediately.
+//
+// Use the Lookup method as a LookupFunc, for example:
+//
+//	consul, err := env.NewConsul(env.ConsulConfig{KeyTemplate: "myapp/{{.Name}}"})
+//	...
+//	loader := env.NewWithLookup("APP_", consul.Lookup, log.Printf)
+func NewConsul(config ConsulConfig) (*Consul, error) {
+	if config.Address == "" {
+		config.Address = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if config.Address == "" {
+		config.Address = "http://127.0.0.1:8500"
+	}
+	if !strings.Contains(config.Address, "://") {
+		config.Address = "http://" + config.Address
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	if config.Token == "" {
+		config.Token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	if config.KeyTemplate == "" {
+		config.KeyTemplate = "{{.Name}}"
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	key, err := template.New("key").Funcs(consulFuncs).Parse(config.KeyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	}
+	c := &Consul{config: config, key: key}
+	if _, _, err := c.get(context.Background(), "status/leader", url.Values{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Lookup returns the value of the key corresponding to the given variable name.
+// It can be used as a LookupFunc. A key that cannot be read is treated as not found,
+// and the failure is reported by Err.
+func (c *Consul) Lookup(name string) (string, bool) {
+	value, ok, err := c.LookupContext(context.Background(), name)
+	c.errs.record(name, err)
+	return value, ok
+}
+
+// LookupContext returns the value of the key corresponding to the given variable name.
+// Unlike Lookup, it returns an error if the key cannot be read for reasons other than not existing.
+func (c *Consul) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	var key strings.Builder
+	if err := c.key.Execute(&key, struct{ Name string }{name}); err != nil {
+		return "", false, fmt.Errorf("consul: %w", err)
+	}
+	return c.get(ctx, "kv/"+strings.TrimPrefix(key.String(), "/"), url.Values{"raw": {""}})
+}
+
+// Err returns the failures of the most recent Lookup calls for names that could not be read,
+// or nil if every name was read successfully.
+func (c *Consul) Err() error {
+	return c.errs.err()
+}
+
+// LookupStats returns the number of Lookup calls that failed to read a key, and no cache hits, so that the failures
+// are counted in the statistics of a loader (see WithStatsReporters).
+func (c *Consul) LookupStats() (cacheHits, errors uint64) {
+	return c.errs.stats()
+}
+
+// get sends a GET request to the Consul HTTP API and returns the response body.
+// It returns false if the response status is 404.
+func (c *Consul) get(ctx context.Context, path string, query url.Values) (string, bool, error) {
+	if c.config.Datacenter != "" {
+		query.Set("dc", c.config.Datacenter)
+	}
+	u := &url.URL{Path: "/v1/" + path, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.Address+u.String(), nil)
+	if err != nil {
+		return "", false, fmt.Errorf("consul: %w", err)
+	}
+	if c.config.Token != "" {
+		req.Header.Set("X-Consul-Token", c.config.Token)
+	}
+
+	res, err := c.config.Client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("consul: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("consul: %w", err)
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return "", false, nil
+	case res.StatusCode != http.StatusOK:
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return "", false, fmt.Errorf("consul: %v: %v", u.Path, msg)
+		}
+		return "", false, fmt.Errorf("consul: %v: %v", u.Path, res.Status)
+	}
+	return string(body), true, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsul_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "t1" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("ACL not found"))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/status/leader":
+			_, _ = w.Write([]byte(`"10.0.0.1:8300"`))
+		case "/v1/kv/myapp/app_port":
+			_, ok := r.URL.Query()["raw"]
+			assert.True(t, ok)
+			if r.URL.Query().Get("dc") == "dc2" {
+				_, _ = w.Write([]byte("9090"))
+				return
+			}
+			_, _ = w.Write([]byte("8080"))
+		case "/v1/kv/myapp/app_denied":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewConsul(ConsulConfig{Address: server.URL, Token: "t1", KeyTemplate: "/myapp/{{.Name | lower}}"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	value, ok := c.Lookup("APP_PORT")
+	assert.True(t, ok)
+	assert.Equal(t, "8080", value)
+	_, ok = c.Lookup("APP_HOST")
+	assert.False(t, ok)
+	assert.Nil(t, c.Err())
+	_, ok = c.Lookup("APP_DENIED")
+	assert.False(t, ok)
+	assert.EqualError(t, c.Err(), "consul: /v1/kv/myapp/app_denied: 500 Internal Server Error")
+	hits, failures := c.LookupStats()
+	assert.Equal(t, uint64(0), hits)
+	assert.Equal(t, uint64(1), failures)
+
+	c, err = NewConsul(ConsulConfig{Address: server.URL, Token: "t1", Datacenter: "dc2", KeyTemplate: `myapp/{{replace .Name "X" "" | lower}}`})
+	if assert.Nil(t, err) {
+		value, _, err = c.LookupContext(context.Background(), "XAPP_PORT")
+		assert.Nil(t, err)
+		assert.Equal(t, "9090", value)
+	}
+
+	_, err = NewConsul(ConsulConfig{Address: server.URL, Token: "t2"})
+	assert.EqualError(t, err, "consul: /v1/status/leader: ACL not found")
+	_, err = NewConsul(ConsulConfig{Address: server.URL, Token: "t1", KeyTemplate: "{{.Name"})
+	assert.NotNil(t, err)
+	c, err = NewConsul(ConsulConfig{Address: server.URL, Token: "t1", KeyTemplate: "{{.Missing}}"})
+	if assert.Nil(t, err) {
+		_, _, err = c.LookupContext(context.Background(), "APP_PORT")
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Doc returns the Markdown documentation of the variables read by the package-level Load function.
+// For more details, please refer to Loader.Doc().
+func Doc(structPtr interface{}) (string, error) {
+	return loader.Doc(structPtr)
+}
+
+// Doc returns a Markdown table documenting every variable read by Load for the given struct, in the order of
+// the struct fields. The struct must be specified as a pointer. The table lists the name, type, default value
+// and required-ness of each variable, along with the description given by the field's "desc" tag, for example:
+//
+//	type Config struct {
+//		Port int `default:"80" desc:"The port the server listens on."`
+//	}
+func (l *Loader) Doc(structPtr interface{}) (string, error) {
+	m, err := l.ExportManifest(structPtr)
+	if err != nil {
+		return "", err
+	}
+	return m.Markdown(), nil
+}
+
+// Markdown returns a Markdown table documenting the variables in the manifest. For more details,
+// please refer to Loader.Doc().
+func (m *Manifest) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Variable | Type | Default | Required | Description |\n")
+	b.WriteString("|----------|------|---------|----------|-------------|\n")
+	for _, v := range m.Vars {
+		def, required := "", ""
+		if v.Default != "" {
+			def = "`" + markdownEscape(v.Default) + "`"
+		}
+		if v.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&b, "| `%v` | %v | %v | %v | %v |\n", v.Name, v.Type, def, required, markdownEscape(v.Description))
+	}
+	return b.String()
+}
+
+// markdownEscape escapes a value so that it can be placed in a Markdown table cell.
+func markdownEscape(value string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(value)
+}
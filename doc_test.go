@@ -0,0 +1,31 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Doc(t *testing.T) {
+	type config struct {
+		Host     string `required:"true" desc:"The host name."`
+		Port     int    `default:"80" desc:"The port to listen on."`
+		Password string `env:",secret"`
+		Pattern  string `default:"a|b" desc:"A pattern,\nsuch as a|b."`
+	}
+	doc, err := NewWithLookup("APP_", mockLookup, nil).Doc(&config{})
+	assert.Nil(t, err)
+	assert.Equal(t, "| Variable | Type | Default | Required | Description |\n"+
+		"|----------|------|---------|----------|-------------|\n"+
+		"| `APP_HOST` | string |  | yes | The host name. |\n"+
+		"| `APP_PORT` | int | `80` |  | The port to listen on. |\n"+
+		"| `APP_PASSWORD` | string |  |  |  |\n"+
+		"| `APP_PATTERN` | string | `a\\|b` |  | A pattern,<br>such as a\\|b. |\n", doc)
+
+	_, err = Doc(config{})
+	assert.Equal(t, ErrStructPointer, err)
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FileLookup reads a file in the .env format and returns a LookupFunc that looks up the variables defined in it.
+func FileLookup(file string) (LookupFunc, error) {
+	vars, err := readDotenvFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return MapLookup(vars), nil
+}
+
+// Parse reads data in the .env format from r and returns the variables defined in it. It makes it possible to load
+// variables from in-memory buffers, embedded files or network streams. Each line defines a variable as NAME=VALUE,
+// optionally preceded by "export". Blank lines and lines starting with "#" are ignored, and values may be quoted.
+//
+// Data encrypted with age, and .env files encrypted with SOPS using age keys, are decrypted transparently with
+// the keys in $SOPS_AGE_KEY, in the file named by $SOPS_AGE_KEY_FILE or in the default key file of SOPS. This also
+// applies to FileLookup, LoaderFromReader and FileSource.
+func Parse(r io.Reader) (map[string]string, error) {
+	return parseDotenv(r)
+}
+
+// LoaderFromReader reads data in the .env format from r and creates a loader looking up the variables defined in it.
+// The prefix, log function and options are used as with NewWithLookup.
+func LoaderFromReader(prefix string, r io.Reader, log LogFunc, opts ...Option) (*Loader, error) {
+	vars, err := parseDotenv(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithLookup(prefix, MapLookup(vars), log, opts...), nil
+}
+
+// readDotenvFile reads a file in the .env format and returns the variables defined in it.
+func readDotenvFile(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDotenv(f)
+}
+
+// parseDotenv parses data in the .env format and returns the variables defined in it.
+//
+// Each line defines a variable in the format of NAME=VALUE, optionally preceded by "export".
+// Blank lines and lines starting with "#" are ignored. Values may be enclosed in single quotes (taken literally)
+// or double quotes (supporting \n, \r, \t, \" and \\ escapes); quoted values may span multiple lines.
+// For unquoted values, anything after a " #" is treated as a comment.
+// Data encrypted with age is decrypted first, and files encrypted with SOPS are read by decryptSopsDotenv.
+func parseDotenv(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err == nil {
+		data, err = decryptAge(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isSopsDotenv(data) {
+		return decryptSopsDotenv(data)
+	}
+	p := &dotenvParser{data: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+	vars := map[string]string{}
+	for {
+		name, value, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return vars, nil
+		}
+		vars[name] = value
+	}
+}
+
+// dotenvParser is a parser of the .env format.
+type dotenvParser struct {
+	data string
+	pos  int
+	line int
+}
+
+// next parses the next variable definition. It returns false if the end of the data is reached.
+func (p *dotenvParser) next() (string, string, bool, error) {
+	for p.pos < len(p.data) {
+		start := p.pos
+		line := p.readLine()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' {
+			p.line++
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return "", "", false, fmt.Errorf("line %v: missing '='", p.line)
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[:i]), "export "))
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return "", "", false, fmt.Errorf("line %v: invalid variable name %q", p.line, name)
+		}
+
+		// the value starts after "=" and may continue over multiple lines if it is quoted
+		p.pos = start + i + 1
+		value, err := p.readValue()
+		if err != nil {
+			return "", "", false, err
+		}
+		p.line++
+		return name, value, true, nil
+	}
+	return "", "", false, nil
+}
+
+// readLine reads the rest of the current line, consuming the line break.
+func (p *dotenvParser) readLine() string {
+	end := strings.IndexByte(p.data[p.pos:], '\n')
+	if end < 0 {
+		line := p.data[p.pos:]
+		p.pos = len(p.data)
+		return line
+	}
+	line := p.data[p.pos : p.pos+end]
+	p.pos += end + 1
+	return line
+}
+
+// readValue reads a variable value starting at the current position, consuming the rest of the line.
+func (p *dotenvParser) readValue() (string, error) {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos >= len(p.data) {
+		return "", nil
+	}
+
+	quote := p.data[p.pos]
+	if quote != '"' && quote != '\'' {
+		value := p.readLine()
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value), nil
+	}
+
+	line := p.line
+	var sb strings.Builder
+	for p.pos++; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			rest := strings.TrimSpace(p.readLine())
+			if rest != "" && rest[0] != '#' {
+				return "", fmt.Errorf("line %v: unexpected characters after the quoted value", p.line)
+			}
+			return sb.String(), nil
+		case c == '\\' && quote == '"' && p.pos+1 < len(p.data):
+			p.pos++
+			switch e := p.data[p.pos]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				if e != '"' && e != '\\' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteByte(e)
+				if e == '\n' {
+					p.line++
+				}
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			sb.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("line %v: unterminated quoted value", line)
+}
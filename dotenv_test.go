@@ -0,0 +1,61 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDotenv(t *testing.T) {
+	tests := []struct {
+		tag      string
+		input    string
+		expected map[string]string
+		err      string
+	}{
+		{"t1", "", map[string]string{}, ""},
+		{"t2", "# comment\n\nA=1\nB = 2 \n", map[string]string{"A": "1", "B": "2"}, ""},
+		{"t3", "export A=1\r\nB=x # comment\nC=a#b", map[string]string{"A": "1", "B": "x", "C": "a#b"}, ""},
+		{"t4", `A="x\ny\"z\\" # comment` + "\nB='x\\ny'", map[string]string{"A": "x\ny\"z\\", "B": `x\ny`}, ""},
+		{"t5", "A=\"line1\nline2\"\nB=", map[string]string{"A": "line1\nline2", "B": ""}, ""},
+		{"t6", "A=1\nB", nil, "line 2: missing '='"},
+		{"t7", "A B=1", nil, `line 1: invalid variable name "A B"`},
+		{"t8", "A=1\nB=\"x\n\nC=2", nil, "line 2: unterminated quoted value"},
+		{"t9", "A='x' y", nil, "line 1: unexpected characters after the quoted value"},
+	}
+
+	for _, test := range tests {
+		vars, err := parseDotenv(strings.NewReader(test.input))
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.tag)
+		} else if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, test.expected, vars, test.tag)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	vars, err := Parse(strings.NewReader("APP_HOST=localhost\nAPP_PORT=8080\n"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"APP_HOST": "localhost", "APP_PORT": "8080"}, vars)
+	}
+}
+
+func TestLoaderFromReader(t *testing.T) {
+	l, err := LoaderFromReader("APP_", strings.NewReader("APP_HOST=localhost\nAPP_PORT=8080\n"), nil)
+	if assert.Nil(t, err) {
+		var cfg Config1
+		if assert.Nil(t, l.Load(&cfg)) {
+			assert.Equal(t, "localhost", cfg.Host)
+			assert.Equal(t, 8080, cfg.Port)
+		}
+	}
+
+	_, err = LoaderFromReader("APP_", strings.NewReader("APP_HOST"), nil)
+	assert.EqualError(t, err, "line 1: missing '='")
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// Dump returns the environment variables that the package-level Load function would read to populate a struct
+// with its current values. For more details, please refer to Loader.Dump().
+func Dump(structPtr interface{}) (map[string]string, error) {
+	return loader.Dump(structPtr)
+}
+
+// Dump reverses Load: it returns the variables, keyed by their names including the loader prefix, that would
+// populate a struct with its current values. The struct must be specified as a pointer. The result can be used,
+// for example, to pass the configuration to a subprocess or to print it for debugging.
+//
+// Field values are formatted as follows:
+//   - types implementing TextMarshaler or BinaryMarshaler: the corresponding interface method is used
+//   - types implementing Setter and fmt.Stringer: the String method is used
+//   - primary types (e.g. int, string), byte slices and byte arrays: the values are formatted as Load parses them,
+//     and byte slices and arrays tagged with the "base64" or "hex" option are encoded accordingly
+//   - other types (e.g. slice, map): the values are encoded in JSON format
+//
+// A map field tagged with the "prefix" option is returned as one variable per entry, named after the prefix
+// followed by the key.
+//
+// Fields with nil pointer values, fields of unsupported kinds, and fields that are not loaded in the loader's
+// environment are omitted.
+// The values of fields tagged as secret are included as is, so the result must be handled with care.
+func (l *Loader) Dump(structPtr interface{}) (map[string]string, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+	fields, err := l.getFields(value.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{}
+	for _, f := range fields {
+		if !l.active(f) || !supportedKind(elemKind(f.typ)) {
+			continue
+		}
+		v := reflect.ValueOf(fieldValue(value.Elem(), f.index))
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if !v.IsValid() || v.Kind() == reflect.Ptr {
+			continue
+		}
+		if f.collect {
+			collectedVars(vars, l.prefix+f.name, v)
+			continue
+		}
+		s, err := formatVar(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.path, err)
+		}
+		vars[l.prefix+f.name] = f.encode(s)
+	}
+	return vars, nil
+}
+
+// formatVar formats a value into a string that setValue parses back into the same value.
+func formatVar(v reflect.Value) (string, error) {
+	// use an addressable copy so that methods with pointer receivers can be called
+	pv := reflect.New(v.Type())
+	pv.Elem().Set(v)
+	p := pv.Interface()
+	if m, ok := p.(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if m, ok := p.(encoding.BinaryMarshaler); ok {
+		b, err := m.MarshalBinary()
+		return string(b), err
+	}
+	if _, ok := p.(Setter); ok {
+		if s, ok := p.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+	}
+	if values, ok := p.(*url.Values); ok {
+		return values.Encode(), nil
+	}
+	if n, ok := p.(*net.IPNet); ok {
+		return n.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return string(b), nil
+		}
+	}
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dumpConfig struct {
+	Host     string
+	Port     int
+	Ratio    float32
+	Debug    bool   `env:"DEBUG_MODE"`
+	Password []byte `env:",secret"`
+	Tags     []string
+	Params   url.Values
+	Timeout  time.Duration
+	IP       net.IP
+	Level    *uint8
+	Missing  *int
+	Nested   *Embedded `prefix:"NESTED_"`
+	None     *Embedded `prefix:"NONE_"`
+	Callback func()
+	Ignored  string `env:"-"`
+	Dev      string `envs:"development"`
+}
+
+func TestLoader_Dump(t *testing.T) {
+	level := uint8(3)
+	cfg := dumpConfig{
+		Host:     "localhost",
+		Port:     8080,
+		Ratio:    0.1,
+		Debug:    true,
+		Password: []byte("xyz"),
+		Tags:     []string{"a", "b"},
+		Params:   url.Values{"b": {"2"}, "a": {"1", "3"}},
+		Timeout:  time.Second,
+		IP:       net.IPv4(127, 0, 0, 1),
+		Level:    &level,
+		Nested:   &Embedded{URL: "http://example.com"},
+		Ignored:  "x",
+		Dev:      "y",
+	}
+	l := NewWithLookup("APP_", mockLookup, nil)
+	vars, err := l.Dump(&cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]string{
+		"APP_HOST":        "localhost",
+		"APP_PORT":        "8080",
+		"APP_RATIO":       "0.1",
+		"APP_DEBUG_MODE":  "true",
+		"APP_PASSWORD":    "xyz",
+		"APP_TAGS":        `["a","b"]`,
+		"APP_PARAMS":      "a=1&a=3&b=2",
+		"APP_TIMEOUT":     "1000000000",
+		"APP_IP":          "127.0.0.1",
+		"APP_LEVEL":       "3",
+		"APP_NESTED_URL":  "http://example.com",
+		"APP_NESTED_PORT": "0",
+	}, vars)
+
+	var cfg2 dumpConfig
+	if assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&cfg2)) {
+		cfg.Ignored, cfg.Dev, cfg2.None = "", "", nil
+		assert.Equal(t, cfg, cfg2)
+	}
+
+	_, err = l.Dump(cfg)
+	assert.Equal(t, ErrStructPointer, err)
+
+	vars, err = Dump(&Config1{Host: "localhost"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "localhost", vars["APP_HOST"])
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxElementDepth is the maximum nesting of slice elements whose variables are looked up to find whether an element
+// is set, which guards against recursive types.
+const maxElementDepth = 8
+
+// assignIndexed populates a slice field of nested structs from numbered variables, where the variables of
+// the element at index i are prefixed with the name of the field followed by i (e.g. $APP_SERVERS_0_HOST and
+// $APP_SERVERS_1_HOST). Elements are read from index 0 until an index for which no variable is set.
+// It returns whether any element was found, in which case the field is replaced with a slice of the elements.
+func (l *Loader) assignIndexed(field reflect.Value, f *fieldInfo) (bool, error) {
+	field = indirect(field)
+	elemType := field.Type().Elem()
+	var elems []reflect.Value
+	for i := 0; ; i++ {
+		elem, ok, err := l.loadElement(f, strconv.Itoa(i), elemType)
+		if err != nil {
+			return false, elementError(fmt.Sprintf("%v[%v]", f.path, i), err)
+		}
+		if !ok {
+			break
+		}
+		elems = append(elems, elem)
+	}
+	if len(elems) == 0 {
+		return false, nil
+	}
+	slice := reflect.MakeSlice(field.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		slice.Index(i).Set(elem)
+	}
+	field.Set(slice)
+	return true, nil
+}
+
+// assignKeyed populates a map field of nested structs from the variables prefixed with the name of the field
+// followed by the keys of the elements (e.g. $APP_UPSTREAMS_PAYMENTS_URL and $APP_UPSTREAMS_BILLING_URL populate
+// the keys "PAYMENTS" and "BILLING"). It returns whether any element was found, in which case the field is replaced
+// with a map of the elements.
+func (l *Loader) assignKeyed(field reflect.Value, f *fieldInfo) (bool, error) {
+	field = indirect(field)
+	keys, err := l.elementKeys(f)
+	if err != nil {
+		return false, err
+	}
+	m := reflect.MakeMapWithSize(field.Type(), len(keys))
+	for _, key := range keys {
+		elem, ok, err := l.loadElement(f, key, field.Type().Elem())
+		if err != nil {
+			return false, elementError(fmt.Sprintf("%v[%v]", f.path, key), err)
+		}
+		if ok {
+			m.SetMapIndex(reflect.ValueOf(key).Convert(field.Type().Key()), elem)
+		}
+	}
+	if m.Len() == 0 {
+		return false, nil
+	}
+	field.Set(m)
+	return true, nil
+}
+
+// elementKeys returns the sorted keys of the elements of a map field of nested structs, found in the names of
+// the variables in the process environment (see assignKeyed).
+func (l *Loader) elementKeys(f *fieldInfo) ([]string, error) {
+	fields, err := l.getFields(indirectType(indirectType(f.typ).Elem()))
+	if err != nil {
+		return nil, err
+	}
+	prefix := l.prefix + f.name + "_"
+	found := map[string]bool{}
+	var keys []string
+	for _, name := range l.collectNames(prefix) {
+		if key := elementKey(strings.TrimPrefix(name, prefix), fields); key != "" && !found[key] {
+			found[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// elementKey returns the key of an element of a map field from the rest of a variable name after the prefix of
+// the field, which is the key followed by the name of a variable of the element. If several variable names match,
+// the longest one is used (e.g. "USER_API_URL" gives the key "USER" if the element reads both $API_URL and $URL).
+// It returns an empty string if no variable name matches.
+func elementKey(rest string, fields []*fieldInfo) string {
+	key, matched := "", 0
+	for _, f := range fields {
+		for _, name := range append([]string{f.name}, f.alternatives()...) {
+			// the variables of the elements of nested slices, maps and collected fields follow the name
+			if f.collect {
+				name = strings.TrimSuffix(name, "_")
+			}
+			if f.collect || f.indexed || f.keyed {
+				if i := strings.Index(rest, "_"+name+"_"); i > 0 && len(name) > matched {
+					key, matched = rest[:i], len(name)
+				}
+			} else if strings.HasSuffix(rest, "_"+name) && len(rest) > len(name)+1 && len(name) > matched {
+				key, matched = strings.TrimSuffix(rest, "_"+name), len(name)
+			}
+		}
+	}
+	return key
+}
+
+// loadElement loads an element of the given type, a struct or a pointer to a struct, from the variables prefixed
+// with the name of a field followed by the given key. It returns false if none of these variables is set.
+func (l *Loader) loadElement(f *fieldInfo, key string, elemType reflect.Type) (reflect.Value, bool, error) {
+	el := l.elementLoader(f, key)
+	structType := indirectType(elemType)
+	fields, err := el.getFields(structType)
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	if !el.anySet(fields) {
+		return reflect.Value{}, false, nil
+	}
+	elem := reflect.New(structType)
+	if _, err := el.loadReport(elem.Interface()); err != nil {
+		return reflect.Value{}, false, err
+	}
+	if elemType.Kind() != reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem, true, nil
+}
+
+// elementLoader returns a copy of the loader that loads an element of a slice or map field from the variables
+// prefixed with the name of the field followed by the given index or key (e.g. "APP_SERVERS_0_"). Sources that name variables after
+// their key paths, such as file sources, are not consulted, as they provide such fields as a whole, and the variables
+// of the elements have no profiles.
+func (l *Loader) elementLoader(f *fieldInfo, key string) *Loader {
+	el := *l
+	el.prefix = l.prefix + f.name + "_" + key + "_"
+	if l.legacyPrefix != "" {
+		el.legacyPrefix = l.legacyPrefix + f.name + "_" + key + "_"
+	}
+	el.strict = false
+	el.profile = ""
+	el.elementDepth++
+	el.sources = nil
+	for _, source := range l.sources {
+		if source.Name == nil {
+			el.sources = append(el.sources, source)
+		}
+	}
+	return &el
+}
+
+// anySet returns whether a variable of any of the given fields that are loaded in the loader's environment is set.
+func (l *Loader) anySet(fields []*fieldInfo) bool {
+	for _, f := range fields {
+		if l.active(f) && l.isSet(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyed returns whether a field of type t can be populated from variables named after the keys of its elements,
+// that is, whether it is a map with string keys of nested structs or of pointers to them (see assignKeyed).
+func (o planOptions) isKeyed(t reflect.Type) bool {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return false
+	}
+	et := indirectType(t.Elem())
+	return isNestedStruct(et) && !o.hasParser(et)
+}
+
+// isIndexed returns whether a field of type t can be populated from numbered variables, that is, whether it is
+// a slice of nested structs or of pointers to them (see assignIndexed).
+func (o planOptions) isIndexed(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	et := indirectType(t.Elem())
+	return isNestedStruct(et) && !o.hasParser(et)
+}
+
+// elementError adds the path of an element (e.g. "Servers[0]") to an error loading it. The paths of field errors
+// are prefixed with it, while other errors are wrapped.
+func elementError(path string, err error) error {
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		e := *fe
+		e.Field = path + "." + fe.Field
+		return &e
+	}
+	return fmt.Errorf("%v: %w", path, err)
+}
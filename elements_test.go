@@ -0,0 +1,139 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type serverConfig struct {
+	Host   string `required:"true"`
+	Port   int    `default:"80"`
+	Routes []routeConfig
+}
+
+type routeConfig struct {
+	Path string
+}
+
+func TestLoader_Load_indexed(t *testing.T) {
+	var cfg struct {
+		Servers []serverConfig
+		Backups []*serverConfig `env:"BACKUP"`
+		Mirrors []serverConfig
+	}
+	vars := map[string]string{
+		"APP_SERVERS_0_HOST":          "a",
+		"APP_SERVERS_0_PORT":          "8080",
+		"APP_SERVERS_1_HOST":          "b",
+		"APP_SERVERS_1_ROUTES_0_PATH": "/x",
+		"APP_SERVERS_3_HOST":          "ignored",
+		"APP_BACKUP_0_HOST":           "c",
+		"APP_MIRRORS":                 `[{"Host":"d"}]`,
+		"APP_MIRRORS_0_HOST":          "ignored",
+	}
+	l := NewWithLookup("APP_", MapLookup(vars), nil)
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []serverConfig{
+			{Host: "a", Port: 8080},
+			{Host: "b", Port: 80, Routes: []routeConfig{{"/x"}}},
+		}, cfg.Servers)
+		assert.Equal(t, []*serverConfig{{Host: "c", Port: 80}}, cfg.Backups)
+		assert.Equal(t, []serverConfig{{Host: "d"}}, cfg.Mirrors)
+		assert.Equal(t, []string{"Servers", "Backups", "Mirrors"}, report.Overridden())
+	}
+
+	vars["APP_SERVERS_2_PORT"] = "x"
+	err = l.Load(&cfg)
+	assert.EqualError(t, err, `$APP_SERVERS_2_HOST: required variable is not set`)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Servers[2].Host", fe.Field)
+		assert.Equal(t, "APP_SERVERS_2_HOST", fe.Var)
+	}
+
+	// a slice without numbered variables keeps its value
+	cfg.Servers = []serverConfig{{Host: "z"}}
+	l = NewWithLookup("APP_", MapLookup(map[string]string{}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, []serverConfig{{Host: "z"}}, cfg.Servers)
+	}
+}
+
+func TestLoader_Load_indexedRecursive(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []node
+	}
+	var cfg struct {
+		Nodes []node
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_NODES_0_NAME":              "root",
+		"APP_NODES_0_CHILDREN_0_NAME":   "leaf",
+		"APP_NODES_1_CHILDREN_0_NAME":   "orphan",
+		"APP_NODES_2_CHILDREN_0_NAME_X": "ignored",
+	}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, []node{
+			{Name: "root", Children: []node{{Name: "leaf"}}},
+			{Children: []node{{Name: "orphan"}}},
+		}, cfg.Nodes)
+	}
+}
+
+func TestLoader_Load_indexedStrict(t *testing.T) {
+	t.Setenv("APP_SERVERS_0_HOST", "a")
+	var cfg struct {
+		Servers []serverConfig
+	}
+	l := New("APP_", nil, WithStrict(true))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, []serverConfig{{Host: "a", Port: 80}}, cfg.Servers)
+	}
+}
+
+func TestLoader_Load_keyed(t *testing.T) {
+	type upstream struct {
+		URL    string `required:"true"`
+		APIURL string `env:"API_URL"`
+		Routes []routeConfig
+	}
+	t.Setenv("APP_UPSTREAMS_PAYMENTS_URL", "http://payments")
+	t.Setenv("APP_UPSTREAMS_USER_API_URL", "http://users")
+	t.Setenv("APP_UPSTREAMS_USER_URL", "http://user")
+	t.Setenv("APP_UPSTREAMS_SEARCH_ROUTES_0_PATH", "/q")
+	t.Setenv("APP_UPSTREAMS_SEARCH_URL", "http://search")
+	t.Setenv("APP_UPSTREAMS_OTHER", "ignored")
+	t.Setenv("APP_BACKENDS_BILLING_URL", "http://billing")
+
+	var cfg struct {
+		Upstreams map[string]upstream
+		Backends  map[string]*upstream
+	}
+	l := New("APP_", nil, WithStrict(true))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, map[string]upstream{
+			"PAYMENTS": {URL: "http://payments"},
+			"USER":     {URL: "http://user", APIURL: "http://users"},
+			"SEARCH":   {URL: "http://search", Routes: []routeConfig{{"/q"}}},
+		}, cfg.Upstreams)
+		assert.Equal(t, map[string]*upstream{"BILLING": {URL: "http://billing"}}, cfg.Backends)
+	}
+
+	os.Unsetenv("APP_BACKENDS_BILLING_URL")
+	t.Setenv("APP_BACKENDS_BILLING_API_URL", "http://billing")
+	err := New("APP_", nil).Load(&cfg)
+	assert.EqualError(t, err, "$APP_BACKENDS_BILLING_URL: required variable is not set")
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Backends[BILLING].URL", fe.Field)
+	}
+}
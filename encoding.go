@@ -0,0 +1,46 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// decodeValue decodes the value of a []byte field tagged with the "base64" or "hex" option, or converts the value
+// of an integer field tagged with the "bytes" option to a number of bytes. The value is returned as is if encoding
+// is empty.
+func decodeValue(encoding, value string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch encoding {
+	case "":
+		return value, nil
+	case "base64":
+		b, err = base64.StdEncoding.DecodeString(value)
+	case "hex":
+		b, err = hex.DecodeString(value)
+	case "bytes":
+		return parseByteSize(value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid %v value: %w", encoding, err)
+	}
+	return string(b), nil
+}
+
+// encodeValue reverses decodeValue. Byte sizes are encoded as numbers of bytes.
+func encodeValue(encoding, value string) string {
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	case "hex":
+		return hex.EncodeToString([]byte(value))
+	}
+	return value
+}
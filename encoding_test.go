@@ -0,0 +1,59 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type encodingConfig struct {
+	Key  []byte  `env:",base64"`
+	Salt *[]byte `env:",hex" default:"cafe"`
+	Raw  []byte
+}
+
+func TestLoader_Load_encoding(t *testing.T) {
+	lookup := MapLookup(map[string]string{"APP_KEY": "aGVsbG8=", "APP_RAW": "aGVsbG8="})
+	var cfg encodingConfig
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, []byte("hello"), cfg.Key)
+		assert.Equal(t, []byte{0xca, 0xfe}, *cfg.Salt)
+		assert.Equal(t, []byte("aGVsbG8="), cfg.Raw)
+	}
+
+	lookup = MapLookup(map[string]string{"APP_SALT": "xyz"})
+	err := NewWithLookup("APP_", lookup, nil).Load(&cfg)
+	assert.EqualError(t, err, "$APP_SALT: invalid hex value: encoding/hex: invalid byte: U+0078 'x'")
+
+	var cfg2 struct {
+		Key string `env:",base64"`
+	}
+	assert.EqualError(t, NewWithLookup("APP_", lookup, nil).Load(&cfg2), "Key: the base64 option requires a []byte or byte array field")
+
+	value, ok, err := NewWithLookup("APP_", MapLookup(nil), nil).Resolve(FieldSpec{Name: "SALT", Default: "cafe", HasDefault: true, Encoding: "hex"})
+	if assert.Nil(t, err) && assert.True(t, ok) {
+		assert.Equal(t, "\xca\xfe", value)
+	}
+}
+
+func TestLoader_Dump_encoding(t *testing.T) {
+	salt := []byte{0xca, 0xfe}
+	cfg := encodingConfig{Key: []byte("hello"), Salt: &salt, Raw: []byte("raw")}
+	l := NewWithLookup("APP_", nil, nil)
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"APP_KEY": "aGVsbG8=", "APP_SALT": "cafe", "APP_RAW": "raw"}, vars)
+	}
+
+	m, err := l.ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "base64", m.Vars[0].Type)
+		assert.Equal(t, "hex", m.Vars[1].Type)
+		assert.Nil(t, m.Validate(vars))
+		assert.EqualError(t, m.Validate(map[string]string{"APP_KEY": "!"}), "$APP_KEY: invalid base64 value: illegal base64 data at input byte 0")
+	}
+}
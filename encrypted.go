@@ -0,0 +1,236 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ageBinaryHeader is the first line of a file encrypted with age in the binary format.
+const ageBinaryHeader = "age-encryption.org/v1\n"
+
+var (
+	// sopsMACPattern matches the MAC of a .env file encrypted with SOPS.
+	sopsMACPattern = regexp.MustCompile(`(?m)^sops_mac=`)
+	// sopsValuePattern matches a value encrypted with SOPS, capturing its data, IV, tag and type.
+	sopsValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]$`)
+	// sopsAgeKeyPattern matches the names of the SOPS metadata holding the data key encrypted for age recipients.
+	sopsAgeKeyPattern = regexp.MustCompile(`^age__list_\d+__map_enc$`)
+)
+
+// decryptAge decrypts data encrypted with age, in the armored or binary format, with the identities returned by
+// ageIdentities. Other data is returned as is.
+func decryptAge(data []byte) ([]byte, error) {
+	var r io.Reader
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte(armor.Header)) {
+		r = armor.NewReader(bytes.NewReader(trimmed))
+	} else if bytes.HasPrefix(data, []byte(ageBinaryHeader)) {
+		r = bytes.NewReader(data)
+	} else {
+		return data, nil
+	}
+	identities, err := ageIdentities()
+	if err != nil {
+		return nil, err
+	}
+	d, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the age-encrypted data: %w", err)
+	}
+	return io.ReadAll(d)
+}
+
+// ageIdentities returns the age identities used to decrypt .env files, which are read from $SOPS_AGE_KEY,
+// the file named by $SOPS_AGE_KEY_FILE and the default key file of SOPS (sops/age/keys.txt in the user
+// configuration directory), like SOPS does.
+func ageIdentities() ([]age.Identity, error) {
+	var keys []string
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		keys = append(keys, key)
+	}
+	if file := os.Getenv("SOPS_AGE_KEY_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the age key file: %w", err)
+		}
+		keys = append(keys, string(data))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(dir, "sops", "age", "keys.txt")); err == nil {
+			keys = append(keys, string(data))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no age key to decrypt the data: set $SOPS_AGE_KEY or $SOPS_AGE_KEY_FILE")
+	}
+
+	var identities []age.Identity
+	for _, key := range keys {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the age keys: %w", err)
+		}
+		identities = append(identities, ids...)
+	}
+	return identities, nil
+}
+
+// isSopsDotenv returns whether data is a .env file encrypted with SOPS, which is recognized by its MAC.
+func isSopsDotenv(data []byte) bool {
+	return sopsMACPattern.Match(data)
+}
+
+// decryptSopsDotenv decrypts a .env file encrypted with SOPS and returns the variables defined in it.
+//
+// The file is read like SOPS writes it: each line is either a comment starting with "#" or a variable defined as
+// NAME=VALUE, with line breaks in values escaped as "\n". Variables whose names start with "sops_" hold the metadata
+// of the file. The data key must be encrypted for an age recipient whose identity is returned by ageIdentities,
+// and the MAC of the file is verified so that values cannot be removed, added or reordered.
+func decryptSopsDotenv(data []byte) (map[string]string, error) {
+	type item struct {
+		name, value string
+		comment     bool
+	}
+	var items []item
+	meta := map[string]string{}
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			items = append(items, item{value: line[1:], comment: true})
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %v: missing '='", i+1)
+		}
+		value = strings.ReplaceAll(value, `\n`, "\n")
+		if strings.HasPrefix(name, "sops_") {
+			meta[strings.TrimPrefix(name, "sops_")] = value
+		} else {
+			items = append(items, item{name: name, value: value})
+		}
+	}
+
+	key, err := sopsDataKey(meta)
+	if err != nil {
+		return nil, err
+	}
+	macOnlyEncrypted := meta["mac_only_encrypted"] == "true"
+	hash := sha512.New()
+	vars := map[string]string{}
+	for _, it := range items {
+		value, encrypted := it.value, sopsValuePattern.MatchString(it.value)
+		if encrypted {
+			// values are authenticated with their paths, which is empty for comments
+			path := it.name + ":"
+			if it.comment {
+				path = ":"
+			}
+			if value, err = sopsDecrypt(key, value, path); err != nil {
+				if it.comment {
+					return nil, fmt.Errorf("failed to decrypt a comment: %w", err)
+				}
+				return nil, fmt.Errorf("failed to decrypt $%v: %w", it.name, err)
+			}
+		}
+		if encrypted || !macOnlyEncrypted {
+			hash.Write([]byte(value))
+		}
+		if !it.comment {
+			vars[it.name] = value
+		}
+	}
+
+	// the MAC is authenticated with the time of the last modification
+	modified, err := time.Parse(time.RFC3339, meta["lastmodified"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid time of the last modification of the SOPS file: %w", err)
+	}
+	mac, err := sopsDecrypt(key, meta["mac"], modified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the MAC of the SOPS file: %w", err)
+	}
+	if mac != fmt.Sprintf("%X", hash.Sum(nil)) {
+		return nil, errors.New("the MAC of the SOPS file does not match its content")
+	}
+	return vars, nil
+}
+
+// sopsDataKey returns the data key of a file encrypted with SOPS, decrypting it with the age identities returned by
+// ageIdentities.
+func sopsDataKey(meta map[string]string) ([]byte, error) {
+	var encrypted []string
+	for name, value := range meta {
+		if sopsAgeKeyPattern.MatchString(name) {
+			encrypted = append(encrypted, value)
+		}
+	}
+	if len(encrypted) == 0 {
+		return nil, errors.New("the SOPS file has no age recipients, which are the only ones supported")
+	}
+	identities, err := ageIdentities()
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range encrypted {
+		d, err := age.Decrypt(armor.NewReader(strings.NewReader(strings.TrimSpace(value))), identities...)
+		if err != nil {
+			continue
+		}
+		if key, err := io.ReadAll(d); err == nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("failed to decrypt the data key of the SOPS file with the available age keys")
+}
+
+// sopsDecrypt decrypts a value encrypted with SOPS using AES-GCM with the data key, authenticating the additional
+// data along with it.
+func sopsDecrypt(key []byte, value, additionalData string) (string, error) {
+	m := sopsValuePattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", errors.New("invalid encrypted value")
+	}
+	var parts [3][]byte
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAgeKey generates an age identity and makes it the only key available to decrypt .env files.
+func newAgeKey(t *testing.T) *age.X25519Identity {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("SOPS_AGE_KEY", id.String())
+	return id
+}
+
+// ageEncrypt encrypts data for the recipient, in the armored format if armored is true.
+func ageEncrypt(t *testing.T, r age.Recipient, data string, armored bool) []byte {
+	var buf bytes.Buffer
+	var out io.WriteCloser = nopCloser{&buf}
+	if armored {
+		out = armor.NewWriter(&buf)
+	}
+	w, err := age.Encrypt(out, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte(data))
+	_ = w.Close()
+	_ = out.Close()
+	return buf.Bytes()
+}
+
+// nopCloser is a buffer with a Close method doing nothing.
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+// sopsEncrypt encrypts the lines of a .env file for the recipient like SOPS does.
+func sopsEncrypt(t *testing.T, r age.Recipient, lines ...string) string {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	encrypt := func(value, additionalData, typ string) string {
+		iv := make([]byte, 32)
+		_, _ = rand.Read(iv)
+		block, _ := aes.NewCipher(key)
+		gcm, _ := cipher.NewGCMWithNonceSize(block, 32)
+		out := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+		b64 := base64.StdEncoding.EncodeToString
+		return fmt.Sprintf("ENC[AES256_GCM,data:%v,iv:%v,tag:%v,type:%v]", b64(out[:len(out)-16]), b64(iv), b64(out[len(out)-16:]), typ)
+	}
+
+	var b strings.Builder
+	hash := sha512.New()
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			hash.Write([]byte(line[1:]))
+			b.WriteString("#" + encrypt(line[1:], ":", "comment") + "\n")
+			continue
+		}
+		name, value, _ := strings.Cut(line, "=")
+		hash.Write([]byte(value))
+		b.WriteString(name + "=" + encrypt(value, name+":", "str") + "\n")
+	}
+	modified := time.Now().UTC().Format(time.RFC3339)
+	enc := strings.ReplaceAll(string(ageEncrypt(t, r, string(key), true)), "\n", `\n`)
+	b.WriteString("sops_age__list_0__map_enc=" + enc + "\n")
+	b.WriteString("sops_lastmodified=" + modified + "\n")
+	b.WriteString("sops_mac=" + encrypt(fmt.Sprintf("%X", hash.Sum(nil)), modified, "str") + "\n")
+	b.WriteString("sops_unencrypted_suffix=_unencrypted\nsops_version=3.9.0\n")
+	return b.String()
+}
+
+func Test_decryptAge(t *testing.T) {
+	id := newAgeKey(t)
+	other, _ := age.GenerateX25519Identity()
+
+	for _, armored := range []bool{false, true} {
+		vars, err := Parse(bytes.NewReader(ageEncrypt(t, id.Recipient(), "HOST=localhost\nPORT=8080\n", armored)))
+		if assert.Nil(t, err) {
+			assert.Equal(t, map[string]string{"HOST": "localhost", "PORT": "8080"}, vars)
+		}
+	}
+
+	_, err := Parse(bytes.NewReader(ageEncrypt(t, other.Recipient(), "HOST=localhost\n", true)))
+	assert.ErrorContains(t, err, "failed to decrypt the age-encrypted data")
+
+	t.Setenv("SOPS_AGE_KEY", "")
+	_, err = Parse(bytes.NewReader(ageEncrypt(t, id.Recipient(), "HOST=localhost\n", true)))
+	assert.ErrorContains(t, err, "no age key")
+
+	file := filepath.Join(t.TempDir(), "keys.txt")
+	_ = os.WriteFile(file, []byte("# created: 2024-01-01\n"+id.String()+"\n"), 0600)
+	t.Setenv("SOPS_AGE_KEY_FILE", file)
+	vars, err := Parse(bytes.NewReader(ageEncrypt(t, id.Recipient(), "HOST=localhost\n", false)))
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"HOST": "localhost"}, vars)
+	}
+
+	// plain data is parsed as is
+	vars, err = Parse(strings.NewReader("HOST=localhost\n"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"HOST": "localhost"}, vars)
+	}
+}
+
+func Test_decryptSopsDotenv(t *testing.T) {
+	id := newAgeKey(t)
+	data := sopsEncrypt(t, id.Recipient(), "#database", "DB_PASSWORD=s3cret", "DB_DSN=host=db\nport=5432")
+
+	file := filepath.Join(t.TempDir(), ".env")
+	_ = os.WriteFile(file, []byte(data), 0600)
+	lookup, err := FileLookup(file)
+	if assert.Nil(t, err) {
+		value, _ := lookup("DB_PASSWORD")
+		assert.Equal(t, "s3cret", value)
+		value, _ = lookup("DB_DSN")
+		assert.Equal(t, "host=db\nport=5432", value)
+		_, ok := lookup("sops_version")
+		assert.False(t, ok)
+	}
+
+	// values cannot be moved to other variables
+	swapped := strings.Replace(data, "DB_PASSWORD=", "DB_USER=", 1)
+	_, err = Parse(strings.NewReader(swapped))
+	assert.ErrorContains(t, err, "failed to decrypt $DB_USER")
+
+	// variables cannot be removed
+	lines := strings.Split(data, "\n")
+	_, err = Parse(strings.NewReader(strings.Join(append(lines[:1:1], lines[2:]...), "\n")))
+	assert.EqualError(t, err, "the MAC of the SOPS file does not match its content")
+
+	other, _ := age.GenerateX25519Identity()
+	_, err = Parse(strings.NewReader(sopsEncrypt(t, other.Recipient(), "A=1")))
+	assert.EqualError(t, err, "failed to decrypt the data key of the SOPS file with the available age keys")
+
+	_, err = Parse(strings.NewReader("A=ENC[AES256_GCM,data:AA==,iv:AA==,tag:AA==,type:str]\nsops_mac=x\n"))
+	assert.EqualError(t, err, "the SOPS file has no age recipients, which are the only ones supported")
+}
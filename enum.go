@@ -0,0 +1,107 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// enumValue is a name of a value of an integer field tagged with "enum".
+type enumValue struct {
+	name, value string
+}
+
+// parseEnum parses the "enum" tag of a field, which maps names to the integer values of the field
+// (e.g. "debug=0,info=1,warn=2"). Names must be unique regardless of case, while several names may map to the same
+// value.
+func parseEnum(tag string) ([]enumValue, error) {
+	items, err := splitTagList(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enum tag %q: %w", tag, err)
+	}
+	var values []enumValue
+	for _, item := range items {
+		name, value, ok := strings.Cut(item, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid enum tag %q: %q must be a name and a value separated by =", tag, item)
+		}
+		for _, v := range values {
+			if strings.EqualFold(v.name, name) {
+				return nil, fmt.Errorf("invalid enum tag %q: duplicate name %v", tag, name)
+			}
+		}
+		values = append(values, enumValue{name: name, value: value})
+	}
+	return values, nil
+}
+
+// checkEnum checks that the values of an "enum" tag can be assigned to a field of the given type.
+func checkEnum(values []enumValue, t reflect.Type) error {
+	if !isInteger(indirectType(t)) {
+		return errors.New("the enum tag requires an integer field")
+	}
+	for _, v := range values {
+		if err := setValue(reflect.New(indirectType(t)), v.value); err != nil {
+			return fmt.Errorf("invalid value of %v in the enum tag: %w", v.name, err)
+		}
+	}
+	return nil
+}
+
+// enumTag reverses parseEnum.
+func enumTag(values []enumValue) string {
+	items := make([]string, len(values))
+	for i, v := range values {
+		items[i] = v.name + "=" + v.value
+	}
+	return strings.Join(items, ",")
+}
+
+// decode decodes a value of the field with decodeValue, and converts the name of an enum value to the value
+// if the field is tagged with "enum". Names are matched regardless of case.
+func (f *fieldInfo) decode(value string) (string, error) {
+	if len(f.enum) == 0 {
+		return decodeValue(f.encoding, value)
+	}
+	for _, v := range f.enum {
+		if strings.EqualFold(v.name, value) {
+			return v.value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid value %q: must be one of %v", value, strings.Join(f.enumNames(), ", "))
+}
+
+// encode reverses decode. Values without names are returned as is.
+func (f *fieldInfo) encode(value string) string {
+	for _, v := range f.enum {
+		if v.value == value {
+			return v.name
+		}
+	}
+	return encodeValue(f.encoding, value)
+}
+
+// enumNames returns the names of the enum values of the field.
+func (f *fieldInfo) enumNames() []string {
+	var names []string
+	for _, v := range f.enum {
+		names = append(names, v.name)
+	}
+	return names
+}
+
+// containsFold returns whether names contains the given name regardless of case.
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logLevel int
+
+func TestEnumTag(t *testing.T) {
+	type config struct {
+		Level    logLevel `enum:"debug=0,info=1,warn=2,warning=2"`
+		Verbose  *uint8   `enum:"low=1, high=9"`
+		Fallback logLevel `enum:"debug=0,info=1" default:"INFO"`
+		Levels   []logLevel
+	}
+	tests := []struct {
+		tag      string
+		vars     map[string]string
+		expected config
+		err      string
+	}{
+		{"t1", map[string]string{"APP_LEVEL": "warn"}, config{Level: 2, Fallback: 1}, ""},
+		{"t2", map[string]string{"APP_LEVEL": "Warning", "APP_FALLBACK": "debug"}, config{Level: 2}, ""},
+		{"t3", map[string]string{"APP_VERBOSE": "high"}, config{Verbose: new(uint8), Fallback: 1}, ""},
+		{"t4", map[string]string{"APP_LEVEL": "2"}, config{}, `$APP_LEVEL: invalid value "2": must be one of debug, info, warn, warning`},
+		{"t5", map[string]string{"APP_LEVEL": "trace"}, config{}, `$APP_LEVEL: invalid value "trace": must be one of debug, info, warn, warning`},
+	}
+	*tests[2].expected.Verbose = 9
+	for _, test := range tests {
+		var cfg config
+		err := NewWithLookup("APP_", MapLookup(test.vars), nil).Load(&cfg)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.tag)
+			assert.ErrorIs(t, err, ErrParse, test.tag)
+			continue
+		}
+		if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, test.expected, cfg, test.tag)
+		}
+	}
+
+	for _, tag := range []string{
+		`enum:"debug=0,debug=1"`,
+		`enum:"debug=0,DEBUG=1"`,
+		`enum:"debug"`,
+		`enum:"=0"`,
+		`enum:"debug=0,"`,
+		`enum:"debug=x"`,
+	} {
+		values, err := parseEnum(reflect.StructTag(tag).Get("enum"))
+		if err == nil {
+			err = checkEnum(values, reflect.TypeOf(logLevel(0)))
+		}
+		assert.NotNil(t, err, tag)
+	}
+
+	var s struct {
+		Name string `enum:"a=1"`
+	}
+	assert.EqualError(t, Load(&s), "Name: the enum tag requires an integer field")
+	var b struct {
+		Size int `env:",bytes" enum:"a=1"`
+	}
+	assert.EqualError(t, Load(&b), "Size: the enum tag cannot be used with the bytes option")
+}
+
+func TestEnumTag_reverse(t *testing.T) {
+	type config struct {
+		Level logLevel `enum:"debug=0,info=1,warn=2,warning=2" required:"true"`
+		Other logLevel `enum:"debug=0,info=1"`
+	}
+	l := NewWithLookup("APP_", MapLookup(nil), nil)
+	cfg := config{Level: 2, Other: 5}
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"APP_LEVEL": "warn", "APP_OTHER": "5"}, vars)
+	}
+
+	m, err := l.ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "enum", m.Vars[0].Type)
+		assert.Equal(t, []string{"debug", "info", "warn", "warning"}, m.Vars[0].Enum)
+		assert.Nil(t, m.Validate(map[string]string{"APP_LEVEL": "INFO"}))
+		assert.EqualError(t, m.Validate(map[string]string{"APP_LEVEL": "1"}),
+			`$APP_LEVEL: invalid value "1": must be one of debug, info, warn, warning`)
+	}
+
+	l2 := NewWithLookup("APP_", MapLookup(map[string]string{"APP_LEVEL": "info"}), nil)
+	value, ok, err := l2.Resolve(FieldSpec{Field: "Level", Name: "LEVEL", Enum: "debug=0,info=1"})
+	assert.Equal(t, "1", value)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		var random config
+		vars, err := l.Random(&random, r)
+		if assert.Nil(t, err) {
+			var loaded config
+			assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&loaded))
+			assert.Equal(t, random, loaded)
+		}
+	}
+}
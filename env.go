@@ -5,28 +5,96 @@
 package env
 
 import (
+	"context"
 	"encoding"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
 	// Loader loads a struct with values returned by a lookup function.
 	Loader struct {
-		log    LogFunc
-		prefix string
-		lookup LookupFunc
+		log          LogFunc
+		logger       *slog.Logger
+		prefix       string
+		lookup       LookupFunc
+		secretLookup LookupFunc
+		environment  string
+		tagName      string
+		legacyPrefix string
+		lookupLabel  string
+		profile      string
+		logMode      LogMode
+		redactor     Redactor
+		strict       bool
+		strictFail   bool
+		autoPrefix   bool
+		legacyNaming bool
+		nameMapper   *NameMapper
+		foldedEnv    map[string]string
+		names        func() []string
+		fallbackTags string
+		sources      []Source
+		layered      bool
+		parsers      *parserSet
+		elementDepth int
+
+		// plans caches the plans of the loader if it has a name mapper or its own parsers, which are not shared
+		// with other loaders (see planOptions.getFields).
+		plans *sync.Map
+
+		optionalPointers bool
+		clearMissing     bool
+		extendedBools    bool
+		lenientErrors    bool
+		hashConfig       bool
+
+		trace func(name string, found bool, source string)
+
+		// parallelism is the maximum number of concurrent lookups (see WithParallelLookups), and prefetched holds
+		// the results of the lookups made in advance for the struct being loaded.
+		parallelism int
+		prefetched  map[prefetchKey]prefetchResult
+
+		// tracer creates the spans of the loader (see WithTracerProvider), and spanContext holds the span of
+		// the struct being loaded or reloaded.
+		tracer      trace.Tracer
+		spanContext context.Context
+
+		transformers      []Transformer
+		namedTransformers map[string]Transformer
+		namedParsers      map[string]ParserFunc
+
+		changeHandlers *changeHandlers
+		stats          *loaderStats
+		statsReporters []StatsReporter
 	}
 
+	// Option configures a Loader.
+	Option func(*Loader)
+
 	// LogFunc logs a message.
 	LogFunc func(format string, args ...interface{})
 
+	// LogMode specifies what a Loader logs when it populates a struct.
+	LogMode int
+
 	// LookupFunc looks up a name and returns the corresponding value and a flag indicating if the name is found.
 	LookupFunc func(name string) (string, bool)
 
@@ -37,15 +105,26 @@ type (
 	}
 )
 
+const (
+	// LogFields logs the population of every field. This is the default.
+	LogFields LogMode = iota
+	// LogSummary logs a single line per struct, e.g. "loaded 12 fields, 2 defaulted, 1 missing", counting the
+	// fields set from variables, those set from their "default" tags, and those whose variables are not set.
+	LogSummary
+	// LogSilent logs nothing.
+	LogSilent
+)
+
 var (
 	// ErrStructPointer represents the error that a pointer to a struct is expected.
 	ErrStructPointer = errors.New("must be a pointer to a struct")
 	// ErrNilPointer represents the error that a nil pointer is received
 	ErrNilPointer = errors.New("the pointer should not be nil")
-	// TagName specifies the tag name for customizing struct field names when loading environment variables
+	// TagName specifies the tag name for customizing struct field names when loading environment variables.
+	// It is used by loaders without their own tag names (see WithTagName), including the package-level loader.
 	TagName = "env"
 
-	// nameRegex is used to convert a string from camelCase into snake case format
+	// nameRegex is used to convert a string from camelCase into snake case format with the legacy rules
 	nameRegex = regexp.MustCompile(`([^A-Z_])([A-Z])`)
 	// loader is the default loader used by the "Load" function at the package level.
 	loader = New("APP_", log.Printf)
@@ -53,14 +132,95 @@ var (
 
 // New creates a new environment variable loader.
 // The prefix will be used to prefix the struct field names when they are used to read from environment variables.
-func New(prefix string, log LogFunc) *Loader {
-	return &Loader{prefix: prefix, lookup: os.LookupEnv, log: log}
+func New(prefix string, log LogFunc, opts ...Option) *Loader {
+	return NewWithLookup(prefix, os.LookupEnv, log, append([]Option{WithLookupLabel("env")}, opts...)...)
 }
 
 // NewWithLookup creates a new loader using the given lookup function.
 // The prefix will be used to prefix the struct field names when they are used to read from environment variables.
-func NewWithLookup(prefix string, lookup LookupFunc, log LogFunc) *Loader {
-	return &Loader{prefix: prefix, lookup: lookup, log: log}
+func NewWithLookup(prefix string, lookup LookupFunc, log LogFunc, opts ...Option) *Loader {
+	l := &Loader{prefix: prefix, lookup: lookup, lookupLabel: "lookup", log: log, changeHandlers: &changeHandlers{}, stats: &loaderStats{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.logMode == LogSilent {
+		l.log = nil
+	}
+	return l
+}
+
+// WithAutoPrefix makes nested structs without "prefix" tags prefixed with their field names in UPPER_SNAKE_CASE,
+// followed by an underscore, so that hierarchical configurations work without tags. For example, the Host field of
+// a nested struct field named Database is read from $APP_DATABASE_HOST. Embedded structs are not prefixed, and an
+// empty "prefix" tag (`prefix:""`) keeps a nested struct unprefixed.
+//
+// The code generated by WriteLoadFunc does not use automatic prefixes.
+func WithAutoPrefix() Option {
+	return func(l *Loader) {
+		l.autoPrefix = true
+	}
+}
+
+// WithTagName specifies the name of the tag customizing the names of variables, instead of TagName. Unlike changing
+// TagName, this does not affect other loaders, so libraries sharing a binary can use different tag names.
+func WithTagName(name string) Option {
+	return func(l *Loader) {
+		l.tagName = name
+	}
+}
+
+// tagNameOrDefault returns the tag name of the loader, or TagName if it has none.
+func (l *Loader) tagNameOrDefault() string {
+	if l.tagName == "" {
+		return TagName
+	}
+	return l.tagName
+}
+
+// WithClearMissing makes Load reset a field to its zero value when its variable is not set and it has no default,
+// instead of leaving it unchanged. This makes the result independent of the previous values of the struct, which
+// matters when loading repeatedly into the same struct.
+func WithClearMissing() Option {
+	return func(l *Loader) {
+		l.clearMissing = true
+	}
+}
+
+// WithLogMode specifies what the loader logs when it populates a struct. Logging every field can be too noisy
+// for production services, which may prefer a summary line or no logging at all. Deprecation notices are logged
+// in all modes except LogSilent.
+func WithLogMode(mode LogMode) Option {
+	return func(l *Loader) {
+		l.logMode = mode
+	}
+}
+
+// WithSecretLookup specifies the lookup function used for fields tagged with `env:",secret"`.
+// Secret fields are looked up with this function first and fall back to the loader's regular lookup
+// when the name is not found, so that secrets can be kept in a secret store such as Vault while the
+// rest of the configuration comes from the process environment.
+func WithSecretLookup(lookup LookupFunc) Option {
+	return func(l *Loader) {
+		l.secretLookup = lookup
+	}
+}
+
+// WithEnvironment specifies the name of the environment (e.g. "production") the loader runs in.
+// Fields with an "envs" tag are only loaded, and only required, when the tag lists this name.
+func WithEnvironment(name string) Option {
+	return func(l *Loader) {
+		l.environment = name
+	}
+}
+
+// WithLegacyPrefix specifies a prefix that was used before the loader's prefix, for example by an application that
+// has been renamed. When a variable with the loader's prefix is not found, the variable with the legacy prefix is
+// used instead, and a deprecation notice is logged. This allows renamed applications to be rolled out before all
+// environments are migrated to the new names.
+func WithLegacyPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.legacyPrefix = prefix
+	}
 }
 
 // Load populates a struct with the values read from the corresponding environment variables.
@@ -71,6 +231,11 @@ func Load(structPtr interface{}) error {
 	return loader.Load(structPtr)
 }
 
+// LoadReport populates a struct like Load and returns a report describing how each field was populated.
+func LoadReport(structPtr interface{}) (*Report, error) {
+	return loader.LoadReport(structPtr)
+}
+
 // Load populates a struct with the values read returned by the specified lookup function.
 // The struct must be specified as a pointer.
 //
@@ -80,97 +245,785 @@ func Load(structPtr interface{}) error {
 // Load uses the following rules to determine what name should be used to look up the value for a struct field:
 //   - If the field has an "env" tag, use the tag value as the name, unless the tag is "-" in which case it means
 //     the field should be skipped.
-//   - If the field has no "env" tag, turn the field name into UPPER_SNAKE_CASE format and use that as the name.
+//   - If the field has no "env" tag, turn the field name into UPPER_SNAKE_CASE format and use that as the name,
+//     or use the name generated by the loader's name mapper (see WithNameMapper).
 //   - Names are prefixed with the specified prefix.
 //
+// The name in an "env" tag may be followed by ",alias=NAME" options listing alternative names, which are used in
+// order when the variable is not set, for example to keep renamed variables working during a migration.
+// Similarly, a "deprecated" tag (e.g. `deprecated:"OLD_NAME,OLDER_NAME"`) lists former names that are used after
+// the aliases, with a deprecation warning naming the replacement.
+//
+// The name in an "env" tag may be followed by the ",secret" option. An error is returned if a tag has an unknown
+// or empty option, or if any other tag recognized by Load is malformed.
+//
+// A field tagged with the ",expand" option (e.g. `env:"PATHS,expand"`) has the references to other variables in
+// its value, such as ${HOME} or $USER, replaced with their values before it is parsed, as os.ExpandEnv does.
+// The referenced variables are looked up by their full names with the loader's lookup function, after
+// the transformers are applied. Its default is expanded likewise.
+//
+// A []byte field tagged with the ",base64" or ",hex" option (e.g. `env:"SIGNING_KEY,base64"`) is populated with
+// the decoded value of its variable, and its default is decoded likewise. Similarly, an integer field tagged with
+// the ",bytes" option (e.g. `env:"MAX_BODY,bytes"`) is populated with a byte size such as "512KB", "10MiB" or
+// "1.5GB", where decimal units are powers of 1000 and binary units are powers of 1024.
+//
+// An array field must receive exactly as many elements as its length, from a JSON array or a comma-separated list
+// (e.g. "a, b, c" for a [3]string field), and a byte array field exactly as many bytes, such as a [32]byte key
+// tagged with the ",hex" or ",base64" option. Other values are rejected with an error.
+//
+// A json.RawMessage field is populated with its value as is, without decoding it, after checking that it is valid
+// JSON, so that opaque JSON documents can be passed through the configuration.
+//
+// A map[string]string field tagged with the ",prefix=PREFIX" option (e.g. `env:",prefix=FEATURE_"`) is populated
+// with every variable whose name starts with the loader prefix, the prefixes of the enclosing structs and PREFIX.
+// The map keys are the rest of the names (e.g. $APP_FEATURE_DARK_MODE populates the key "DARK_MODE"). The names
+// are taken from the process environment, while the values are read with the loader's lookup function.
+//
 // The following types of struct fields are supported:
 //   - types implementing Setter, TextUnmarshaler, BinaryUnmarshaler: the corresponding interface method will be used
 //     to populate the field with a string
 //   - primary types (e.g. int, string): appropriate parsing functions will be called to parse a string value
+//   - url.Values: the string value is parsed as a query string (e.g. "a=1&b=2")
+//   - url.URL: the string value is parsed as a URL (e.g. "https://example.com/api"), instead of being loaded as
+//     a nested struct
+//   - regexp.Regexp: the string value is compiled as a regular expression
+//   - big.Int, big.Float and big.Rat: the string value is parsed as a number of arbitrary precision. A big.Float
+//     gets a precision sufficient for the digits of the value
+//   - net.IP and netip.Addr: the string value is parsed as an IP address (e.g. "10.0.0.1" or "::1")
+//   - net.IPNet and netip.Prefix: the string value is parsed as a CIDR block (e.g. "10.0.0.0/8")
 //   - other types (e.g. array, struct): the string value is assumed to be in JSON format and is decoded/assigned to the field.
 //
+// Fields of kind chan, func and unsafe.Pointer cannot be populated. They are ignored unless their variables are set,
+// in which case an error naming the field is returned.
+//
 // Special handling for nested structures:
 //   - For fields that are structures (or pointers to structures), Load checks for a "prefix" tag.
-//     If found, this prefix is temporarily appended to the current prefix for loading nested fields,
-//     allowing hierarchical configuration management. The original prefix is restored afterwards.
+//     If found, this prefix is appended to the current prefix when loading nested fields,
+//     allowing hierarchical configuration management. With WithAutoPrefix, a prefix is derived from the field
+//     name when the tag is absent.
 //   - If a field is a nil pointer to a struct, it is automatically initialized to ensure that nested
 //     configurations can be loaded without prior manual initialization.
+//   - The fields of embedded (anonymous) structs are loaded as if they were declared in the containing struct,
+//     without any extra prefix unless a "prefix" tag is present. This also applies to embedded structs of unexported
+//     types, so that shared configuration mixins can be composed, but not to pointers to such structs.
+//
+// Before a variable value is parsed, it is passed through the transformers configured with WithTransformers,
+// followed by the named transformers listed in the field's "transform" tag (see WithNamedTransformer).
+// It is then parsed by the named parser in the field's "parser" tag, if any (see WithNamedParser), or by the parser
+// registered for the field type (see RegisterParser).
+//
+// An integer field with an "enum" tag is set from the names listed by the tag rather than from numbers, which suits
+// enumerations of iota constants (e.g. `enum:"debug=0,info=1,warn=2"` sets the field to 1 from the value "info").
+// Names are matched regardless of case, and other values are rejected. Dump reverses the mapping.
+//
+// A slice of nested structs (or pointers to structs) whose variable is not set is populated from numbered
+// variables, each element being loaded like a nested struct prefixed with the name of the field followed by its
+// index (e.g. $APP_SERVERS_0_HOST, $APP_SERVERS_0_PORT, $APP_SERVERS_1_HOST). Elements are read from index 0 until
+// an index for which no variable is set. Setting the variable of the field itself (e.g. $APP_SERVERS) to a JSON
+// array takes precedence. The code generated by WriteLoadFunc only reads the JSON array.
+//
+// Likewise, a map of nested structs with string keys is populated from the variables named after the field, the key
+// of an element and the name of a variable of the element (e.g. $APP_UPSTREAMS_PAYMENTS_URL populates the URL field
+// of the element with the key "PAYMENTS"). The names are taken from the process environment, while the values are
+// read with the loader's lookup function. If several variable names of the element match the end of a name,
+// the longest one is used.
+//
+// If a field has a "default" tag and its variable is not found, the tag value is parsed and assigned to the field
+// as if it were the variable value. Otherwise the field keeps its current value (or is reset to its zero value,
+// see WithClearMissing), unless it has a `required:"true"` tag in which case an error is returned.
+//
+// The value of a field, whether from its variable or its default, is checked after parsing against the constraints
+// declared by its tags, and an error naming the variable is returned if it violates them:
+//   - oneof: the value must be one of the comma-separated values of the tag (e.g. `oneof:"debug,info,warn,error"`).
+//   - min, max: the value of a numeric field must be greater than or equal to, or less than or equal to, the value
+//     of the tag (e.g. `min:"1" max:"65535"`).
+//   - minlen, maxlen: the length of a string, slice or map field must be at least, or at most, the value of the tag.
+//     The length of a string is its number of characters.
+//   - match: the value of a string field must match the regular expression of the tag (e.g. `match:"^[a-z0-9-]+$"`).
+//
+// The elements of slices other than []byte are checked individually.
+//
+// Errors about the values of fields, such as values that cannot be parsed or required variables that are not set,
+// are returned as *FieldError.
+//
+// Before the fields are populated, the Defaults methods of the nested structs implementing Defaulter are called,
+// followed by that of the struct itself, so that they can set defaults that "default" tags cannot express. The values
+// of variables and "default" tags override the values they set, and so does WithClearMissing.
+//
+// After the fields are populated, the AfterLoad methods of the nested structs implementing AfterLoader are called,
+// from the most deeply nested ones, followed by that of the struct itself, so that they can normalize values,
+// derive computed fields or validate fields against each other. An error returned by AfterLoad is returned by Load.
+//
+// A field with an "envs" tag (e.g. `envs:"production,staging"`) is only loaded when the tag lists the environment
+// specified with WithEnvironment. In other environments the field is skipped as if it had an `env:"-"` tag,
+// so that it is neither populated nor required.
 //
 // Load will log every field that is populated. In case when a field is tagged with `env:",secret"`, the value being
-// logged will be masked for security purpose.
+// logged will be masked for security purpose, as specified with WithRedactor.
+//
+// Instead of a struct, Load also accepts a pointer to a map with string keys and string or interface{} values,
+// for schema-less uses such as passing variables through to plugins. The map is filled with every variable whose
+// name starts with the loader prefix, keyed by the rest of the name (e.g. $APP_PLUGIN_DIR populates the key
+// "PLUGIN_DIR"). The names are taken from the process environment, while the values are read with the loader's
+// lookup function and transformed by its global transformers. The values of a map of interface{} values are typed
+// like those returned by LoadTree.
 func (l *Loader) Load(structPtr interface{}) error {
+	_, err := l.LoadReport(structPtr)
+	return err
+}
+
+// LoadReport populates a struct like Load and returns a report describing, for every field,
+// whether it was overridden by a variable or left at its default. The report is returned along with the errors
+// if the loader has lenient errors (see WithLenientErrors), and nil otherwise.
+func (l *Loader) LoadReport(structPtr interface{}) (*Report, error) {
+	var (
+		report *Report
+		err    error
+	)
+	if l.tracer != nil {
+		traced, end := l.startLoadSpan(structPtr)
+		report, err = traced.loadReport(structPtr)
+		end(report, err)
+	} else {
+		report, err = l.loadReport(structPtr)
+	}
+	var hash string
+	if err == nil && l.hashConfig {
+		hash = l.configHash(structPtr)
+	}
+	l.stats.record(report, hash, err)
+	if err == nil && l.log != nil && l.logMode == LogSummary {
+		l.logSummary(report)
+	}
+	return report, err
+}
+
+// logSummary logs the number of fields set from variables, set from defaults, and missing.
+func (l *Loader) logSummary(report *Report) {
+	var loaded, defaulted, missing int
+	for _, f := range report.Fields {
+		switch {
+		case f.Overridden:
+			loaded++
+		case f.DefaultUsed:
+			defaulted++
+		default:
+			missing++
+		}
+	}
+	if l.logger != nil {
+		l.logger.Info("loaded configuration", "loaded", loaded, "defaulted", defaulted, "missing", missing)
+	} else {
+		l.log("loaded %v fields, %v defaulted, %v missing", loaded, defaulted, missing)
+	}
+}
+
+// loadReport implements LoadReport.
+func (l *Loader) loadReport(structPtr interface{}) (*Report, error) {
 	value := reflect.ValueOf(structPtr)
+	if isMapTarget(value) {
+		return l.loadMap(value.Elem())
+	}
 	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
-		return ErrStructPointer
+		return nil, ErrStructPointer
 	}
-
-	valueType := value.Elem().Type()
 	value = value.Elem()
 
-	for i := 0; i < value.NumField(); i++ {
-		field := value.Field(i)
-		if !field.CanSet() {
+	fields, err := l.getFields(value.Type())
+	if err != nil {
+		return nil, err
+	}
+	if l.parallelism > 1 {
+		prefetched := *l
+		prefetched.prefetched = l.prefetch(fields)
+		l = &prefetched
+	}
+	// the nil pointers to the nested structs that will be populated are allocated first, so that the Defaults
+	// hooks of those structs are called as well
+	absent := l.absentFields(value, fields)
+	for _, f := range fields {
+		if l.active(f) && !absent[f] {
+			fieldByIndex(value, f.index)
+		}
+	}
+	setDefaults(value, fields)
+	report := &Report{}
+	var errs []error
+	for _, f := range fields {
+		if !l.active(f) || absent[f] {
 			continue
 		}
+		field := fieldByIndex(value, f.index)
+		var old reflect.Value
+		if l.lenientErrors {
+			old = cloneValue(field)
+		}
+		fr, err := l.assignValue(field, f)
+		if err != nil {
+			if !l.lenientErrors {
+				return nil, err
+			}
+			field.Set(old)
+			fr.Overridden, fr.DefaultUsed, fr.Source, fr.Err = false, false, "", err
+			errs = append(errs, err)
+		}
+		fr.Value = formatValue(field.Interface())
+		if f.secret {
+			fr.Value = l.redact(fr.Var, fr.Value)
+		}
+		report.Fields = append(report.Fields, fr)
+	}
+	if err := l.checkUnknown(fields); err != nil {
+		if !l.lenientErrors {
+			return nil, err
+		}
+		report.problems = append(report.problems, err)
+	}
+	if err := afterLoad(value, fields); err != nil {
+		if !l.lenientErrors {
+			return nil, err
+		}
+		report.problems = append(report.problems, err)
+	}
+	return report, errors.Join(append(errs, report.problems...)...)
+}
 
-		fieldType := valueType.Field(i)
+// assignValue assigns a value to a struct field from an environment variable, or from its default if the variable
+// is not found. It returns the report of the field, without its value. The variable name in the report is the one
+// returned by find. Errors are returned as *FieldError.
+func (l *Loader) assignValue(field reflect.Value, f *fieldInfo) (FieldReport, error) {
+	if f.collect {
+		return l.assignCollected(field, f)
+	}
+	fr := FieldReport{Field: f.path, Secret: f.secret}
+	var (
+		value string
+		ok    bool
+	)
+	fr.Var, value, fr.Source, ok = l.find(f)
+	if ok {
+		fr.Overridden = true
+		if kind := elemKind(f.typ); !supportedKind(kind) {
+			err := fmt.Errorf("cannot set %v: unsupported field kind %v", f.path, kind)
+			return fr, l.fieldError(ErrUnsupportedType, f, fr.Var, value, err)
+		}
+		v, err := l.prepare(f, fr.Var, value)
+		if err == nil {
+			v, err = f.decode(v)
+		}
+		if err == nil {
+			err = l.setValue(field, f, v)
+		}
+		if err == nil && f.cons != nil {
+			err = f.cons.check(field)
+		}
+		return fr, l.fieldError(ErrParse, f, fr.Var, value, err)
+	}
+	if f.indexed || f.keyed {
+		assign := l.assignIndexed
+		if f.keyed {
+			assign = l.assignKeyed
+		}
+		ok, err := assign(field, f)
+		if ok || err != nil {
+			fr.Overridden = ok
+			return fr, err
+		}
+	}
+	if f.hasDefault {
+		fr.DefaultUsed, fr.Source = true, "default"
+		def, err := l.defaultValue(f)
+		if err == nil {
+			err = l.setValue(field, f, def)
+		}
+		if err == nil && f.cons != nil {
+			err = f.cons.check(field)
+		}
+		return fr, l.fieldError(ErrParse, f, fr.Var, f.def, err)
+	}
+	if f.required {
+		return fr, l.fieldError(ErrMissingRequired, f, fr.Var, "", errors.New("required variable is not set"))
+	}
+	l.clear(field)
+	return fr, nil
+}
+
+// clear resets a field to its zero value if the loader is configured so (see WithClearMissing).
+func (l *Loader) clear(field reflect.Value) {
+	if l.clearMissing {
+		field.Set(reflect.Zero(field.Type()))
+	}
+}
 
-		if field.Kind() == reflect.Struct || (field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct) {
-			if field.Kind() == reflect.Ptr && field.IsNil() {
-				field.Set(reflect.New(fieldType.Type.Elem()))
+// find looks up the variable of a field in the active profile (see WithProfile), then falls back to the regular
+// variable, its aliases, its deprecated names, and to the legacy prefix if the variable is not found. It returns
+// the full name of the variable, its value and the label of the source it was found in (see lookupValue).
+// If the variable is not found, the name with the loader prefix is returned.
+func (l *Loader) find(f *fieldInfo) (string, string, string, bool) {
+	if name, value, source, ok := l.findProfile(f); ok {
+		return name, value, source, true
+	}
+	fullName := l.prefix + f.name
+	value, source, ok := l.lookupValue(l.prefix, f)
+	for _, alias := range f.aliases {
+		if ok {
+			break
+		}
+		if value, source, ok = l.lookupValue(l.prefix, f.alias(alias)); ok {
+			if l.log != nil && l.logger != nil {
+				l.logger.Info("alias used", "variable", fullName, "alias", l.prefix+alias)
+			} else if l.log != nil {
+				l.log("$%v is set with its alias $%v", fullName, l.prefix+alias)
 			}
+			fullName = l.prefix + alias
+		}
+	}
+	for _, name := range f.deprecated {
+		if ok {
+			break
+		}
+		if value, source, ok = l.lookupValue(l.prefix, f.alias(name)); ok {
+			l.logDeprecated(l.prefix+name, fullName)
+			fullName = l.prefix + name
+		}
+	}
+	if !ok && l.legacyPrefix != "" {
+		if value, source, ok = l.lookupValue(l.legacyPrefix, f); ok {
+			l.logDeprecated(l.legacyPrefix+f.name, fullName)
+			fullName = l.legacyPrefix + f.name
+		}
+	}
+	return fullName, value, source, ok
+}
 
-			fieldToLoad := field
-			if field.Kind() == reflect.Ptr {
-				fieldToLoad = field.Elem()
+// logDeprecated logs that a deprecated variable is used instead of its replacement.
+func (l *Loader) logDeprecated(name, replacement string) {
+	if l.log != nil && l.logger != nil {
+		l.logger.Warn("deprecated variable", "variable", name, "replacement", replacement)
+	} else if l.log != nil {
+		l.log("$%v is deprecated, use $%v instead", name, replacement)
+	}
+}
+
+// prepare transforms the value of a variable found for a field and logs it.
+func (l *Loader) prepare(f *fieldInfo, fullName, value string) (string, error) {
+	value, err := l.transform(f, value)
+	if err != nil {
+		return "", err
+	}
+	if l.log != nil && l.logMode == LogFields {
+		logValue := value
+		if f.secret {
+			logValue = l.redact(fullName, value)
+		}
+		// the structured logger is only used when log is set, so that copies of the loader are silenced by clearing log
+		if l.logger != nil {
+			l.logger.Info("set field", "field", f.field, "variable", fullName, "value", logValue, "masked", f.secret)
+		} else {
+			l.log("set %v with $%v=\"%v\"", f.field, fullName, logValue)
+		}
+	}
+	return value, nil
+}
+
+// active returns whether a field is loaded in the loader's environment, according to the field's "envs" tag.
+func (l *Loader) active(f *fieldInfo) bool {
+	if len(f.envs) == 0 {
+		return true
+	}
+	for _, env := range f.envs {
+		if env == l.environment {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupValue looks up the variable of a field with the given prefix. Secret variables are looked up with
+// the secret lookup first, and variables not found by the loader's lookup function are looked up case-insensitively
+// (see WithCaseInsensitive), then in its sources. If the sources are layered (see Layer), only the sources are
+// consulted after the secret lookup. It returns the label of the source the variable was found in: "secret" for
+// the secret lookup, the label of the loader's lookup function (see WithLookupLabel), "env" for the case-insensitive
+// snapshot of the process environment, or the label of one of the loader's sources. The results of the lookups
+// made in advance are used if available (see WithParallelLookups). The lookup is reported to the trace function,
+// if any (see WithTrace).
+func (l *Loader) lookupValue(prefix string, f *fieldInfo) (string, string, bool) {
+	var (
+		value, source string
+		ok            bool
+	)
+	if r, found := l.prefetched[prefetchKey{name: prefix + f.name, secret: f.secret}]; found {
+		value, source, ok = r.value, r.source, r.ok
+	} else {
+		value, source, ok = l.lookupName(prefix, f)
+	}
+	if l.trace != nil {
+		l.trace(prefix+f.name, ok, source)
+	}
+	return value, source, ok
+}
+
+// lookupName looks up the variable of a field with the given prefix as described in lookupValue, creating a span
+// of the lookup if the loader is traced (see WithTracerProvider).
+func (l *Loader) lookupName(prefix string, f *fieldInfo) (string, string, bool) {
+	if l.tracer == nil {
+		return l.resolveName(prefix, f)
+	}
+	start := time.Now()
+	value, source, ok := l.resolveName(prefix, f)
+	l.traceLookup(start, prefix+f.name, ok, source)
+	return value, source, ok
+}
+
+// resolveName implements lookupName.
+func (l *Loader) resolveName(prefix string, f *fieldInfo) (string, string, bool) {
+	name := prefix + f.name
+	if f.secret && l.secretLookup != nil {
+		if value, ok := l.secretLookup(name); ok {
+			return value, "secret", true
+		}
+	}
+	if !l.layered {
+		if value, ok := l.lookup(name); ok {
+			return value, l.lookupLabel, true
+		}
+		if value, ok := l.lookupFolded(name); ok {
+			return value, "env", true
+		}
+	}
+	return l.lookupSources(name, Key{Prefix: prefix, Path: f.keyPath})
+}
+
+// fieldInfo describes a struct field that is populated from a single variable.
+type fieldInfo struct {
+	// index is the index sequence of the field, starting from the root struct.
+	index []int
+	// path is the dot-separated path of the field, starting from the root struct (e.g. "Nested.URL").
+	path string
+	// field is the name of the field.
+	field string
+	// name is the variable name without the loader prefix (e.g. "NESTED_URL").
+	name string
+	// keyPath is the path of the Key of the variable (e.g. ["NESTED", "URL"]).
+	keyPath []string
+	// secret indicates whether the field is tagged as secret.
+	secret bool
+	// collect indicates whether the field is a map populated with all variables starting with name
+	// (see the "prefix" tag option).
+	collect bool
+	// aliases lists the alternative variable names without the loader prefix, from the "alias" tag options.
+	aliases []string
+	// deprecated lists the former variable names without the loader prefix, from the "deprecated" tag.
+	deprecated []string
+	// encoding is the encoding of the values of a []byte field, "base64" or "hex", or "bytes" for an integer field
+	// holding a byte size. It is empty if the values are parsed as is.
+	encoding string
+	// enum lists the names of the values of an integer field, from the "enum" tag.
+	enum []enumValue
+	// def is the value of the "default" tag, which is used when hasDefault is true.
+	def        string
+	hasDefault bool
+	// required indicates whether the field is tagged with `required:"true"`.
+	required bool
+	// envs lists the environments in the "envs" tag. The field is loaded in all environments if it is empty.
+	envs []string
+	// desc is the value of the "desc" tag, describing the field.
+	desc string
+	// transforms lists the names of the transformers in the "transform" tag.
+	transforms []string
+	// expand indicates whether references to other variables in the values are expanded (see the "expand" tag
+	// option).
+	expand bool
+	// parser is the name of the parser in the "parser" tag, or empty if the field is parsed according to its type.
+	parser string
+	// cons holds the constraints on the value of the field from its tags such as "oneof", or nil if it has none.
+	cons *constraints
+	// indexed indicates whether the field is a slice of nested structs, which can be populated from numbered
+	// variables (see assignIndexed).
+	indexed bool
+	// keyed indicates whether the field is a map of nested structs, which can be populated from variables named
+	// after the keys of its elements (see assignKeyed).
+	keyed bool
+	// typ is the type of the field.
+	typ reflect.Type
+}
+
+type (
+	// planOptions holds the options that determine how the fields of a struct type are populated.
+	planOptions struct {
+		// tagName is the name of the tag customizing variable names (see TagName).
+		tagName string
+		// autoPrefix indicates whether nested structs without "prefix" tags are prefixed with their field names.
+		autoPrefix bool
+		// legacyNaming indicates whether field names are converted with the legacy rules.
+		legacyNaming bool
+		// fallbackTags is the comma-separated list of the tags whose names are used when the field tag has none.
+		fallbackTags string
+		// nameMapper generates the names of variables, if set. It is a pointer so that the options can be used
+		// as a map key.
+		nameMapper *NameMapper
+		// parsers holds the parsers registered with WithParser, if any. Struct types with parsers are not nested.
+		parsers *parserSet
+	}
+
+	// planKey identifies a cached plan. The options are part of the key because they vary between loaders,
+	// and TagName can be changed. The generation of the global parsers is part of it because registering a parser
+	// outdates the plans cached by loaders.
+	planKey struct {
+		typ        reflect.Type
+		opts       planOptions
+		generation uint64
+	}
+
+	// plan is the cached result of getFields for a struct type.
+	plan struct {
+		fields []*fieldInfo
+		err    error
+	}
+)
+
+// plans caches the plans of the struct types loaded so far by loaders without a name mapper or their own parsers,
+// keyed by planKey.
+var plans sync.Map
+
+// getFields returns the fields of a struct type that are populated from variables with the default options.
+// For more details, please refer to planOptions.getFields().
+func getFields(t reflect.Type) ([]*fieldInfo, error) {
+	return planOptions{tagName: TagName}.getFields(t, &plans)
+}
+
+// getFields returns the fields of a struct type that are populated from variables by the loader.
+// For more details, please refer to planOptions.getFields().
+func (l *Loader) getFields(t reflect.Type) ([]*fieldInfo, error) {
+	o := planOptions{tagName: l.tagNameOrDefault(), autoPrefix: l.autoPrefix, legacyNaming: l.legacyNaming, fallbackTags: l.fallbackTags, nameMapper: l.nameMapper, parsers: l.parsers}
+	if l.plans != nil {
+		return o.getFields(t, l.plans)
+	}
+	return o.getFields(t, &plans)
+}
+
+// getFields returns the fields of a struct type that are populated from variables, in the order they are declared.
+// Fields of nested structs (or pointers to structs) are included with the nested "prefix" tag prepended to their names.
+// An error is returned if a field has a malformed tag.
+//
+// The result is computed once per struct type and options, and cached in the given cache, so that repeated loads
+// skip the parsing of tags and names. The returned fields are shared and must not be modified. The options holding
+// a name mapper or parsers are compared by identity, so their plans are cached by the loader they belong to rather
+// than globally, which would grow with every loader created.
+func (o planOptions) getFields(t reflect.Type, cache *sync.Map) ([]*fieldInfo, error) {
+	key := planKey{t, o, planGeneration.Load()}
+	if p, ok := cache.Load(key); ok {
+		return p.(*plan).fields, p.(*plan).err
+	}
+	fields, err := o.appendFields(nil, t, nil, "", "", nil, nil, map[reflect.Type]bool{})
+	p, _ := cache.LoadOrStore(key, &plan{fields, err})
+	return p.(*plan).fields, p.(*plan).err
+}
+
+// appendFields appends the fields of the struct type t to fields. The names are the field path passed to the
+// name mapper (see NameMapper). The visiting map guards against recursive types.
+func (o planOptions) appendFields(fields []*fieldInfo, t reflect.Type, index []int, path, prefix string, keyPath, names []string, visiting map[reflect.Type]bool) ([]*fieldInfo, error) {
+	if visiting[t] {
+		return fields, nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+			// unexported field, except for embedded structs whose exported fields are promoted
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		fieldPath := path + sf.Name
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if isNestedStruct(ft) && !o.hasParser(ft) && sf.Tag.Get("parser") == "" {
+			nestedPrefix, ok := sf.Tag.Lookup("prefix")
+			if !ok && o.autoPrefix && !sf.Anonymous {
+				nestedPrefix = o.snakeCase(sf.Name) + "_"
 			}
-			if err := l.loadStructField(fieldToLoad, fieldType); err != nil {
-				return err
+			nestedPath, nestedNames := keyPath, names
+			if p := strings.TrimSuffix(nestedPrefix, "_"); p != "" {
+				nestedPath, nestedNames = append(append([]string{}, keyPath...), p), nil
+			} else if !sf.Anonymous {
+				nestedNames = append(append([]string{}, names...), sf.Name)
 			}
-		} else {
-			if err := l.assignValue(field, fieldType); err != nil {
-				return err
+			var err error
+			fields, err = o.appendFields(fields, ft, fieldIndex, fieldPath+".", prefix+nestedPrefix, nestedPath, nestedNames, visiting)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tag := sf.Tag.Get(o.tagName)
+		name, opts, err := getName(tag, sf.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid %v tag %q: %w", fieldPath, o.tagName, tag, err)
+		}
+		if name == "-" {
+			continue
+		}
+		if opts.collect {
+			if t := sf.Type; t.Kind() != reflect.Map || t.Key().Kind() != reflect.String || t.Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("%v: the prefix option requires a map[string]string field", fieldPath)
+			}
+		} else if !hasTagName(tag) {
+			if fallback, ok := o.fallbackName(sf.Tag); ok {
+				name = fallback
+			} else {
+				name = o.mapName(append(append([]string{}, names...), sf.Name))
+			}
+		}
+		var aliases []string
+		for _, alias := range opts.aliases {
+			aliases = append(aliases, prefix+alias)
+		}
+		deprecated, err := splitTagList(sf.Tag.Get("deprecated"))
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid deprecated tag %q: %w", fieldPath, sf.Tag.Get("deprecated"), err)
+		}
+		for i, name := range deprecated {
+			deprecated[i] = prefix + name
+		}
+		if opts.encoding == "bytes" {
+			if !isInteger(indirectType(sf.Type)) {
+				return nil, fmt.Errorf("%v: the bytes option requires an integer field", fieldPath)
+			}
+		} else if opts.encoding != "" {
+			if t := indirectType(sf.Type); (t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8) && !isByteArray(t) {
+				return nil, fmt.Errorf("%v: the %v option requires a []byte or byte array field", fieldPath, opts.encoding)
+			}
+		}
+		var enum []enumValue
+		if tag, ok := sf.Tag.Lookup("enum"); ok {
+			if opts.encoding != "" {
+				return nil, fmt.Errorf("%v: the enum tag cannot be used with the %v option", fieldPath, opts.encoding)
+			}
+			if enum, err = parseEnum(tag); err == nil {
+				err = checkEnum(enum, sf.Type)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", fieldPath, err)
+			}
+		}
+		def, hasDefault := sf.Tag.Lookup("default")
+		transforms, err := splitTagList(sf.Tag.Get("transform"))
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid transform tag %q: %w", fieldPath, sf.Tag.Get("transform"), err)
+		}
+		parser := strings.TrimSpace(sf.Tag.Get("parser"))
+		if _, ok := sf.Tag.Lookup("parser"); ok && parser == "" {
+			return nil, fmt.Errorf("%v: invalid parser tag: empty name", fieldPath)
+		}
+		envs, err := splitTagList(sf.Tag.Get("envs"))
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid envs tag %q: %w", fieldPath, sf.Tag.Get("envs"), err)
+		}
+		cons, err := parseConstraints(sf, opts.encoding)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", fieldPath, err)
+		}
+		var required bool
+		if tag, ok := sf.Tag.Lookup("required"); ok {
+			if required, err = strconv.ParseBool(tag); err != nil {
+				return nil, fmt.Errorf("%v: invalid required tag %q: must be a boolean", fieldPath, tag)
 			}
 		}
+		fields = append(fields, &fieldInfo{
+			index:      fieldIndex,
+			path:       fieldPath,
+			field:      sf.Name,
+			name:       prefix + name,
+			keyPath:    append(append([]string{}, keyPath...), name),
+			secret:     opts.secret,
+			collect:    opts.collect,
+			encoding:   opts.encoding,
+			enum:       enum,
+			aliases:    aliases,
+			deprecated: deprecated,
+			def:        def,
+			hasDefault: hasDefault,
+			required:   required,
+			envs:       envs,
+			desc:       sf.Tag.Get("desc"),
+			transforms: transforms,
+			expand:     opts.expand,
+			parser:     parser,
+			cons:       cons,
+			indexed:    !opts.collect && opts.encoding == "" && parser == "" && o.isIndexed(sf.Type),
+			keyed:      !opts.collect && opts.encoding == "" && parser == "" && o.isKeyed(sf.Type),
+			typ:        sf.Type,
+		})
 	}
-	return nil
+	return fields, nil
 }
 
-// loadStructField loads a struct field with values from environment variables.
-func (l *Loader) loadStructField(field reflect.Value, fieldType reflect.StructField) error {
-	prefixTag := fieldType.Tag.Get("prefix")
-	originalPrefix := l.prefix
-	if prefixTag != "" {
-		l.prefix += prefixTag
-		defer func() { l.prefix = originalPrefix }()
+// isNestedStruct returns whether a field type is a struct whose fields are loaded from their own variables.
+// The struct types listed in valueStructs are parsed from a single value instead, and so are those without
+// exported fields whose pointers implement Setter, TextUnmarshaler or BinaryUnmarshaler (e.g. time.Time, big.Int,
+// or third-party decimal types).
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || valueStructs[t] {
+		return false
 	}
-
-	if field.Kind() == reflect.Ptr && field.IsNil() {
-		field.Set(reflect.New(fieldType.Type.Elem()))
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.IsExported() || sf.Anonymous {
+			return true
+		}
 	}
+	pt := reflect.PtrTo(t)
+	return !pt.Implements(setterType) && !pt.Implements(textUnmarshalerType) && !pt.Implements(binaryUnmarshalerType)
+}
 
-	return l.Load(field.Addr().Interface())
+// alternatives returns the aliases of the field followed by its deprecated names.
+func (f *fieldInfo) alternatives() []string {
+	return append(append([]string{}, f.aliases...), f.deprecated...)
 }
 
-// assignValue assigns a value to a struct field from an environment variable.
-func (l *Loader) assignValue(field reflect.Value, fieldType reflect.StructField) error {
-	name, secret := getName(fieldType.Tag.Get(TagName), fieldType.Name)
-	if name == "-" {
-		return nil
+// alias returns a copy of the field whose variable is named after the given alias.
+func (f *fieldInfo) alias(name string) *fieldInfo {
+	a := *f
+	last := len(f.keyPath) - 1
+	prefix := strings.TrimSuffix(f.name, f.keyPath[last])
+	a.name = name
+	a.keyPath = append(append([]string{}, f.keyPath[:last]...), strings.TrimPrefix(name, prefix))
+	return &a
+}
+
+// fieldByIndex returns the nested field of a struct value corresponding to index.
+// Nil pointers to structs along the way are initialized.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			v = indirect(v)
+		}
+		v = v.Field(x)
 	}
+	return v
+}
 
-	fullName := l.prefix + name
-	if value, ok := l.lookup(fullName); ok {
-		if l.log != nil {
-			logValue := value
-			if secret {
-				logValue = "***"
+// existingField returns the nested field of a struct value corresponding to index, like fieldByIndex, but without
+// allocating the nil pointers along the way. It returns false if one of them is nil.
+func existingField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
 			}
-			l.log("set %v with $%v=\"%v\"", fieldType.Name, fullName, logValue)
 		}
-		return setValue(field, value)
+		v = v.Field(x)
 	}
-	return nil
+	return v, true
 }
 
 // indirect dereferences pointers and returns the actual value it points to.
@@ -185,25 +1038,143 @@ func indirect(v reflect.Value) reflect.Value {
 	return v
 }
 
-// getName generates the environment variable name from a struct field tag and the field name.
-func getName(tag string, field string) (string, bool) {
-	name := strings.TrimSuffix(tag, ",secret")
-	nameLen := len(name)
+// indirectType returns a type after dereferencing pointers.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// elemKind returns the kind of a type after dereferencing pointers.
+func elemKind(t reflect.Type) reflect.Kind {
+	return indirectType(t).Kind()
+}
 
-	// If the `,secret` suffix was found, it would have been trimmed, so the length should be different.
-	secret := nameLen < len(tag)
+// supportedKind returns whether a field of the given kind can be populated from a string.
+func supportedKind(kind reflect.Kind) bool {
+	return kind != reflect.Chan && kind != reflect.Func && kind != reflect.UnsafePointer
+}
+
+// tagOptions holds the options following the name in a struct field tag.
+type tagOptions struct {
+	// secret indicates whether the "secret" option is present.
+	secret bool
+	// collect indicates whether the "prefix" option is present, in which case prefix is its value.
+	collect bool
+	prefix  string
+	// encoding is "base64", "hex" or "bytes" if the corresponding option is present.
+	encoding string
+	// aliases lists the values of the "alias" options.
+	aliases []string
+	// expand indicates whether the "expand" option is present.
+	expand bool
+}
+
+// getName generates the environment variable name from a struct field tag and the field name.
+// The tag consists of an optional name followed by comma-separated options: "secret", "expand", "prefix=PREFIX",
+// "base64", "hex", "bytes" and "alias=NAME", which may be repeated.
+// If the "prefix" option is present, the returned name is its value, and the tag must not specify a name.
+func getName(tag string, field string) (string, tagOptions, error) {
+	parts := strings.Split(tag, ",")
+	name, opts := parts[0], tagOptions{}
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "secret":
+			opts.secret = true
+		case opt == "expand":
+			opts.expand = true
+		case strings.HasPrefix(opt, "prefix="):
+			opts.collect, opts.prefix = true, strings.TrimPrefix(opt, "prefix=")
+		case opt == "base64" || opt == "hex" || opt == "bytes":
+			if opts.encoding != "" {
+				return "", tagOptions{}, fmt.Errorf("conflicting options %q and %q", opts.encoding, opt)
+			}
+			opts.encoding = opt
+		case strings.HasPrefix(opt, "alias="):
+			alias := strings.TrimPrefix(opt, "alias=")
+			if alias == "" {
+				return "", tagOptions{}, errors.New("empty alias")
+			}
+			opts.aliases = append(opts.aliases, alias)
+		case opt == "":
+			return "", tagOptions{}, errors.New("empty option")
+		default:
+			return "", tagOptions{}, fmt.Errorf("unknown option %q", opt)
+		}
+	}
 
-	if nameLen == 0 {
+	if opts.collect {
+		if name != "" {
+			return "", tagOptions{}, errors.New("the prefix option cannot be used with a name")
+		}
+		if len(opts.aliases) > 0 {
+			return "", tagOptions{}, errors.New("the prefix option cannot be used with aliases")
+		}
+		return opts.prefix, opts, nil
+	}
+	if name == "" {
 		name = camelCaseToUpperSnakeCase(field)
 	}
-	return name, secret
+	return name, opts, nil
+}
+
+// splitTagList splits a tag holding a comma-separated list. Spaces around the items are ignored.
+func splitTagList(tag string) ([]string, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	items := strings.Split(tag, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+		if items[i] == "" {
+			return nil, errors.New("empty item")
+		}
+	}
+	return items, nil
 }
 
 // camelCaseToUpperSnakeCase converts a name from camelCase format into UPPER_SNAKE_CASE format.
+// A word starts at an upper case letter following a lower case letter or a digit, or at the last letter of
+// a sequence of upper case letters followed by a lower case letter, so that acronyms are kept together
+// (e.g. APIKey becomes API_KEY, and S3Bucket becomes S3_BUCKET).
 func camelCaseToUpperSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// legacyCamelCaseToUpperSnakeCase converts a name from camelCase format into UPPER_SNAKE_CASE format with the rules
+// used before acronyms were recognized, which only start a word at an upper case letter following a character
+// other than an upper case letter (e.g. APIKey becomes APIKEY).
+func legacyCamelCaseToUpperSnakeCase(name string) string {
 	return strings.ToUpper(nameRegex.ReplaceAllString(name, "${1}_$2"))
 }
 
+// bigFloatPrec returns the precision in bits used to parse a big.Float value, which is enough to represent
+// every digit of its mantissa, and at least the precision of a float64. It only depends on the significant digits,
+// so that a value formatted by Dump is parsed back with the same precision.
+func bigFloatPrec(value string) uint {
+	mantissa, _, _ := strings.Cut(strings.ToLower(value), "e")
+	digits := 0
+	for _, r := range mantissa {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return uint(max(64, 4*digits))
+}
+
 // setValue assigns a string value to a reflection value using appropriate string parsing and conversion logic.
 func setValue(rval reflect.Value, value string) error {
 	rval = indirect(rval)
@@ -215,6 +1186,38 @@ func setValue(rval reflect.Value, value string) error {
 
 	// if the reflection value implements supported interface, use the interface to set the value
 	pval := rval.Addr().Interface()
+	if p, ok := pval.(*url.URL); ok {
+		u, err := url.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", value, errors.Unwrap(err))
+		}
+		*p = *u
+		return nil
+	}
+	if p, ok := pval.(*regexp.Regexp); ok {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		*p = *re
+		return nil
+	}
+	if p, ok := pval.(*big.Float); ok {
+		f, _, err := big.ParseFloat(value, 0, bigFloatPrec(value), big.ToNearestEven)
+		if err != nil {
+			return err
+		}
+		*p = *f
+		return nil
+	}
+	if p, ok := pval.(*net.IPNet); ok {
+		_, n, err := net.ParseCIDR(value)
+		if err != nil {
+			return err
+		}
+		*p = *n
+		return nil
+	}
 	if p, ok := pval.(Setter); ok {
 		return p.Set(value)
 	}
@@ -224,6 +1227,18 @@ func setValue(rval reflect.Value, value string) error {
 	if p, ok := pval.(encoding.BinaryUnmarshaler); ok {
 		return p.UnmarshalBinary([]byte(value))
 	}
+	if p, ok := pval.(*json.RawMessage); ok {
+		// the value is validated and copied as is rather than decoded
+		return json.Unmarshal([]byte(value), p)
+	}
+	if p, ok := pval.(*url.Values); ok {
+		values, err := url.ParseQuery(value)
+		if err != nil {
+			return err
+		}
+		*p = values
+		return nil
+	}
 
 	// parse the string according to the type of the reflection value and assign it
 	switch rtype.Kind() {
@@ -259,6 +1274,8 @@ func setValue(rval reflect.Value, value string) error {
 		}
 		rval.SetFloat(val)
 		break
+	case reflect.Array:
+		return setArray(rval, value)
 	case reflect.Slice:
 		if rtype.Elem().Kind() == reflect.Uint8 {
 			sl := reflect.ValueOf([]byte(value))
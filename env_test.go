@@ -5,11 +5,20 @@
 package env
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -72,6 +81,14 @@ func Test_setValue(t *testing.T) {
 		mystr1 myString
 		mystr2 *myString
 		myset  mySet
+		query1 url.Values
+		url1   url.URL
+		url2   *url.URL
+		ip1    net.IP
+		ipnet1 net.IPNet
+		addr1  netip.Addr
+		pfx1   *netip.Prefix
+		re1    *regexp.Regexp
 	}{}
 
 	tests := []struct {
@@ -109,6 +126,20 @@ func Test_setValue(t *testing.T) {
 		{"t7.2", reflect.ValueOf(&cfg.myset), "TRuE", mySet(true), true, false},
 		{"t7.3", reflect.ValueOf(&cfg.myset), "TRUE", mySet(true), true, false},
 		{"t8.1", reflect.ValueOf("test"), "test", "test", true, true},
+		{"t9.1", reflect.ValueOf(&cfg.query1), "a=1&b=2&a=3", url.Values{"a": {"1", "3"}, "b": {"2"}}, true, false},
+		{"t9.2", reflect.ValueOf(&cfg.query1), "a=%zz", url.Values{}, true, true},
+		{"t10.1", reflect.ValueOf(&cfg.url1), "https://example.com/api?a=1", url.URL{Scheme: "https", Host: "example.com", Path: "/api", RawQuery: "a=1"}, true, false},
+		{"t10.2", reflect.ValueOf(&cfg.url2), "/api", url.URL{Path: "/api"}, true, false},
+		{"t10.3", reflect.ValueOf(&cfg.url1), "http://[::1", url.URL{}, true, true},
+		{"t11.1", reflect.ValueOf(&cfg.ip1), "10.0.0.1", net.ParseIP("10.0.0.1"), true, false},
+		{"t11.2", reflect.ValueOf(&cfg.ip1), "10.0.0", nil, true, true},
+		{"t11.3", reflect.ValueOf(&cfg.ipnet1), "10.1.2.3/8", net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}, true, false},
+		{"t11.4", reflect.ValueOf(&cfg.ipnet1), "10.0.0.0", nil, true, true},
+		{"t11.5", reflect.ValueOf(&cfg.addr1), "::1", netip.IPv6Loopback(), true, false},
+		{"t11.6", reflect.ValueOf(&cfg.addr1), "::g", nil, true, true},
+		{"t11.7", reflect.ValueOf(&cfg.pfx1), "192.168.0.0/16", netip.MustParsePrefix("192.168.0.0/16"), true, false},
+		{"t12.1", reflect.ValueOf(&cfg.re1), "^a+$", *regexp.MustCompile("^a+$"), true, false},
+		{"t12.2", reflect.ValueOf(&cfg.re1), "(a", nil, true, true},
 	}
 
 	for _, test := range tests {
@@ -138,8 +169,16 @@ func Test_camelCaseToUpperSnakeCase(t *testing.T) {
 		{"t4", "MyID", "MY_ID"},
 		{"t5", "My_Name", "MY_NAME"},
 		{"t6", "MyFullName", "MY_FULL_NAME"},
-		{"t7", "URLName", "URLNAME"},
-		{"t8", "MyURLName", "MY_URLNAME"},
+		{"t7", "URLName", "URL_NAME"},
+		{"t8", "MyURLName", "MY_URL_NAME"},
+		{"t9", "APIKey", "API_KEY"},
+		{"t10", "HTTPPort", "HTTP_PORT"},
+		{"t11", "S3Bucket", "S3_BUCKET"},
+		{"t12", "HTTP2Server", "HTTP2_SERVER"},
+		{"t13", "UserID", "USER_ID"},
+		{"t14", "ID", "ID"},
+		{"t15", "MyURL", "MY_URL"},
+		{"t16", "TLSCertFile", "TLS_CERT_FILE"},
 	}
 
 	for _, test := range tests {
@@ -148,28 +187,188 @@ func Test_camelCaseToUpperSnakeCase(t *testing.T) {
 	}
 }
 
+func Test_legacyCamelCaseToUpperSnakeCase(t *testing.T) {
+	tests := []struct {
+		tag      string
+		input    string
+		expected string
+	}{
+		{"t1", "MyName", "MY_NAME"},
+		{"t2", "URLName", "URLNAME"},
+		{"t3", "MyURLName", "MY_URLNAME"},
+		{"t4", "APIKey", "APIKEY"},
+		{"t5", "S3Bucket", "S3_BUCKET"},
+	}
+
+	for _, test := range tests {
+		output := legacyCamelCaseToUpperSnakeCase(test.input)
+		assert.Equal(t, test.expected, output, test.tag)
+	}
+}
+
 func Test_getName(t *testing.T) {
 	tests := []struct {
-		tag    string
-		tg     string
-		field  string
-		name   string
-		secret bool
+		tag   string
+		tg    string
+		field string
+		name  string
+		opts  tagOptions
+		err   string
 	}{
-		{"t1", "", "Name", "NAME", false},
-		{"t2", "", "MyName", "MY_NAME", false},
-		{"t3", "NaME", "Name", "NaME", false},
-		{"t4", "NaME,secret", "Name", "NaME", true},
-		{"t5", ",secret", "Name", "NAME", true},
-		{"t6", "NameWith,Comma", "Name", "NameWith,Comma", false},
-		{"t7", "NameWith,Comma,secret", "Name", "NameWith,Comma", true},
+		{"t1", "", "Name", "NAME", tagOptions{}, ""},
+		{"t2", "", "MyName", "MY_NAME", tagOptions{}, ""},
+		{"t3", "NaME", "Name", "NaME", tagOptions{}, ""},
+		{"t4", "NaME,secret", "Name", "NaME", tagOptions{secret: true}, ""},
+		{"t5", ",secret", "Name", "NAME", tagOptions{secret: true}, ""},
+		{"t6", "NameWith,Comma", "Name", "", tagOptions{}, `unknown option "Comma"`},
+		{"t7", "NAME,secrt", "Name", "", tagOptions{}, `unknown option "secrt"`},
+		{"t8", "NAME,", "Name", "", tagOptions{}, "empty option"},
+		{"t9", "NAME,,secret", "Name", "", tagOptions{}, "empty option"},
+		{"t10", "-", "Name", "-", tagOptions{}, ""},
+		{"t11", ",prefix=FEATURE_", "Features", "FEATURE_", tagOptions{collect: true, prefix: "FEATURE_"}, ""},
+		{"t12", ",secret,prefix=", "Features", "", tagOptions{secret: true, collect: true}, ""},
+		{"t13", "NAME,prefix=FEATURE_", "Features", "", tagOptions{}, "the prefix option cannot be used with a name"},
+		{"t14", "KEY,base64", "Key", "KEY", tagOptions{encoding: "base64"}, ""},
+		{"t15", ",secret,hex", "Key", "KEY", tagOptions{secret: true, encoding: "hex"}, ""},
+		{"t16", ",base64,hex", "Key", "", tagOptions{}, `conflicting options "base64" and "hex"`},
+		{"t17", "HOST,alias=HOSTNAME,alias=SERVER", "Host", "HOST", tagOptions{aliases: []string{"HOSTNAME", "SERVER"}}, ""},
+		{"t18", "HOST,alias=", "Host", "", tagOptions{}, "empty alias"},
+		{"t19", ",prefix=X_,alias=Y_", "Host", "", tagOptions{}, "the prefix option cannot be used with aliases"},
+		{"t20", "PATHS,expand", "Paths", "PATHS", tagOptions{expand: true}, ""},
 	}
 
 	for _, test := range tests {
-		name, secret := getName(test.tg, test.field)
+		name, opts, err := getName(test.tg, test.field)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.tag)
+			continue
+		}
+		assert.Nil(t, err, test.tag)
 		assert.Equal(t, test.name, name, test.tag)
-		assert.Equal(t, test.secret, secret, test.tag)
+		assert.Equal(t, test.opts, opts, test.tag)
+	}
+}
+
+func Test_splitTagList(t *testing.T) {
+	items, err := splitTagList("")
+	assert.Nil(t, err)
+	assert.Nil(t, items)
+	items, err = splitTagList("a, b ,c")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, items)
+	_, err = splitTagList("a,")
+	assert.EqualError(t, err, "empty item")
+}
+
+func Test_getFields_invalidTags(t *testing.T) {
+	tests := []struct {
+		tag string
+		typ interface{}
+		err string
+	}{
+		{"t1", struct {
+			Name string `env:"NAME,secrt"`
+		}{}, `Name: invalid env tag "NAME,secrt": unknown option "secrt"`},
+		{"t2", struct {
+			Nested struct {
+				Port int `env:",secret,"`
+			}
+		}{}, `Nested.Port: invalid env tag ",secret,": empty option`},
+		{"t3", struct {
+			Name string `transform:"trim,"`
+		}{}, `Name: invalid transform tag "trim,": empty item`},
+		{"t4", struct {
+			Name string `envs:",production"`
+		}{}, `Name: invalid envs tag ",production": empty item`},
+		{"t5", struct {
+			Name string `required:"yes"`
+		}{}, `Name: invalid required tag "yes": must be a boolean`},
+		{"t6", struct {
+			Name string `deprecated:"OLD,"`
+		}{}, `Name: invalid deprecated tag "OLD,": empty item`},
+	}
+	for _, test := range tests {
+		_, err := getFields(reflect.TypeOf(test.typ))
+		assert.EqualError(t, err, test.err, test.tag)
+	}
+
+	var cfg struct {
+		Name string `env:"NAME,secrt"`
+	}
+	l := NewWithLookup("", mockLookup, nil)
+	assert.EqualError(t, l.Load(&cfg), `Name: invalid env tag "NAME,secrt": unknown option "secrt"`)
+	_, err := l.ExportManifest(&cfg)
+	assert.NotNil(t, err)
+}
+
+func Test_getFields_cached(t *testing.T) {
+	type config struct {
+		Host string `env:"SERVER" json:"HOST"`
+	}
+	typ := reflect.TypeOf(config{})
+	fields1, err1 := getFields(typ)
+	fields2, err2 := getFields(typ)
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	if assert.Len(t, fields1, 1) && assert.Len(t, fields2, 1) {
+		assert.Same(t, fields1[0], fields2[0])
+		assert.Equal(t, "SERVER", fields1[0].name)
+	}
+
+	// the plan depends on the tag name
+	defer func(name string) { TagName = name }(TagName)
+	TagName = "json"
+	fields, _ := getFields(typ)
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "HOST", fields[0].name)
+	}
+
+	// errors are cached too
+	typ = reflect.TypeOf(struct {
+		Port int `required:"yes"`
+	}{})
+	_, err1 = getFields(typ)
+	_, err2 = getFields(typ)
+	assert.NotNil(t, err1)
+	assert.Same(t, err1, err2)
+}
+
+func Test_getFields_loaderCache(t *testing.T) {
+	type config struct {
+		Host string
+	}
+	typ := reflect.TypeOf(config{})
+	count := func() int {
+		n := 0
+		plans.Range(func(key, _ interface{}) bool {
+			if key.(planKey).typ == typ {
+				n++
+			}
+			return true
+		})
+		return n
+	}
+	exact := func(fieldPath []string) string {
+		return fieldPath[len(fieldPath)-1]
+	}
+	parse := func(value string) (interface{}, error) { return value, nil }
+
+	// the plans of loaders with a name mapper or their own parsers are not cached globally
+	for i := 0; i < 3; i++ {
+		l := NewWithLookup("", MapLookup(map[string]string{}), nil, WithNameMapper(exact), WithParser(reflect.TypeOf(time.Time{}), parse))
+		fields1, _ := l.getFields(typ)
+		fields2, _ := l.getFields(typ)
+		if assert.Len(t, fields1, 1) && assert.Len(t, fields2, 1) {
+			assert.Same(t, fields1[0], fields2[0])
+			assert.Equal(t, "Host", fields1[0].name)
+		}
 	}
+	assert.Equal(t, 0, count())
+
+	l := NewWithLookup("", MapLookup(map[string]string{}), nil)
+	_, _ = l.getFields(typ)
+	_, _ = l.getFields(typ)
+	assert.Equal(t, 1, count())
 }
 
 type myLogger struct {
@@ -313,3 +512,553 @@ func TestLoad(t *testing.T) {
 	loader.log = oldLog
 
 }
+
+func TestWithSecretLookup(t *testing.T) {
+	secrets := func(name string) (string, bool) {
+		if name == "PASSWORD" {
+			return "secret", true
+		}
+		return "", false
+	}
+	l := NewWithLookup("", mockLookup, nil, WithSecretLookup(secrets))
+	var cfg Config2
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, 8080, cfg.Prt)
+		assert.Equal(t, "secret", cfg.Password)
+	}
+
+	l = NewWithLookup("", mockLookup, nil, WithSecretLookup(mockLookup3))
+	cfg = Config2{}
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, 8080, cfg.Prt)
+		assert.Equal(t, "xyz", cfg.Password)
+	}
+}
+
+func TestWithEnvironment(t *testing.T) {
+	type config struct {
+		Host     string `required:"true"`
+		Port     int    `envs:"production, staging" required:"true"`
+		URL      string `envs:"development"`
+		Password string `envs:"production" required:"true" default:"abc"`
+	}
+
+	var cfg config
+	l := NewWithLookup("", mockLookup, nil, WithEnvironment("development"))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, 0, cfg.Port)
+		assert.Equal(t, "http://example.com", cfg.URL)
+		assert.Equal(t, "", cfg.Password)
+	}
+
+	cfg = config{}
+	l = NewWithLookup("", mockLookup, nil, WithEnvironment("staging"))
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, "", cfg.URL)
+		assert.Equal(t, []string{"Host", "Port"}, report.Overridden())
+		assert.Nil(t, report.Defaulted())
+	}
+
+	cfg = config{}
+	l = NewWithLookup("", mockLookup2, nil, WithEnvironment("production"))
+	assert.EqualError(t, l.Load(&cfg), "$HOST: required variable is not set")
+
+	l = NewWithLookup("", MapLookup(map[string]string{"HOST": "a"}), nil, WithEnvironment("production"))
+	assert.EqualError(t, l.Load(&cfg), "$PORT: required variable is not set")
+	l = NewWithLookup("", MapLookup(map[string]string{"HOST": "a"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "", cfg.Password)
+	}
+}
+
+func TestLoader_Load_unsupportedKind(t *testing.T) {
+	type config struct {
+		Host     string
+		Callback func()
+		Events   chan string
+		Ptr      unsafe.Pointer
+		Handler  *func() `env:"PORT"`
+	}
+	var cfg config
+	l := NewWithLookup("", MapLookup(map[string]string{"HOST": "localhost"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+	}
+
+	tests := []struct {
+		tag  string
+		name string
+		err  string
+	}{
+		{"t1", "CALLBACK", "$CALLBACK: cannot set Callback: unsupported field kind func"},
+		{"t2", "EVENTS", "$EVENTS: cannot set Events: unsupported field kind chan"},
+		{"t3", "PTR", "$PTR: cannot set Ptr: unsupported field kind unsafe.Pointer"},
+		{"t4", "PORT", "$PORT: cannot set Handler: unsupported field kind func"},
+	}
+	for _, test := range tests {
+		l := NewWithLookup("", MapLookup(map[string]string{test.name: "x"}), nil)
+		assert.EqualError(t, l.Load(&cfg), test.err, test.tag)
+	}
+	assert.Nil(t, cfg.Handler)
+}
+
+func TestWithLegacyPrefix(t *testing.T) {
+	vars := map[string]string{"APP_HOST": "localhost", "APP_PORT": "8080", "LEGACYAPP_PORT": "80", "LEGACYAPP_URL": "http://example.com"}
+	var logger myLogger
+	l := NewWithLookup("APP_", MapLookup(vars), logger.Log, WithLegacyPrefix("LEGACYAPP_"))
+	var cfg Config1
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, "http://example.com", cfg.URL)
+	}
+	assert.Contains(t, logger.logs, "$LEGACYAPP_URL is deprecated, use $APP_URL instead")
+	assert.Contains(t, logger.logs, `set URL with $LEGACYAPP_URL="http://example.com"`)
+	assert.Len(t, logger.logs, 5)
+}
+
+func TestWithLogMode(t *testing.T) {
+	type config struct {
+		Host string
+		Port int    `default:"80"`
+		Mode string `default:"dev"`
+		URL  string
+		Name string
+	}
+	vars := map[string]string{"APP_HOST": "localhost", "APP_MODE": "prod", "OLD_URL": "http://example.com"}
+
+	tests := []struct {
+		tag  string
+		mode LogMode
+		logs []string
+	}{
+		{"t1", LogFields, []string{
+			`set Host with $APP_HOST="localhost"`,
+			`set Mode with $APP_MODE="prod"`,
+			"$OLD_URL is deprecated, use $APP_URL instead",
+			`set URL with $OLD_URL="http://example.com"`,
+		}},
+		{"t2", LogSummary, []string{
+			"$OLD_URL is deprecated, use $APP_URL instead",
+			"loaded 3 fields, 1 defaulted, 1 missing",
+		}},
+		{"t3", LogSilent, nil},
+	}
+	for _, test := range tests {
+		var logger myLogger
+		l := NewWithLookup("APP_", MapLookup(vars), logger.Log, WithLegacyPrefix("OLD_"), WithLogMode(test.mode))
+		var cfg config
+		if assert.Nil(t, l.Load(&cfg), test.tag) {
+			assert.Equal(t, test.logs, logger.logs, test.tag)
+		}
+	}
+
+	var logger myLogger
+	l := NewWithLookup("APP_", MapLookup(nil), logger.Log, WithLogMode(LogSummary))
+	var cfg struct {
+		Host string `required:"true"`
+	}
+	assert.NotNil(t, l.Load(&cfg))
+	assert.Empty(t, logger.logs)
+}
+
+type httpMixin struct {
+	Host    string
+	Port    int `default:"80"`
+	Timeout int
+	private string
+}
+
+func TestLoader_Load_embedded(t *testing.T) {
+	type config struct {
+		httpMixin
+		*Embedded
+		Nested struct {
+			httpMixin `prefix:"NESTED_"`
+		}
+		Name string
+	}
+	lookup := MapLookup(map[string]string{
+		"APP_HOST":        "localhost",
+		"APP_URL":         "http://example.com",
+		"APP_NESTED_HOST": "example.com",
+		"APP_NESTED_PORT": "8080",
+		"APP_PRIVATE":     "x",
+	})
+	var cfg config
+	report, err := NewWithLookup("APP_", lookup, nil).LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, 80, cfg.httpMixin.Port)
+		assert.Equal(t, "", cfg.private)
+		assert.Equal(t, "http://example.com", cfg.URL)
+		assert.Equal(t, 0, cfg.Embedded.Port)
+		assert.Equal(t, "example.com", cfg.Nested.Host)
+		assert.Equal(t, 8080, cfg.Nested.Port)
+		assert.Equal(t, []string{"httpMixin.Host", "Embedded.URL", "Nested.httpMixin.Host", "Nested.httpMixin.Port"}, report.Overridden())
+	}
+
+	// pointers to embedded structs of unexported types cannot be allocated, so they are skipped
+	var cfg2 struct {
+		*httpMixin
+	}
+	assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg2))
+	assert.Nil(t, cfg2.httpMixin)
+}
+
+func TestWithAutoPrefix(t *testing.T) {
+	type config struct {
+		Host     string
+		Database struct {
+			Host    string
+			MaxConn int
+		}
+		ReadReplica *struct {
+			Host string
+		}
+		Cache struct {
+			Host string
+		} `prefix:"REDIS_"`
+		Shared struct {
+			Port int
+		} `prefix:""`
+		Embedded
+	}
+	lookup := MapLookup(map[string]string{
+		"APP_HOST":              "localhost",
+		"APP_DATABASE_HOST":     "db",
+		"APP_DATABASE_MAX_CONN": "10",
+		"APP_READ_REPLICA_HOST": "replica",
+		"APP_REDIS_HOST":        "redis",
+		"APP_PORT":              "8080",
+		"APP_URL":               "http://example.com",
+	})
+
+	var cfg config
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithAutoPrefix()).Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, "db", cfg.Database.Host)
+		assert.Equal(t, 10, cfg.Database.MaxConn)
+		assert.Equal(t, "replica", cfg.ReadReplica.Host)
+		assert.Equal(t, "redis", cfg.Cache.Host)
+		assert.Equal(t, 8080, cfg.Shared.Port)
+		assert.Equal(t, "http://example.com", cfg.URL)
+	}
+
+	// without the option, nested structs are not prefixed
+	cfg = config{}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Database.Host)
+	}
+
+	m, err := NewWithLookup("APP_", lookup, nil, WithAutoPrefix()).ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "APP_DATABASE_HOST", m.Vars[1].Name)
+	}
+}
+
+func TestWithClearMissing(t *testing.T) {
+	type config struct {
+		Host string
+		Port int `default:"80"`
+		Tags []string
+	}
+	lookup := MapLookup(map[string]string{"APP_HOST": "localhost"})
+
+	cfg := config{Host: "example.com", Port: 8080, Tags: []string{"a"}}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithClearMissing()).Load(&cfg)) {
+		assert.Equal(t, config{Host: "localhost", Port: 80}, cfg)
+	}
+
+	cfg = config{Host: "example.com", Port: 8080, Tags: []string{"a"}}
+	if assert.Nil(t, NewWithLookup("APP_", MapLookup(nil), nil, WithClearMissing()).Load(&cfg)) {
+		assert.Equal(t, config{Port: 80}, cfg)
+	}
+
+	// without the option, fields are left unchanged
+	cfg = config{Host: "example.com", Port: 8080, Tags: []string{"a"}}
+	if assert.Nil(t, NewWithLookup("APP_", MapLookup(nil), nil).Load(&cfg)) {
+		assert.Equal(t, config{Host: "example.com", Port: 80, Tags: []string{"a"}}, cfg)
+	}
+}
+
+func TestWithTagName(t *testing.T) {
+	type config struct {
+		Host string `cfg:"SERVER_HOST" env:"HOST_NAME"`
+		Port int    `cfg:",secret"`
+	}
+	lookup := MapLookup(map[string]string{"APP_SERVER_HOST": "localhost", "APP_HOST_NAME": "example.com", "APP_PORT": "8080"})
+
+	var cfg config
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithTagName("cfg")).Load(&cfg)) {
+		assert.Equal(t, config{Host: "localhost", Port: 8080}, cfg)
+	}
+	cfg = config{}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, config{Host: "example.com", Port: 8080}, cfg)
+	}
+}
+
+func TestLoader_Load_alias(t *testing.T) {
+	type config struct {
+		Host string `env:"HOST,alias=HOSTNAME,alias=SERVER_HOST"`
+		DB   struct {
+			Name string `env:",alias=DATABASE"`
+		} `prefix:"DB_"`
+	}
+	var logs []string
+	logger := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+	lookup := MapLookup(map[string]string{"APP_SERVER_HOST": "server", "APP_DB_DATABASE": "main"})
+
+	var cfg config
+	report, err := NewWithLookup("APP_", lookup, logger, WithLogMode(LogSummary)).LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "server", cfg.Host)
+		assert.Equal(t, "main", cfg.DB.Name)
+		assert.Equal(t, "APP_SERVER_HOST", report.Fields[0].Var)
+		assert.Equal(t, []string{
+			"$APP_HOST is set with its alias $APP_SERVER_HOST",
+			"$APP_DB_NAME is set with its alias $APP_DB_DATABASE",
+			"loaded 2 fields, 0 defaulted, 0 missing",
+		}, logs)
+	}
+
+	// the variable takes precedence over its aliases
+	lookup = MapLookup(map[string]string{"APP_HOST": "host", "APP_HOSTNAME": "hostname", "APP_SERVER_HOST": "server"})
+	cfg = config{}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, "host", cfg.Host)
+	}
+
+	m, err := NewWithLookup("APP_", lookup, nil).ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"APP_HOSTNAME", "APP_SERVER_HOST"}, m.Vars[0].Aliases)
+		assert.Equal(t, []string{"APP_DB_DATABASE"}, m.Vars[1].Aliases)
+	}
+}
+
+func TestLoader_Load_deprecated(t *testing.T) {
+	type config struct {
+		Host string `env:",alias=HOSTNAME" deprecated:"SERVER,SERVER_HOST"`
+	}
+	var logs []string
+	logger := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	var cfg config
+	lookup := MapLookup(map[string]string{"APP_SERVER_HOST": "server"})
+	report, err := NewWithLookup("APP_", lookup, logger, WithLogMode(LogSummary)).LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "server", cfg.Host)
+		assert.Equal(t, "APP_SERVER_HOST", report.Fields[0].Var)
+		assert.Equal(t, "$APP_SERVER_HOST is deprecated, use $APP_HOST instead", logs[0])
+	}
+
+	// aliases take precedence over deprecated names
+	lookup = MapLookup(map[string]string{"APP_SERVER": "server", "APP_HOSTNAME": "hostname"})
+	cfg = config{}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, "hostname", cfg.Host)
+	}
+
+	m, err := NewWithLookup("APP_", lookup, nil).ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"APP_SERVER", "APP_SERVER_HOST"}, m.Vars[0].Deprecated)
+		assert.Nil(t, m.Validate(map[string]string{"APP_SERVER": "x"}))
+	}
+}
+
+func TestLoader_Load_url(t *testing.T) {
+	type urlConfig struct {
+		Endpoint url.URL
+		Proxy    *url.URL `default:"http://proxy:3128"`
+	}
+	var cfg urlConfig
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_ENDPOINT": "https://example.com/api"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "https://example.com/api", cfg.Endpoint.String())
+		assert.Equal(t, "http://proxy:3128", cfg.Proxy.String())
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_ENDPOINT": "https://example.com/%zz"}), nil)
+	err := l.Load(&cfg)
+	assert.EqualError(t, err, `$APP_ENDPOINT: invalid URL "https://example.com/%zz": invalid URL escape "%zz"`)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Endpoint", fe.Field)
+	}
+}
+
+func TestLoader_Load_net(t *testing.T) {
+	type netConfig struct {
+		Bind    netip.Addr `default:"0.0.0.0"`
+		Allowed []netip.Prefix
+		Trusted *net.IPNet
+		Gateway net.IP
+	}
+	var cfg netConfig
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_ALLOWED": `["10.0.0.0/8","192.168.0.0/16"]`,
+		"APP_TRUSTED": "172.16.0.0/12",
+		"APP_GATEWAY": "10.0.0.1",
+	}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, netip.IPv4Unspecified(), cfg.Bind)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.0.0/16")}, cfg.Allowed)
+		assert.Equal(t, "172.16.0.0/12", cfg.Trusted.String())
+		assert.Equal(t, "10.0.0.1", cfg.Gateway.String())
+	}
+
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "0.0.0.0", vars["APP_BIND"])
+		assert.Equal(t, "172.16.0.0/12", vars["APP_TRUSTED"])
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_TRUSTED": "172.16.0.0"}), nil)
+	assert.EqualError(t, l.Load(&cfg), "$APP_TRUSTED: invalid CIDR address: 172.16.0.0")
+}
+
+func TestLoader_Load_regexp(t *testing.T) {
+	var cfg struct {
+		Pattern *regexp.Regexp `default:"^v[0-9]+$"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.True(t, cfg.Pattern.MatchString("v12"))
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PATTERN": "(v"}), nil)
+	assert.EqualError(t, l.Load(&cfg), "$APP_PATTERN: invalid regular expression: error parsing regexp: missing closing ): `(v`")
+
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "^v[0-9]+$", vars["APP_PATTERN"])
+	}
+}
+
+func TestLoader_Load_rawJSON(t *testing.T) {
+	type config struct {
+		Doc   json.RawMessage
+		Docs  []json.RawMessage
+		Extra *json.RawMessage `default:"null"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_DOC":  `{"b": [1, 2],  "a": "x"}`,
+		"APP_DOCS": `[{"a":1}, "x"]`,
+	}), nil)
+	var cfg config
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, `{"b": [1, 2],  "a": "x"}`, string(cfg.Doc))
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`{"a":1}`), json.RawMessage(`"x"`)}, cfg.Docs)
+		assert.Equal(t, "null", string(*cfg.Extra))
+	}
+
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, `{"b": [1, 2],  "a": "x"}`, vars["APP_DOC"])
+	}
+	b, err := l.MarshalMasked(&cfg)
+	if assert.Nil(t, err) {
+		var doc struct{ Doc map[string]interface{} }
+		assert.Nil(t, json.Unmarshal(b, &doc))
+		assert.Equal(t, map[string]interface{}{"a": "x", "b": []interface{}{float64(1), float64(2)}}, doc.Doc)
+	}
+	m, err := l.ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "json", m.Vars[0].Type)
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_DOC": "{a:1}"}), nil)
+	err = l.Load(&cfg)
+	assert.ErrorIs(t, err, ErrParse)
+	var se *json.SyntaxError
+	assert.ErrorAs(t, err, &se)
+
+	g := &codeGenerator{imports: map[string]string{}}
+	assert.Contains(t, g.parse("c.Doc", rawMessageType), "json.Unmarshal([]byte(value), &c.Doc)")
+}
+
+// decimal is a fixed-point number with unexported fields, like third-party decimal types.
+type decimal struct {
+	units int64
+}
+
+func (d *decimal) UnmarshalText(text []byte) error {
+	whole, frac, _ := strings.Cut(string(text), ".")
+	units, err := strconv.ParseInt(whole+(frac + "00")[:2], 10, 64)
+	d.units = units
+	return err
+}
+
+func (d decimal) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", d.units/100, d.units%100)), nil
+}
+
+func TestLoader_Load_big(t *testing.T) {
+	type bigConfig struct {
+		Supply  *big.Int
+		Rate    big.Float
+		Ratio   *big.Rat
+		Price   decimal
+		Expires time.Time
+	}
+	var cfg bigConfig
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_SUPPLY":  "123456789012345678901234567890",
+		"APP_RATE":    "0.1234567890123456789012345",
+		"APP_RATIO":   "1/3",
+		"APP_PRICE":   "12.34",
+		"APP_EXPIRES": "2030-01-02T03:04:05Z",
+	}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "123456789012345678901234567890", cfg.Supply.String())
+		assert.Equal(t, "0.1234567890123456789012345", cfg.Rate.Text('f', 25))
+		assert.Equal(t, "1/3", cfg.Ratio.String())
+		assert.Equal(t, int64(1234), cfg.Price.units)
+		assert.Equal(t, 2030, cfg.Expires.Year())
+	}
+
+	vars, err := l.Dump(&cfg)
+	if assert.Nil(t, err) {
+		var loaded bigConfig
+		if assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&loaded)) {
+			assert.Equal(t, 0, cfg.Rate.Cmp(&loaded.Rate))
+			assert.Equal(t, cfg.Rate.Prec(), loaded.Rate.Prec())
+		}
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_SUPPLY": "12x"}), nil)
+	assert.NotNil(t, l.Load(&cfg))
+}
+
+func TestLoader_Load_expand(t *testing.T) {
+	var cfg struct {
+		Paths   string `env:"PATHS,expand"`
+		Cache   string `env:",expand" default:"${HOME}/.cache"`
+		Literal string
+	}
+	vars := map[string]string{
+		"HOME":        "/home/app",
+		"USER":        "app",
+		"APP_PATHS":   "${HOME}/bin:/opt/$USER/bin:$UNSET",
+		"APP_LITERAL": "$HOME",
+	}
+	l := NewWithLookup("APP_", MapLookup(vars), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "/home/app/bin:/opt/app/bin:", cfg.Paths)
+		assert.Equal(t, "/home/app/.cache", cfg.Cache)
+		assert.Equal(t, "$HOME", cfg.Literal)
+	}
+
+	value, ok, err := l.Resolve(FieldSpec{Field: "Paths", Name: "PATHS", Expand: true})
+	if assert.Nil(t, err) && assert.True(t, ok) {
+		assert.Equal(t, "/home/app/bin:/opt/app/bin:", value)
+	}
+}
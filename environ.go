@@ -0,0 +1,60 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "sort"
+
+// Environ returns the variables, named with the given prefix, that would populate a struct with its current values,
+// as "NAME=VALUE" strings. For more details, please refer to Loader.Environ().
+func Environ(structPtr interface{}, prefix string) ([]string, error) {
+	return New(prefix, nil).Environ(structPtr)
+}
+
+// Environ is like Dump, but it returns the variables as "NAME=VALUE" strings sorted by name, which is the format
+// of exec.Cmd.Env. This makes it possible to hand a configuration over to a child process, which loads it with
+// Load or LoadFromEnviron:
+//
+//	vars, err := loader.Environ(&cfg)
+//	...
+//	cmd := exec.Command("worker")
+//	cmd.Env = append(os.Environ(), vars...)
+//
+// The values of fields tagged as secret are included as is.
+func (l *Loader) Environ(structPtr interface{}) ([]string, error) {
+	vars, err := l.Dump(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	environ := make([]string, 0, len(vars))
+	for name, value := range vars {
+		environ = append(environ, name+"="+value)
+	}
+	sort.Strings(environ)
+	return environ, nil
+}
+
+// LoadFromEnviron populates a struct like Load, reading the variables from a list of "NAME=VALUE" strings instead
+// of the process environment. For more details, please refer to Loader.LoadFromEnviron().
+func LoadFromEnviron(environ []string, structPtr interface{}) error {
+	return loader.LoadFromEnviron(environ, structPtr)
+}
+
+// LoadFromEnviron populates a struct like Load, reading the variables from a list of "NAME=VALUE" strings, such as
+// those returned by os.Environ or Environ, instead of the loader's lookup function. The loader's secret lookup and
+// sources are not consulted either. As with exec.Cmd.Env, a later string overrides an earlier one for the same
+// name, and strings without "=" are ignored. The names of the variables are listed from the strings as well, for
+// the fields populated from all the variables with a prefix, such as maps tagged with the "prefix" option. The
+// variables are reported with the "environ" source (see FieldReport.Source).
+func (l *Loader) LoadFromEnviron(environ []string, structPtr interface{}) error {
+	other := *l
+	vars := environMap(environ)
+	other.lookup, other.secretLookup, other.sources, other.layered = MapLookup(vars), nil, nil, false
+	other.names = MapNames(vars)
+	other.lookupLabel = "environ"
+	if other.foldedEnv != nil {
+		other.foldedEnv = foldEnviron(environ)
+	}
+	return other.Load(structPtr)
+}
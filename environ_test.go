@@ -0,0 +1,70 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnviron(t *testing.T) {
+	type config struct {
+		Host     string
+		Port     int
+		Tags     []string
+		Password string   `env:",secret"`
+		Nested   Embedded `prefix:"NESTED_"`
+	}
+	cfg := config{Host: "localhost", Port: 80, Tags: []string{"a", "b"}, Password: "x=y", Nested: Embedded{Port: 90}}
+	environ, err := Environ(&cfg, "APP_")
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{
+			"APP_HOST=localhost",
+			"APP_NESTED_PORT=90",
+			"APP_NESTED_URL=",
+			"APP_PASSWORD=x=y",
+			"APP_PORT=80",
+			`APP_TAGS=["a","b"]`,
+		}, environ)
+	}
+
+	var loaded config
+	err = NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "example.com"}), nil).LoadFromEnviron(append(environ, "APP_PORT=8080", "INVALID"), &loaded)
+	if assert.Nil(t, err) {
+		assert.Equal(t, config{Host: "localhost", Port: 8080, Tags: []string{"a", "b"}, Password: "x=y", Nested: Embedded{Port: 90}}, loaded)
+	}
+
+	_, err = Environ(cfg, "APP_")
+	assert.Equal(t, ErrStructPointer, err)
+	assert.Equal(t, ErrStructPointer, LoadFromEnviron(environ, loaded))
+}
+
+func TestLoader_LoadFromEnviron_names(t *testing.T) {
+	type upstream struct {
+		URL string
+	}
+	type config struct {
+		Features  map[string]string `env:",prefix=FEATURE_"`
+		Upstreams map[string]upstream
+	}
+	cfg := config{Features: map[string]string{"SEARCH": "on"}, Upstreams: map[string]upstream{"USERS": {URL: "http://users"}}}
+	environ, err := Environ(&cfg, "APP_")
+	if !assert.Nil(t, err) {
+		return
+	}
+	var loaded config
+	l := NewWithLookup("APP_", MapLookup(map[string]string{}), nil, WithStrict(true))
+	if assert.Nil(t, l.LoadFromEnviron(environ, &loaded)) {
+		assert.Equal(t, cfg, loaded)
+	}
+	err = l.LoadFromEnviron(append(environ, "APP_PROT=80"), &loaded)
+	assert.EqualError(t, err, "unknown variables: $APP_PROT")
+
+	vars := map[string]string{}
+	if assert.Nil(t, NewWithLookup("APP_", MapLookup(map[string]string{}), nil).LoadFromEnviron([]string{"APP_DIR=/opt"}, &vars)) {
+		assert.Equal(t, map[string]string{"DIR": "/opt"}, vars)
+	}
+}
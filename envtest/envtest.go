@@ -0,0 +1,96 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package envtest provides helpers for testing code that loads configuration structs with go-env: loaders reading
+// variables from maps, setting variables for the duration of a test, and assertions on the loaded values.
+package envtest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	env "github.com/garaekz/go-env"
+)
+
+// Loader returns a loader without a prefix that looks up and lists the given variables instead of the process
+// environment and does not log, so that tests do not depend on the environment they run in. The options are applied
+// as with env.NewWithLookup.
+func Loader(vars map[string]string, opts ...env.Option) *env.Loader {
+	opts = append([]env.Option{env.WithVariableNames(env.MapNames(vars))}, opts...)
+	return env.NewWithLookup("", env.MapLookup(vars), nil, opts...)
+}
+
+// Setenv sets the given environment variables for the duration of the test with t.Setenv, which restores their
+// previous values when the test and its subtests complete. Like t.Setenv, it cannot be used in parallel tests.
+func Setenv(t testing.TB, vars map[string]string) {
+	t.Helper()
+	for name, value := range vars {
+		t.Setenv(name, value)
+	}
+}
+
+// AssertLoad loads a new struct of the type of expected with the given loader, or with the package-level loader if
+// it is nil, and reports a test error listing the differing fields if loading fails or the struct does not equal
+// expected. The expected struct can be specified as a value or a pointer. It returns whether the assertion passed.
+//
+//	envtest.AssertLoad(t, envtest.Loader(map[string]string{"PORT": "8080"}), Config{Host: "localhost", Port: 8080})
+func AssertLoad(t testing.TB, l *env.Loader, expected interface{}) bool {
+	t.Helper()
+	want := reflect.ValueOf(expected)
+	if want.Kind() != reflect.Ptr {
+		p := reflect.New(want.Type())
+		p.Elem().Set(want)
+		want = p
+	}
+	got := reflect.New(want.Type().Elem())
+	if err := load(l, got.Interface()); err != nil {
+		t.Errorf("failed to load %v: %v", want.Type().Elem(), err)
+		return false
+	}
+	if reflect.DeepEqual(got.Interface(), want.Interface()) {
+		return true
+	}
+
+	diff := env.Diff
+	if l != nil {
+		diff = l.Diff
+	}
+	changes, err := diff(want.Interface(), got.Interface())
+	if err != nil || len(changes) == 0 {
+		t.Errorf("loaded %+v, expected %+v", got.Elem().Interface(), want.Elem().Interface())
+		return false
+	}
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = "\t" + change.String()
+	}
+	t.Errorf("loaded %v differs from the expected values (expected != loaded):\n%v", want.Type().Elem(), strings.Join(lines, "\n"))
+	return false
+}
+
+// AssertLoadError loads the given struct with the given loader, or with the package-level loader if it is nil,
+// and reports a test error if loading does not fail with an error whose message contains the given text.
+// It returns whether the assertion passed.
+func AssertLoadError(t testing.TB, l *env.Loader, structPtr interface{}, contains string) bool {
+	t.Helper()
+	err := load(l, structPtr)
+	if err == nil {
+		t.Errorf("loading %T succeeded, expected an error containing %q", structPtr, contains)
+		return false
+	}
+	if !strings.Contains(err.Error(), contains) {
+		t.Errorf("loading %T failed with %q, expected an error containing %q", structPtr, err, contains)
+		return false
+	}
+	return true
+}
+
+// load populates a struct with the given loader, or with the package-level loader if it is nil.
+func load(l *env.Loader, structPtr interface{}) error {
+	if l == nil {
+		return env.Load(structPtr)
+	}
+	return l.Load(structPtr)
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package envtest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type config struct {
+	Host     string `default:"localhost"`
+	Port     int
+	Password string `env:",secret"`
+}
+
+// recorder is a testing.TB recording the errors reported to it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLoader(t *testing.T) {
+	var cfg config
+	err := Loader(map[string]string{"PORT": "8080"}).Load(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, config{Host: "localhost", Port: 8080}, cfg)
+}
+
+func TestLoader_names(t *testing.T) {
+	t.Setenv("FEATURE_ENV", "on")
+	var cfg struct {
+		Features map[string]string `env:",prefix=FEATURE_"`
+	}
+	err := Loader(map[string]string{"FEATURE_SEARCH": "on"}).Load(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"SEARCH": "on"}, cfg.Features)
+}
+
+func TestSetenv(t *testing.T) {
+	t.Run("t1", func(t *testing.T) {
+		Setenv(t, map[string]string{"APP_PORT": "8080", "APP_PASSWORD": "xyz"})
+		assert.Equal(t, "8080", os.Getenv("APP_PORT"))
+		AssertLoad(t, nil, config{Host: "localhost", Port: 8080, Password: "xyz"})
+	})
+	_, ok := os.LookupEnv("APP_PORT")
+	assert.False(t, ok)
+}
+
+func TestAssertLoad(t *testing.T) {
+	l := Loader(map[string]string{"PORT": "8080", "PASSWORD": "xyz"})
+	r := &recorder{TB: t}
+	assert.True(t, AssertLoad(r, l, config{Host: "localhost", Port: 8080, Password: "xyz"}))
+	assert.True(t, AssertLoad(r, l, &config{Host: "localhost", Port: 8080, Password: "xyz"}))
+	assert.Empty(t, r.errors)
+
+	assert.False(t, AssertLoad(r, l, config{Host: "example.com", Port: 8080, Password: "abc"}))
+	assert.Equal(t, []string{"loaded envtest.config differs from the expected values (expected != loaded):\n" +
+		"\tHost ($HOST): \"example.com\" != \"localhost\"\n" +
+		"\tPassword ($PASSWORD): \"***\" != \"***\""}, r.errors)
+
+	r.errors = nil
+	assert.False(t, AssertLoad(r, Loader(map[string]string{"PORT": "x"}), config{}))
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "failed to load envtest.config")
+}
+
+func TestAssertLoadError(t *testing.T) {
+	r := &recorder{TB: t}
+	assert.True(t, AssertLoadError(r, Loader(map[string]string{"PORT": "x"}), &config{}, "invalid syntax"))
+	assert.Empty(t, r.errors)
+
+	assert.False(t, AssertLoadError(r, Loader(nil), &config{}, "invalid syntax"))
+	assert.False(t, AssertLoadError(r, Loader(map[string]string{"PORT": "x"}), &config{}, "missing"))
+	assert.Len(t, r.errors, 2)
+	assert.Equal(t, `loading *envtest.config succeeded, expected an error containing "invalid syntax"`, r.errors[0])
+	assert.Contains(t, r.errors[1], `expected an error containing "missing"`)
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrMissingRequired is the error of a field tagged with `required:"true"` whose variable is not set.
+	ErrMissingRequired = errors.New("a required variable is not set")
+	// ErrParse is the error of a field whose value, read from its variable or its "default" tag, cannot be parsed
+	// into the type of the field or does not satisfy its constraints.
+	ErrParse = errors.New("the value cannot be parsed")
+	// ErrUnsupportedType is the error of a field whose variable is set but whose type cannot be populated, such as
+	// a channel or a function.
+	ErrUnsupportedType = errors.New("the field type is not supported")
+)
+
+// FieldError describes a failure to populate a struct field. Load returns errors of this type for problems with
+// the values of fields, so that callers can use errors.As to build their own messages:
+//
+//	var fe *env.FieldError
+//	if errors.As(err, &fe) {
+//		fmt.Printf("invalid value %q for %v (set $%v)\n", fe.Value, fe.Field, fe.Var)
+//	}
+//
+// A FieldError matches one of ErrMissingRequired, ErrParse and ErrUnsupportedType with errors.Is, depending on
+// the failure, as well as its underlying error, so that callers can branch without matching messages:
+//
+//	if errors.Is(err, env.ErrMissingRequired) {
+//		...
+//	}
+type FieldError struct {
+	// Field is the dot-separated path of the struct field (e.g. "Server.Port").
+	Field string
+	// Var is the name of the variable of the field, including the loader prefix (e.g. "APP_SERVER_PORT").
+	Var string
+	// Value is the value that could not be used, which is the value of the variable or the default of the field,
+	// or empty if the field is required but its variable is not set. The values of secret fields are masked
+	// (see WithRedactor).
+	Value string
+	// Err is the underlying error (e.g. a *strconv.NumError). For secret fields, the value is masked in its message
+	// as well, while errors.As and errors.Is still reach the original error.
+	Err error
+
+	// kind is ErrMissingRequired, ErrParse or ErrUnsupportedType.
+	kind error
+}
+
+// Error returns the message of the underlying error prefixed with the variable (e.g. "$APP_PORT: ...").
+func (e *FieldError) Error() string {
+	return "$" + e.Var + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is the sentinel error describing the failure, which is one of ErrMissingRequired,
+// ErrParse and ErrUnsupportedType.
+func (e *FieldError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// fieldError returns a FieldError of the given kind for the given field, variable and value wrapping err, or nil if
+// err is nil.
+func (l *Loader) fieldError(kind error, f *fieldInfo, name, value string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if f.secret && value != "" {
+		masked := l.redact(name, value)
+		err = &redactedError{msg: strings.ReplaceAll(err.Error(), value, masked), err: err}
+		value = masked
+	}
+	return &FieldError{Field: f.path, Var: name, Value: value, Err: err, kind: kind}
+}
+
+// redactedError is an error whose message has the value of a secret field masked.
+type redactedError struct {
+	msg string
+	err error
+}
+
+// Error returns the masked message.
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original error.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
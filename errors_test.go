@@ -0,0 +1,97 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldError(t *testing.T) {
+	type config struct {
+		Server struct {
+			Port int
+		} `prefix:"SERVER_"`
+		Password int    `env:",secret"`
+		Mode     int    `default:"x"`
+		Host     string `required:"true"`
+	}
+	tests := []struct {
+		tag    string
+		vars   map[string]string
+		err    *FieldError
+		syntax bool
+		kind   error
+	}{
+		{"t1", map[string]string{"APP_SERVER_PORT": "abc"}, &FieldError{Field: "Server.Port", Var: "APP_SERVER_PORT", Value: "abc"}, true, ErrParse},
+		{"t2", map[string]string{"APP_PASSWORD": "abc"}, &FieldError{Field: "Password", Var: "APP_PASSWORD", Value: "***"}, true, ErrParse},
+		{"t3", map[string]string{}, &FieldError{Field: "Mode", Var: "APP_MODE", Value: "x"}, true, ErrParse},
+		{"t4", map[string]string{"APP_MODE": "1"}, &FieldError{Field: "Host", Var: "APP_HOST"}, false, ErrMissingRequired},
+	}
+	for _, test := range tests {
+		var cfg config
+		err := NewWithLookup("APP_", MapLookup(test.vars), nil).Load(&cfg)
+		var fe *FieldError
+		if assert.True(t, errors.As(err, &fe), test.tag) {
+			assert.Equal(t, test.err.Field, fe.Field, test.tag)
+			assert.Equal(t, test.err.Var, fe.Var, test.tag)
+			assert.Equal(t, test.err.Value, fe.Value, test.tag)
+			assert.Equal(t, test.syntax, errors.Is(err, strconv.ErrSyntax), test.tag)
+			for _, kind := range []error{ErrMissingRequired, ErrParse, ErrUnsupportedType} {
+				assert.Equal(t, kind == test.kind, errors.Is(err, kind), test.tag)
+			}
+			assert.Equal(t, "$"+fe.Var+": "+fe.Err.Error(), err.Error(), test.tag)
+		}
+	}
+
+	_, _, err := NewWithLookup("APP_", MapLookup(nil), nil).Resolve(FieldSpec{Field: "Host", Name: "HOST", Required: true})
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, &FieldError{Field: "Host", Var: "APP_HOST", Err: fe.Err, kind: ErrMissingRequired}, fe)
+		assert.EqualError(t, err, "$APP_HOST: required variable is not set")
+	}
+}
+
+func TestFieldError_secret(t *testing.T) {
+	var cfg struct {
+		Password int `env:",secret"`
+	}
+	err := NewWithLookup("APP_", MapLookup(map[string]string{"APP_PASSWORD": "s3cr3t"}), nil).Load(&cfg)
+	assert.EqualError(t, err, `$APP_PASSWORD: strconv.ParseInt: parsing "***": invalid syntax`)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	var ne *strconv.NumError
+	assert.True(t, errors.As(err, &ne))
+
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_PASSWORD": "s3cr3t"}), nil, WithRedactor(RedactKeepLast(2)))
+	err = l.Load(&cfg)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+}
+
+func TestFieldError_Is(t *testing.T) {
+	var cfg struct {
+		Events  chan string
+		Servers []struct {
+			Port int
+		} `env:"SERVERS"`
+		Hosts map[string]string `env:",prefix=HOSTS_" required:"true"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_EVENTS": "x"}), nil)
+	err := l.Load(&cfg)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	assert.NotErrorIs(t, err, ErrParse)
+
+	cfg.Events = nil
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_SERVERS_0_PORT": "x"}), nil)
+	err = l.Load(&cfg)
+	assert.ErrorIs(t, err, ErrParse)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+
+	l = NewWithLookup("APP_", MapLookup(nil), nil)
+	assert.ErrorIs(t, l.Load(&cfg), ErrMissingRequired)
+}
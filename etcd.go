@@ -0,0 +1,258 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+type (
+	// EtcdConfig configures an etcd lookup.
+	EtcdConfig struct {
+		// Endpoint is the URL of an etcd v3 server (e.g. "http://127.0.0.1:2379").
+		// Defaults to the first endpoint in $ETCDCTL_ENDPOINTS.
+		Endpoint string
+		// Prefix is the key prefix under which the variables are stored (e.g. "/myapp/").
+		// A variable is read from the key formed by appending its name to the prefix.
+		Prefix string
+		// Username and Password are the credentials used when etcd authentication is enabled.
+		Username string
+		Password string
+		// Client is the HTTP client used to talk to etcd. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// Etcd looks up values from keys stored under a prefix in etcd v3.
+	// It keeps a snapshot of the keys, which can be kept up to date with Watch.
+	Etcd struct {
+		config EtcdConfig
+		token  string
+		mu     sync.RWMutex
+		data   map[string]string
+		rev    int64
+	}
+
+	// etcdKeyValue is a key-value pair returned by the etcd JSON gateway.
+	etcdKeyValue struct {
+		Key         []byte `json:"key"`
+		Value       []byte `json:"value"`
+		ModRevision int64  `json:"mod_revision,string"`
+	}
+)
+
+// NewEtcd creates an etcd lookup. It authenticates if credentials are given and reads all keys under the prefix,
+// so that any configuration or connectivity problem is reported immediately.
+//
+// Use the Lookup method as a LookupFunc, for example:
+//
+//	etcd, err := env.NewEtcd(env.EtcdConfig{Endpoint: "http://127.0.0.1:2379", Prefix: "/myapp/"})
+//	...
+//	loader := env.NewWithLookup("", etcd.Lookup, log.Printf)
+func NewEtcd(config EtcdConfig) (*Etcd, error) {
+	if config.Endpoint == "" {
+		config.Endpoint = strings.Split(os.Getenv("ETCDCTL_ENDPOINTS"), ",")[0]
+	}
+	if config.Endpoint == "" {
+		return nil, errors.New("etcd: endpoint is not specified")
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	e := &Etcd{config: config}
+	if config.Username != "" {
+		var result struct {
+			Token string `json:"token"`
+		}
+		err := e.call(context.Background(), "auth/authenticate", map[string]string{
+			"name":     config.Username,
+			"password": config.Password,
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+		e.token = result.Token
+	}
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Lookup returns the value of the key corresponding to the given variable name.
+// It can be used as a LookupFunc.
+func (e *Etcd) Lookup(name string) (string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	value, ok := e.data[name]
+	return value, ok
+}
+
+// Refresh reads all keys under the prefix again, replacing the values returned by Lookup.
+func (e *Etcd) Refresh() error {
+	var result struct {
+		Header struct {
+			Revision int64 `json:"revision,string"`
+		} `json:"header"`
+		Kvs []etcdKeyValue `json:"kvs"`
+	}
+	prefix := []byte(e.config.Prefix)
+	err := e.call(context.Background(), "kv/range", map[string][]byte{
+		"key":       prefix,
+		"range_end": etcdPrefixEnd(prefix),
+	}, &result)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(result.Kvs))
+	for _, kv := range result.Kvs {
+		data[strings.TrimPrefix(string(kv.Key), e.config.Prefix)] = string(kv.Value)
+	}
+	e.mu.Lock()
+	e.data, e.rev = data, result.Header.Revision
+	e.mu.Unlock()
+	return nil
+}
+
+// Watch watches the keys under the prefix and applies changes to the snapshot returned by Lookup.
+// After each batch of changes is applied, onChange is called (if not nil), for example to load the
+// configuration struct again. Watch blocks until ctx is canceled or the watch fails.
+func (e *Etcd) Watch(ctx context.Context, onChange func()) error {
+	e.mu.RLock()
+	rev := e.rev
+	e.mu.RUnlock()
+
+	prefix := []byte(e.config.Prefix)
+	res, err := e.post(ctx, "watch", map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            prefix,
+			"range_end":      etcdPrefixEnd(prefix),
+			"start_revision": fmt.Sprint(rev + 1),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Canceled     bool   `json:"canceled"`
+				CancelReason string `json:"cancel_reason"`
+				Events       []struct {
+					Type string       `json:"type"`
+					Kv   etcdKeyValue `json:"kv"`
+				} `json:"events"`
+			} `json:"result"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("etcd: %w", err)
+		}
+		if msg.Result.Canceled {
+			return fmt.Errorf("etcd: watch canceled: %v", msg.Result.CancelReason)
+		}
+		if len(msg.Result.Events) == 0 {
+			continue
+		}
+
+		e.mu.Lock()
+		data := make(map[string]string, len(e.data))
+		for k, v := range e.data {
+			data[k] = v
+		}
+		for _, event := range msg.Result.Events {
+			name := strings.TrimPrefix(string(event.Kv.Key), e.config.Prefix)
+			if event.Type == "DELETE" {
+				delete(data, name)
+			} else {
+				data[name] = string(event.Kv.Value)
+			}
+			if event.Kv.ModRevision > e.rev {
+				e.rev = event.Kv.ModRevision
+			}
+		}
+		e.data = data
+		e.mu.Unlock()
+
+		if onChange != nil {
+			onChange()
+		}
+	}
+}
+
+// call sends a request to the etcd JSON gateway and decodes the response into result.
+func (e *Etcd) call(ctx context.Context, path string, data interface{}, result interface{}) error {
+	res, err := e.post(ctx, path, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("etcd: %w", err)
+	}
+	return nil
+}
+
+// post posts a JSON request to the etcd JSON gateway. It returns an error if the response status is not 200.
+func (e *Etcd) post(ctx context.Context, path string, data interface{}) (*http.Response, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint+"/v3/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", e.token)
+	}
+
+	res, err := e.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		var result struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(res.Body)
+		if json.Unmarshal(b, &result) == nil && result.Message != "" {
+			return nil, fmt.Errorf("etcd: %v: %v", path, result.Message)
+		}
+		return nil, fmt.Errorf("etcd: %v: %v", path, res.Status)
+	}
+	return res, nil
+}
+
+// etcdPrefixEnd returns the range end for reading all keys with the given prefix.
+func etcdPrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// the prefix is empty or consists of 0xff bytes only: read all keys
+	return []byte{0}
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEtcdServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			if req["password"] != "p1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"authentication failed","code":16,"message":"authentication failed"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"t1"}`))
+		case "/v3/kv/range":
+			assert.Equal(t, "L215YXBwLw==", req["key"])       // "/myapp/"
+			assert.Equal(t, "L215YXBwMA==", req["range_end"]) // "/myapp0"
+			_, _ = w.Write([]byte(`{"header":{"revision":"5"},"kvs":[` +
+				`{"key":"L215YXBwL0hPU1Q=","value":"bG9jYWxob3N0","mod_revision":"3"},` +
+				`{"key":"L215YXBwL1BPUlQ=","value":"ODA4MA==","mod_revision":"5"}]}`))
+		case "/v3/watch":
+			create := req["create_request"].(map[string]interface{})
+			assert.Equal(t, "6", create["start_revision"])
+			flusher := w.(http.Flusher)
+			_, _ = w.Write([]byte(`{"result":{"created":true}}` + "\n"))
+			flusher.Flush()
+			_, _ = w.Write([]byte(`{"result":{"events":[` +
+				`{"kv":{"key":"L215YXBwL1BPUlQ=","value":"OTA5MA==","mod_revision":"6"}},` +
+				`{"type":"DELETE","kv":{"key":"L215YXBwL0hPU1Q=","mod_revision":"7"}}]}}` + "\n"))
+			flusher.Flush()
+			_, _ = w.Write([]byte(`{"result":{"canceled":true,"cancel_reason":"compacted"}}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestNewEtcd(t *testing.T) {
+	server := newEtcdServer(t)
+	defer server.Close()
+
+	e, err := NewEtcd(EtcdConfig{Endpoint: server.URL, Prefix: "/myapp/", Username: "root", Password: "p1"})
+	if assert.Nil(t, err) {
+		value, ok := e.Lookup("HOST")
+		assert.True(t, ok)
+		assert.Equal(t, "localhost", value)
+		value, _ = e.Lookup("PORT")
+		assert.Equal(t, "8080", value)
+		_, ok = e.Lookup("URL")
+		assert.False(t, ok)
+	}
+
+	_, err = NewEtcd(EtcdConfig{Endpoint: server.URL, Prefix: "/myapp/", Username: "root", Password: "p2"})
+	assert.EqualError(t, err, "etcd: auth/authenticate: authentication failed")
+	t.Setenv("ETCDCTL_ENDPOINTS", "")
+	_, err = NewEtcd(EtcdConfig{Prefix: "/myapp/"})
+	assert.NotNil(t, err)
+}
+
+func TestEtcd_Watch(t *testing.T) {
+	server := newEtcdServer(t)
+	defer server.Close()
+
+	e, err := NewEtcd(EtcdConfig{Endpoint: server.URL, Prefix: "/myapp/"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	changes := 0
+	err = e.Watch(context.Background(), func() {
+		changes++
+		value, _ := e.Lookup("PORT")
+		assert.Equal(t, "9090", value)
+		_, ok := e.Lookup("HOST")
+		assert.False(t, ok)
+	})
+	assert.EqualError(t, err, "etcd: watch canceled: compacted")
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, int64(7), e.rev)
+}
+
+func Test_etcdPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("/myapp0"), etcdPrefixEnd([]byte("/myapp/")))
+	assert.Equal(t, []byte("b"), etcdPrefixEnd([]byte{'a', 0xff}))
+	assert.Equal(t, []byte{0}, etcdPrefixEnd(nil))
+}
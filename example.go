@@ -0,0 +1,68 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteExample writes a .env.example file for the variables read by the package-level Load function.
+// For more details, please refer to Loader.WriteExample().
+func WriteExample(w io.Writer, structPtr interface{}) error {
+	return loader.WriteExample(w, structPtr)
+}
+
+// WriteExample writes to w an example .env file listing every variable read by Load for the given struct,
+// in the order of the struct fields. The struct must be specified as a pointer.
+//
+// Each variable is preceded by a comment naming its field and type, annotated with "required" and "secret"
+// when the field is tagged so, and by the description given by the field's "desc" tag. Variables with
+// a "default" tag are assigned the default value, while the others are left empty.
+func (l *Loader) WriteExample(w io.Writer, structPtr interface{}) error {
+	m, err := l.ExportManifest(structPtr)
+	if err != nil {
+		return err
+	}
+	for i, v := range m.Vars {
+		notes := []string{v.Type}
+		if v.Required {
+			notes = append(notes, "required")
+		}
+		if v.Secret {
+			notes = append(notes, "secret")
+		}
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# %v (%v)\n", v.Field, strings.Join(notes, ", ")); err != nil {
+			return err
+		}
+		for _, line := range strings.Split(v.Description, "\n") {
+			if line == "" {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "# %v\n", line); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%v=%v\n", v.Name, dotenvValue(v.Default)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotenvValue formats a value for a .env file, quoting it if it cannot be written as is.
+func dotenvValue(value string) string {
+	if !strings.ContainsAny(value, " \t\r\n#'\"\\") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_WriteExample(t *testing.T) {
+	type config struct {
+		Host     string   `required:"true"`
+		Port     int      `default:"80" desc:"The port to listen on."`
+		Password string   `env:",secret" required:"true"`
+		Greeting string   `default:"hello \"world\""`
+		Nested   Embedded `prefix:"NESTED_"`
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, NewWithLookup("APP_", mockLookup, nil).WriteExample(&buf, &config{}))
+	assert.Equal(t, `# Host (string, required)
+APP_HOST=
+
+# Port (int)
+# The port to listen on.
+APP_PORT=80
+
+# Password (string, required, secret)
+APP_PASSWORD=
+
+# Greeting (string)
+APP_GREETING="hello \"world\""
+
+# Nested.URL (string)
+APP_NESTED_URL=
+
+# Nested.Port (int)
+APP_NESTED_PORT=
+`, buf.String())
+
+	vars, err := parseDotenv(&buf)
+	if assert.Nil(t, err) {
+		assert.Equal(t, `hello "world"`, vars["APP_GREETING"])
+	}
+
+	assert.Equal(t, ErrStructPointer, WriteExample(&buf, config{}))
+}
+
+func Test_dotenvValue(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value string
+		want  string
+	}{
+		{"t1", "", ""},
+		{"t2", "abc", "abc"},
+		{"t3", "a b", `"a b"`},
+		{"t4", "a#b", `"a#b"`},
+		{"t5", "a\nb\\", `"a\nb\\"`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, dotenvValue(test.value), test.tag)
+		vars, err := parseDotenv(strings.NewReader("A=" + dotenvValue(test.value)))
+		if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, test.value, vars["A"], test.tag)
+		}
+	}
+}
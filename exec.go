@@ -0,0 +1,55 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// CommandScheme is the scheme of the values resolved by ExecCommands.
+const CommandScheme = "cmd://"
+
+// ExecCommands returns a Transformer resolving values of the form "cmd://<command> <args>" to the output of the
+// command, so that secret-manager CLIs such as the 1Password CLI or pass can be used without glue code:
+//
+//	loader := env.New("APP_", log.Printf, env.WithTransformers(env.ExecCommands("op", "pass")))
+//
+// With this loader, $APP_DB_PASSWORD set to "cmd://op read op://vault/db/password" is resolved to the password
+// printed by op. The command is run directly, not through a shell, with its arguments split at white space, and its
+// standard output is used with the trailing line break removed. Only the programs listed in allowed can be run,
+// matched against the first word of the command as is, so that a variable cannot run arbitrary programs. Values
+// without the scheme are returned unchanged.
+//
+// An error is returned if the program is not allowed, or if the command fails, in which case the error includes
+// the standard error of the command.
+func ExecCommands(allowed ...string) Transformer {
+	return func(value string) (string, error) {
+		if !strings.HasPrefix(value, CommandScheme) {
+			return value, nil
+		}
+		args := strings.Fields(strings.TrimPrefix(value, CommandScheme))
+		if len(args) == 0 {
+			return "", errors.New("empty command")
+		}
+		if !slices.Contains(allowed, args[0]) {
+			return "", fmt.Errorf("command %q is not allowed", args[0])
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("command %q: %w: %v", args[0], err, msg)
+			}
+			return "", fmt.Errorf("command %q: %w", args[0], err)
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(stdout.String(), "\n"), "\r"), nil
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecCommands(t *testing.T) {
+	exec := ExecCommands("echo", "sh")
+
+	value, err := exec("cmd://echo s3cret  value")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cret value", value)
+
+	value, err = exec("plain")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", value)
+
+	_, err = exec("cmd://cat /etc/passwd")
+	assert.EqualError(t, err, `command "cat" is not allowed`)
+
+	_, err = exec("cmd:// ")
+	assert.EqualError(t, err, "empty command")
+
+	_, err = exec("cmd://sh ./missing-script")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "missing-script")
+	}
+
+	var cfg struct {
+		Password string
+		Host     string
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{
+		"APP_PASSWORD": "cmd://echo pa55",
+		"APP_HOST":     "localhost",
+	}), nil, WithTransformers(exec))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "pa55", cfg.Password)
+		assert.Equal(t, "localhost", cfg.Host)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "expvar"
+
+// Expvar returns an expvar.Var exposing the statistics of the loader (see Stats) as a JSON object, so that they can
+// be published with the other metrics of a service:
+//
+//	expvar.Publish("config", loader.Expvar())
+//
+// The object has the fields "loads", "errors", "fields_set", "fields_defaulted", "fields_missing", "reloads",
+// "reload_errors", "cache_hits", "lookup_errors" and "config_hash", and "last_load" and "last_error" once a struct has been loaded and a load
+// has failed respectively. The values are read when the variable is formatted, so they are always current.
+// The same fields can be exported to Prometheus by a collector calling Stats.
+func (l *Loader) Expvar() expvar.Var {
+	return expvar.Func(func() interface{} {
+		s := l.Stats()
+		m := map[string]interface{}{
+			"loads":            s.Loads,
+			"errors":           s.Errors,
+			"fields_set":       s.FieldsSet,
+			"fields_defaulted": s.FieldsDefaulted,
+			"fields_missing":   s.FieldsMissing,
+			"reloads":          s.Reloads,
+			"reload_errors":    s.ReloadErrors,
+			"cache_hits":       s.CacheHits,
+			"lookup_errors":    s.LookupErrors,
+			"config_hash":      s.ConfigHash,
+		}
+		if !s.LastLoad.IsZero() {
+			m["last_load"] = s.LastLoad
+		}
+		if s.LastError != nil {
+			m["last_error"] = s.LastError.Error()
+		}
+		return m
+	})
+}
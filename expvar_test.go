@@ -0,0 +1,37 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Expvar(t *testing.T) {
+	l := NewWithLookup("", MapLookup(map[string]string{"HOST": "localhost"}), nil)
+	v := l.Expvar()
+
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &m))
+	assert.Equal(t, float64(0), m["loads"])
+	assert.Equal(t, "", m["config_hash"])
+	assert.Equal(t, float64(0), m["cache_hits"])
+	assert.Equal(t, float64(0), m["lookup_errors"])
+	assert.NotContains(t, m, "last_load")
+
+	var cfg Config1
+	assert.Nil(t, l.Load(&cfg))
+	assert.NotNil(t, l.Load(cfg))
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &m))
+	assert.Equal(t, float64(2), m["loads"])
+	assert.Equal(t, float64(1), m["errors"])
+	assert.Equal(t, float64(1), m["fields_set"])
+	assert.Equal(t, float64(3), m["fields_missing"])
+	assert.Equal(t, l.Stats().ConfigHash, m["config_hash"])
+	assert.Equal(t, ErrStructPointer.Error(), m["last_error"])
+	assert.Contains(t, m, "last_load")
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileSource reads a configuration file in the JSON, YAML or TOML format, as determined by its extension
+// (".json", ".yaml", ".yml" or ".toml"), and returns a Source looking up the values in it, labeled with the file
+// name. Used with WithSources or Layer, it lets a struct be loaded from a file with environment variables taking
+// precedence, using the same tags:
+//
+//	source, err := env.FileSource("config.yaml")
+//	...
+//	loader := env.New("APP_", log.Printf, env.WithSources(source))
+//
+// A variable is looked up by the path of its key without the loader prefix, so the Host field of a struct nested
+// with `prefix:"DB_"` is read from:
+//
+//	db:
+//	  host: localhost
+//
+// Names are matched case-insensitively, and dots, hyphens and underscores are equivalent, so the value above can
+// also be given as "db_host" or "DB.HOST". Scalar values are formatted as Load parses them, while arrays and
+// objects are encoded in JSON, so that they can populate slice, map and struct fields.
+//
+// A file with the ".env" extension is read in the .env format (see Parse), and its variables are looked up by their
+// names including the loader prefix.
+func FileSource(file string) (Source, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Source{}, err
+	}
+	var tree map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".json":
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		err = d.Decode(&tree)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	case ".env":
+		vars, err := parseDotenv(bytes.NewReader(data))
+		if err != nil {
+			return Source{}, fmt.Errorf("%v: %w", file, err)
+		}
+		return Source{Label: file, Lookup: MapLookup(vars)}, nil
+	default:
+		return Source{}, fmt.Errorf("%v: unsupported file format %q", file, ext)
+	}
+	if err != nil {
+		return Source{}, fmt.Errorf("%v: %w", file, err)
+	}
+
+	values := map[string]string{}
+	if err := flattenTree(values, "", tree); err != nil {
+		return Source{}, fmt.Errorf("%v: %w", file, err)
+	}
+	return Source{
+		Label:  file,
+		Lookup: MapLookup(values),
+		Name: func(key Key) string {
+			return normalizeFileKey(strings.Join(key.Path, "."))
+		},
+	}, nil
+}
+
+// flattenTree adds to values the scalars of a decoded configuration file, keyed by their normalized paths.
+// Arrays and objects are added as well, encoded in JSON.
+func flattenTree(values map[string]string, path string, node interface{}) error {
+	switch node := node.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key, child := range node {
+			if err := flattenTree(values, joinFileKey(path, key), child); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(node))
+		for key, child := range node {
+			m[fmt.Sprint(key)] = child
+		}
+		return flattenTree(values, path, m)
+	case time.Time:
+		values[path] = node.Format(time.RFC3339Nano)
+		return nil
+	case []interface{}:
+	default:
+		values[path] = fmt.Sprint(node)
+		return nil
+	}
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(jsonTree(node))
+	if err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	values[path] = string(data)
+	return nil
+}
+
+// jsonTree converts the maps with non-string keys of a decoded configuration file, which cannot be encoded
+// in JSON, into maps with string keys.
+func jsonTree(node interface{}) interface{} {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(node))
+		for key, child := range node {
+			m[key] = jsonTree(child)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(node))
+		for key, child := range node {
+			m[fmt.Sprint(key)] = jsonTree(child)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(node))
+		for i, child := range node {
+			s[i] = jsonTree(child)
+		}
+		return s
+	}
+	return node
+}
+
+// joinFileKey appends a key to the normalized path of its parent.
+func joinFileKey(path, key string) string {
+	if path == "" {
+		return normalizeFileKey(key)
+	}
+	return path + "_" + normalizeFileKey(key)
+}
+
+// normalizeFileKey normalizes a key of a configuration file, so that keys differing only in case and in the use
+// of dots, hyphens and underscores as separators are equal.
+func normalizeFileKey(key string) string {
+	return fileKeyReplacer.Replace(strings.ToLower(key))
+}
+
+// fileKeyReplacer replaces the separators in the keys of configuration files with underscores.
+var fileKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
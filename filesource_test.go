@@ -0,0 +1,81 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fileConfig struct {
+	Host    string
+	Port    int
+	Debug   bool
+	Tags    []string
+	Limits  map[string]int
+	Started string
+	DB      struct {
+		Host    string
+		MaxConn int
+	} `prefix:"DB_"`
+}
+
+func TestFileSource(t *testing.T) {
+	expected := fileConfig{Host: "localhost", Port: 8080, Debug: true, Tags: []string{"a", "b"}, Limits: map[string]int{"rps": 10}, Started: "2024-01-02T03:04:05Z"}
+	expected.DB.Host = "db"
+	expected.DB.MaxConn = 5
+
+	tests := []struct {
+		tag  string
+		file string
+		data string
+	}{
+		{"t1", "config.json", `{"host": "localhost", "port": 8080, "debug": true, "tags": ["a", "b"], "limits": {"rps": 10},
+			"started": "2024-01-02T03:04:05Z", "db": {"host": "db", "max-conn": 5}}`},
+		{"t2", "config.yaml", "host: localhost\nport: 8080\ndebug: true\ntags: [a, b]\nlimits:\n  rps: 10\n" +
+			"started: 2024-01-02T03:04:05Z\ndb:\n  host: db\n  max_conn: 5\n"},
+		{"t3", "config.toml", "host = \"localhost\"\nport = 8080\ndebug = true\ntags = [\"a\", \"b\"]\n" +
+			"started = 2024-01-02T03:04:05Z\nDB_MAX_CONN = 5\n[limits]\nrps = 10\n[db]\nhost = \"db\"\n"},
+	}
+	for _, test := range tests {
+		file := filepath.Join(t.TempDir(), test.file)
+		assert.Nil(t, os.WriteFile(file, []byte(test.data), 0o600), test.tag)
+		source, err := FileSource(file)
+		if !assert.Nil(t, err, test.tag) {
+			continue
+		}
+		var cfg fileConfig
+		if assert.Nil(t, NewWithLookup("APP_", MapLookup(nil), nil, WithSources(source)).Load(&cfg), test.tag) {
+			assert.Equal(t, expected, cfg, test.tag)
+		}
+	}
+
+	// variables take precedence over the file
+	file := filepath.Join(t.TempDir(), "config.yml")
+	assert.Nil(t, os.WriteFile(file, []byte("host: localhost\nport: 8080\n"), 0o600))
+	source, err := FileSource(file)
+	if assert.Nil(t, err) {
+		var cfg fileConfig
+		lookup := MapLookup(map[string]string{"APP_PORT": "9090"})
+		if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithSources(source)).Load(&cfg)) {
+			assert.Equal(t, "localhost", cfg.Host)
+			assert.Equal(t, 9090, cfg.Port)
+		}
+	}
+
+	_, err = FileSource(filepath.Join(t.TempDir(), "config.ini"))
+	assert.NotNil(t, err)
+	file = filepath.Join(t.TempDir(), "config.ini")
+	assert.Nil(t, os.WriteFile(file, nil, 0o600))
+	_, err = FileSource(file)
+	assert.EqualError(t, err, file+`: unsupported file format ".ini"`)
+	file = filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(file, []byte("{"), 0o600))
+	_, err = FileSource(file)
+	assert.EqualError(t, err, file+": unexpected EOF")
+}
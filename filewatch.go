@@ -0,0 +1,125 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileChangeDelay is the time to wait after a change of a watched file before reloading, so that the changes made
+// in quick succession, such as by an editor saving a file, result in a single reload.
+var fileChangeDelay = 100 * time.Millisecond
+
+// dotenvFiles holds the variables defined in a list of .env files, overriding each other in order.
+// It is only read and refreshed by the goroutine loading a watched struct.
+type dotenvFiles struct {
+	files []string
+	vars  map[string]string
+}
+
+// WatchFiles is like Watch, but it reloads the struct whenever one of the given .env files changes instead of
+// periodically. The variables defined in the files are looked up when the loader's lookup function does not find
+// them, so that the process environment still takes precedence, and those of later files override those of earlier
+// ones. The files are parsed again on every change: a file that cannot be read or parsed makes the reload fail,
+// keeping the current configuration.
+//
+// The changes are detected with fsnotify by watching the directories of the files, so that files replaced by
+// editors or deployment tools are picked up as well as files written in place.
+//
+//	w, err := loader.WatchFiles(ctx, &cfg, ".env", ".env.local")
+//	...
+//	for change := range w.Events() {
+//		...
+//	}
+//
+// An error is returned if the initial load fails, including when a file cannot be read.
+func (l *Loader) WatchFiles(ctx context.Context, structPtr interface{}, files ...string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, file := range files {
+		names[filepath.Clean(file)] = true
+		if err := fsw.Add(filepath.Dir(file)); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	d := &dotenvFiles{files: files}
+	loader, lookup := *l, l.lookup
+	loader.lookup = func(name string) (string, bool) {
+		if value, ok := lookup(name); ok {
+			return value, true
+		}
+		value, ok := d.vars[name]
+		return value, ok
+	}
+	w, err := loader.newWatcher(structPtr, d.read)
+	if err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	reloads := make(chan time.Time)
+	go watchFileEvents(fsw, names, reloads, w.done)
+	go w.run(ctx, reloads, func() { fsw.Close() })
+	return w, nil
+}
+
+// read parses the files again, replacing the variables only if all of them are parsed successfully.
+func (d *dotenvFiles) read() error {
+	vars := map[string]string{}
+	for _, file := range d.files {
+		v, err := readDotenvFile(file)
+		if err != nil {
+			return err
+		}
+		maps.Copy(vars, v)
+	}
+	d.vars = vars
+	return nil
+}
+
+// watchFileEvents sends the time of the changes of the named files reported by fsw to reloads, after waiting for
+// fileChangeDelay without further changes. Errors of fsw are treated as changes, as events may have been lost.
+// It returns when fsw is closed or done is closed.
+func watchFileEvents(fsw *fsnotify.Watcher, names map[string]bool, reloads chan<- time.Time, done <-chan struct{}) {
+	timer := time.NewTimer(fileChangeDelay)
+	timer.Stop()
+	var pending <-chan time.Time
+	for {
+		select {
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if names[filepath.Clean(event.Name)] && event.Op != fsnotify.Chmod {
+				timer.Reset(fileChangeDelay)
+				pending = timer.C
+			}
+		case _, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			timer.Reset(fileChangeDelay)
+			pending = timer.C
+		case t := <-pending:
+			pending = nil
+			select {
+			case reloads <- t:
+			case <-done:
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_WatchFiles(t *testing.T) {
+	delay := fileChangeDelay
+	defer func() { fileChangeDelay = delay }()
+	fileChangeDelay = time.Millisecond
+	dir := t.TempDir()
+	file, local := filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local")
+	_ = os.WriteFile(file, []byte("APP_HOST=localhost\nAPP_PORT=80\n"), 0600)
+	_ = os.WriteFile(local, []byte("APP_PORT=8080\n"), 0600)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type config struct {
+		Host string
+		Port int
+		Name string
+	}
+	lookup := MapLookup(map[string]string{"APP_NAME": "app", "APP_HOST": "example.com"})
+	w, err := NewWithLookup("APP_", lookup, nil).WatchFiles(ctx, &config{}, file, local)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, &config{Host: "example.com", Port: 8080, Name: "app"}, w.Current())
+
+	_ = os.WriteFile(local, []byte("APP_PORT=9090\n"), 0600)
+	change := <-w.Events()
+	assert.Nil(t, change.Err)
+	assert.Equal(t, []FieldDiff{{Field: "Port", Var: "APP_PORT", Left: "8080", Right: "9090"}}, change.Changes)
+	assert.Equal(t, &config{Host: "example.com", Port: 9090, Name: "app"}, change.Config)
+
+	// files replaced by renaming are picked up
+	_ = os.WriteFile(filepath.Join(dir, "tmp"), []byte("APP_PORT=80\n"), 0600)
+	_ = os.Rename(filepath.Join(dir, "tmp"), local)
+	change = <-w.Events()
+	assert.Nil(t, change.Err)
+	assert.Equal(t, 80, change.Config.(*config).Port)
+
+	_ = os.WriteFile(file, []byte("APP_HOST\n"), 0600)
+	change = <-w.Events()
+	assert.EqualError(t, change.Err, "line 1: missing '='")
+	assert.Equal(t, 80, w.Current().(*config).Port)
+
+	report, err := w.Freeze(context.Background())
+	assert.Nil(t, err)
+	assert.NotNil(t, report)
+
+	_, err = New("APP_", nil).WatchFiles(ctx, &config{}, filepath.Join(dir, "missing.env"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = New("APP_", nil).WatchFiles(ctx, config{}, file)
+	assert.Equal(t, ErrStructPointer, err)
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Flags holds a set of boolean feature flags loaded from variables.
+// It is safe for concurrent use, so flags can be checked while they are being reloaded.
+type Flags struct {
+	prefix   string
+	lookup   LookupFunc
+	log      LogFunc
+	defaults map[string]bool
+	extended bool
+
+	mu     sync.RWMutex
+	values map[string]bool
+}
+
+// LoadFlags loads feature flags from the "APP_FF_*" environment variables. For more details, see Loader.LoadFlags().
+func LoadFlags(defaults map[string]bool) (*Flags, error) {
+	return loader.LoadFlags(defaults)
+}
+
+// LoadFlags loads the feature flags named by the keys of defaults. A flag is read from the variable formed by
+// the loader prefix, "FF_" and the flag name (e.g. $APP_FF_NEW_CHECKOUT for the "NEW_CHECKOUT" flag), and takes
+// its default value when the variable is not set. Values are parsed with strconv.ParseBool, and may use extended
+// spellings such as "yes" if the loader is configured so (see WithExtendedBools).
+//
+// An error is returned if any variable has an invalid value. Such a flag is left at its default.
+func (l *Loader) LoadFlags(defaults map[string]bool) (*Flags, error) {
+	f := &Flags{
+		prefix:   l.prefix + "FF_",
+		lookup:   l.lookup,
+		log:      l.log,
+		defaults: defaults,
+		extended: l.extendedBools,
+		values:   make(map[string]bool, len(defaults)),
+	}
+	for name, enabled := range defaults {
+		f.values[name] = enabled
+	}
+	_, err := f.Reload()
+	return f, err
+}
+
+// IsEnabled returns whether the named flag is enabled. Unknown flags are disabled.
+func (f *Flags) IsEnabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.values[name]
+}
+
+// All returns a copy of the current values of all flags.
+func (f *Flags) All() map[string]bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	values := make(map[string]bool, len(f.values))
+	for name, enabled := range f.values {
+		values[name] = enabled
+	}
+	return values
+}
+
+// Reload reads the flag variables again and returns the names of the flags whose values changed, in sorted order.
+// A flag whose variable is no longer set returns to its default. A flag whose variable has an invalid value keeps
+// its current value, and the problem is reported in the returned error.
+func (f *Flags) Reload() ([]string, error) {
+	names := make([]string, 0, len(f.defaults))
+	for name := range f.defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		changed []string
+		errs    []error
+	)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range names {
+		enabled := f.defaults[name]
+		if value, ok := f.lookup(f.prefix + name); ok {
+			if f.extended {
+				value = normalizeBool(value)
+			}
+			var err error
+			if enabled, err = strconv.ParseBool(value); err != nil {
+				errs = append(errs, fmt.Errorf("$%v%v: %w", f.prefix, name, err))
+				continue
+			}
+		}
+		if f.values[name] != enabled {
+			f.values[name] = enabled
+			changed = append(changed, name)
+			if f.log != nil {
+				f.log("set flag %v to %v", name, enabled)
+			}
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+// Watch reloads the flags at the given interval and calls onChange for every flag whose value changed.
+// Invalid values are ignored until they are fixed, as the affected flags keep their current values.
+// Watch blocks until ctx is canceled and returns the context error. It returns an error immediately if the interval
+// is not positive.
+func (f *Flags) Watch(ctx context.Context, interval time.Duration, onChange func(name string, enabled bool)) error {
+	if interval <= 0 {
+		return fmt.Errorf("the reload interval must be positive, got %v", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			changed, _ := f.Reload()
+			if onChange == nil {
+				continue
+			}
+			for _, name := range changed {
+				onChange(name, f.IsEnabled(name))
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_LoadFlags(t *testing.T) {
+	vars := map[string]string{"APP_FF_NEW_CHECKOUT": "true", "APP_FF_BETA": "0"}
+	var logger myLogger
+	l := NewWithLookup("APP_", MapLookup(vars), logger.Log)
+	flags, err := l.LoadFlags(map[string]bool{"NEW_CHECKOUT": false, "BETA": true, "DARK_MODE": true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, flags.IsEnabled("NEW_CHECKOUT"))
+	assert.False(t, flags.IsEnabled("BETA"))
+	assert.True(t, flags.IsEnabled("DARK_MODE"))
+	assert.False(t, flags.IsEnabled("UNKNOWN"))
+	assert.Equal(t, map[string]bool{"NEW_CHECKOUT": true, "BETA": false, "DARK_MODE": true}, flags.All())
+	assert.Equal(t, []string{"set flag BETA to false", "set flag NEW_CHECKOUT to true"}, logger.logs)
+
+	delete(vars, "APP_FF_BETA")
+	vars["APP_FF_DARK_MODE"] = "false"
+	changed, err := flags.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"BETA", "DARK_MODE"}, changed)
+	assert.True(t, flags.IsEnabled("BETA"))
+
+	vars["APP_FF_NEW_CHECKOUT"] = "maybe"
+	changed, err = flags.Reload()
+	assert.EqualError(t, err, `$APP_FF_NEW_CHECKOUT: strconv.ParseBool: parsing "maybe": invalid syntax`)
+	assert.Nil(t, changed)
+	assert.True(t, flags.IsEnabled("NEW_CHECKOUT"))
+
+	flags, err = NewWithLookup("APP_", MapLookup(vars), nil).LoadFlags(map[string]bool{"NEW_CHECKOUT": false})
+	assert.NotNil(t, err)
+	assert.False(t, flags.IsEnabled("NEW_CHECKOUT"))
+}
+
+func TestFlags_Watch(t *testing.T) {
+	var mu sync.Mutex
+	vars := map[string]string{}
+	lookup := func(name string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := vars[name]
+		return value, ok
+	}
+	flags, _ := NewWithLookup("APP_", lookup, nil).LoadFlags(map[string]bool{"BETA": false})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan bool, 1)
+	done := make(chan error)
+	go func() {
+		done <- flags.Watch(ctx, time.Millisecond, func(name string, enabled bool) {
+			assert.Equal(t, "BETA", name)
+			changes <- enabled
+		})
+	}()
+
+	mu.Lock()
+	vars["APP_FF_BETA"] = "true"
+	mu.Unlock()
+	select {
+	case enabled := <-changes:
+		assert.True(t, enabled)
+	case <-time.After(time.Second):
+		assert.Fail(t, "no change reported")
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	assert.EqualError(t, flags.Watch(context.Background(), -time.Second, nil), "the reload interval must be positive, got -1s")
+}
@@ -0,0 +1,255 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type (
+	// GCPSecretManagerConfig configures a Google Cloud Secret Manager lookup.
+	GCPSecretManagerConfig struct {
+		// Project is the ID or number of the project owning the secrets. Defaults to $GOOGLE_CLOUD_PROJECT.
+		Project string
+		// Version is the secret version to access. Defaults to "latest".
+		Version string
+		// Versions pins the versions of individual secrets, keyed by secret ID, overriding Version.
+		Versions map[string]string
+		// Secret maps a variable name to a secret ID. Defaults to using the variable name as is.
+		Secret func(name string) string
+		// Token returns the access token used to call the API. Defaults to GCPDefaultToken().
+		Token TokenFunc
+		// Endpoint is the Secret Manager API endpoint. Defaults to "https://secretmanager.googleapis.com".
+		Endpoint string
+		// Client is the HTTP client used to call the API. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// GCPSecretManager looks up values from secrets stored in Google Cloud Secret Manager.
+	GCPSecretManager struct {
+		config GCPSecretManagerConfig
+		errs   lookupErrors
+	}
+)
+
+// gcpMetadataHost is the host of the GCE metadata server.
+var gcpMetadataHost = "metadata.google.internal"
+
+// NewGCPSecretManager creates a Google Cloud Secret Manager lookup.
+// It obtains an access token so that credential problems are reported immediately.
+//
+// Use the Lookup method as a LookupFunc, for example:
+//
+//	sm, err := env.NewGCPSecretManager(env.GCPSecretManagerConfig{Project: "my-project"})
+//	...
+//	loader := env.NewWithLookup("APP_", sm.Lookup, log.Printf)
+func NewGCPSecretManager(config GCPSecretManagerConfig) (*GCPSecretManager, error) {
+	if config.Project == "" {
+		config.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if config.Project == "" {
+		return nil, errors.New("gcp: project is not specified")
+	}
+	if config.Version == "" {
+		config.Version = "latest"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://secretmanager.googleapis.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Token == nil {
+		config.Token = GCPDefaultToken()
+	}
+	if _, err := config.Token(context.Background()); err != nil {
+		return nil, fmt.Errorf("gcp: %w", err)
+	}
+	return &GCPSecretManager{config: config}, nil
+}
+
+// Lookup returns the payload of the secret corresponding to the given variable name.
+// It can be used as a LookupFunc. A secret that cannot be accessed is treated as not found,
+// and the failure is reported by Err.
+func (sm *GCPSecretManager) Lookup(name string) (string, bool) {
+	value, ok, err := sm.LookupContext(context.Background(), name)
+	sm.errs.record(name, err)
+	return value, ok
+}
+
+// LookupContext returns the payload of the secret corresponding to the given variable name.
+// Unlike Lookup, it returns an error if the secret cannot be accessed for reasons other than not existing.
+func (sm *GCPSecretManager) LookupContext(ctx context.Context, name string) (string, bool, error) {
+	secret := name
+	if sm.config.Secret != nil {
+		secret = sm.config.Secret(name)
+	}
+	version := sm.config.Version
+	if v, ok := sm.config.Versions[secret]; ok {
+		version = v
+	}
+
+	token, err := sm.config.Token(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: %w", err)
+	}
+	u := fmt.Sprintf("%v/v1/projects/%v/secrets/%v/versions/%v:access", sm.config.Endpoint,
+		url.PathEscape(sm.config.Project), url.PathEscape(secret), url.PathEscape(version))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := sm.config.Client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	var result struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil && res.StatusCode == http.StatusOK {
+		return "", false, fmt.Errorf("gcp: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if result.Error.Message != "" {
+			return "", false, fmt.Errorf("gcp: secret %v: %v", secret, result.Error.Message)
+		}
+		return "", false, fmt.Errorf("gcp: secret %v: %v", secret, res.Status)
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: secret %v: %w", secret, err)
+	}
+	return string(data), true, nil
+}
+
+// Err returns the failures of the most recent Lookup calls for names that could not be accessed,
+// or nil if every name was accessed successfully.
+func (sm *GCPSecretManager) Err() error {
+	return sm.errs.err()
+}
+
+// LookupStats returns the number of Lookup calls that failed to access a secret, and no cache hits, so that the
+// failures are counted in the statistics of a loader (see WithStatsReporters).
+func (sm *GCPSecretManager) LookupStats() (cacheHits, errors uint64) {
+	return sm.errs.stats()
+}
+
+// GCPDefaultToken returns a TokenFunc that follows the Application Default Credentials strategy:
+// it uses the service account or authorized user credentials file specified by $GOOGLE_APPLICATION_CREDENTIALS
+// if set, and the GCE metadata server otherwise. Tokens are cached until they are about to expire.
+func GCPDefaultToken() TokenFunc {
+	return newCachedToken(func(ctx context.Context) (string, time.Duration, error) {
+		if file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); file != "" {
+			return gcpCredentialsToken(ctx, file)
+		}
+		return requestToken(ctx, http.DefaultClient,
+			"http://"+gcpMetadataHost+"/computeMetadata/v1/instance/service-accounts/default/token",
+			nil, http.Header{"Metadata-Flavor": {"Google"}})
+	})
+}
+
+// gcpCredentialsToken obtains an access token using a Google credentials JSON file.
+func gcpCredentialsToken(ctx context.Context, file string) (string, time.Duration, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", 0, err
+	}
+	var creds struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", 0, err
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	switch creds.Type {
+	case "authorized_user":
+		return requestToken(ctx, http.DefaultClient, creds.TokenURI, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}, nil)
+	case "service_account":
+		assertion, err := gcpAssertion(creds.ClientEmail, creds.PrivateKey, creds.TokenURI)
+		if err != nil {
+			return "", 0, err
+		}
+		return requestToken(ctx, http.DefaultClient, creds.TokenURI, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}, nil)
+	}
+	return "", 0, fmt.Errorf("unsupported credentials type %q", creds.Type)
+}
+
+// gcpAssertion creates a signed JWT used to exchange service account credentials for an access token.
+func gcpAssertion(email, privateKey, audience string) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", errors.New("invalid private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private key is not an RSA key")
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   email,
+		"scope": "https://www.googleapis.com/auth/cloud-platform",
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
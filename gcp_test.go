@@ -0,0 +1,150 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func staticToken(token string) TokenFunc {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+func TestGCPSecretManager_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":401,"message":"unauthenticated"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/projects/p1/secrets/APP_PASSWORD/versions/latest:access":
+			_, _ = w.Write([]byte(`{"payload":{"data":"eHl6"}}`))
+		case "/v1/projects/p1/secrets/APP_PASSWORD/versions/2:access":
+			_, _ = w.Write([]byte(`{"payload":{"data":"YWJj"}}`))
+		case "/v1/projects/p1/secrets/APP_DENIED/versions/latest:access":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":403,"message":"permission denied"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sm, err := NewGCPSecretManager(GCPSecretManagerConfig{Project: "p1", Endpoint: server.URL, Token: staticToken("t1")})
+	if !assert.Nil(t, err) {
+		return
+	}
+	value, ok := sm.Lookup("APP_PASSWORD")
+	assert.True(t, ok)
+	assert.Equal(t, "xyz", value)
+	_, ok = sm.Lookup("APP_HOST")
+	assert.False(t, ok)
+	assert.Nil(t, sm.Err())
+
+	_, ok = sm.Lookup("APP_DENIED")
+	assert.False(t, ok)
+	assert.EqualError(t, sm.Err(), "gcp: secret APP_DENIED: permission denied")
+	_, failures := sm.LookupStats()
+	assert.Equal(t, uint64(1), failures)
+
+	sm, _ = NewGCPSecretManager(GCPSecretManagerConfig{
+		Project:  "p1",
+		Endpoint: server.URL,
+		Token:    staticToken("t1"),
+		Versions: map[string]string{"APP_PASSWORD": "2"},
+		Secret:   func(name string) string { return "APP_" + name },
+	})
+	value, _ = sm.Lookup("PASSWORD")
+	assert.Equal(t, "abc", value)
+
+	sm, _ = NewGCPSecretManager(GCPSecretManagerConfig{Project: "p1", Endpoint: server.URL, Token: staticToken("t2")})
+	_, _, err = sm.LookupContext(context.Background(), "APP_PASSWORD")
+	assert.EqualError(t, err, "gcp: secret APP_PASSWORD: unauthenticated")
+
+	_, err = NewGCPSecretManager(GCPSecretManagerConfig{Project: "p1", Token: func(context.Context) (string, error) {
+		return "", errors.New("no credentials")
+	}})
+	assert.EqualError(t, err, "gcp: no credentials")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	_, err = NewGCPSecretManager(GCPSecretManagerConfig{Token: staticToken("t1")})
+	assert.NotNil(t, err)
+}
+
+func TestGCPDefaultToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token":
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			_, _ = w.Write([]byte(`{"access_token":"metadata","expires_in":3599}`))
+		case r.URL.Path == "/token" && r.FormValue("grant_type") == "refresh_token":
+			_, _ = w.Write([]byte(`{"access_token":"user","expires_in":3599}`))
+		case r.URL.Path == "/token" && strings.Count(r.FormValue("assertion"), ".") == 2:
+			_, _ = w.Write([]byte(`{"access_token":"sa","expires_in":3599}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"bad request"}`))
+		}
+	}))
+	defer server.Close()
+
+	oldHost := gcpMetadataHost
+	gcpMetadataHost = strings.TrimPrefix(server.URL, "http://")
+	defer func() { gcpMetadataHost = oldHost }()
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	token, err := GCPDefaultToken()(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "metadata", token)
+
+	dir := t.TempDir()
+	writeCreds := func(creds map[string]string) string {
+		creds["token_uri"] = server.URL + "/token"
+		data, _ := json.Marshal(creds)
+		file := filepath.Join(dir, creds["type"]+".json")
+		assert.Nil(t, os.WriteFile(file, data, 0600))
+		return file
+	}
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeCreds(map[string]string{"type": "authorized_user", "refresh_token": "r1"}))
+	token, err = GCPDefaultToken()(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "user", token)
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeCreds(map[string]string{
+		"type":         "service_account",
+		"client_email": "sa@example.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}))
+	token, err = GCPDefaultToken()(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "sa", token)
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeCreds(map[string]string{"type": "service_account", "private_key": "x"}))
+	_, err = GCPDefaultToken()(context.Background())
+	assert.EqualError(t, err, "invalid private key")
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeCreds(map[string]string{"type": "external_account"}))
+	_, err = GCPDefaultToken()(context.Background())
+	assert.EqualError(t, err, `unsupported credentials type "external_account"`)
+}
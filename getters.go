@@ -0,0 +1,210 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteGetters writes to w the Go source code of typed, nil-safe getter methods for the fields populated by Load
+// in the given struct, so that a configuration can be accessed without touching its fields directly.
+// The struct must be specified as a pointer, and the code is generated for the package declaring its type.
+//
+// A getter is generated for every field, named after the field path with a "Get" prefix (e.g. GetServerPort for
+// the field Server.Port). It returns the field value, or the value of the field's "default" tag if the receiver
+// or a pointer to a nested struct along the way is nil. Defaults of types other than the primary types, and fields
+// without defaults, fall back to the zero value.
+//
+// WriteGetters is typically called by a small program run with go:generate:
+//
+//	//go:generate go run ./gen
+//
+//	// gen/main.go
+//	func main() {
+//		f, _ := os.Create("config_getters.go")
+//		defer f.Close()
+//		if err := env.WriteGetters(f, &config.Config{}); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func WriteGetters(w io.Writer, structPtr interface{}) error {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrStructPointer
+	}
+	t := value.Elem().Type()
+	if t.Name() == "" {
+		return fmt.Errorf("getters cannot be generated for the unnamed type %v", t)
+	}
+	fields, err := getFields(t)
+	if err != nil {
+		return err
+	}
+
+	g := &codeGenerator{pkgPath: t.PkgPath(), imports: map[string]string{}}
+	var body bytes.Buffer
+	for _, f := range fields {
+		if !supportedKind(elemKind(f.typ)) {
+			continue
+		}
+		zero, def := "v", "the zero value"
+		if f.hasDefault {
+			if lit, ok := goLiteral(f.typ, f.def); ok {
+				zero, def = lit, lit
+			}
+		}
+		name := g.methodName(f.path)
+		fmt.Fprintf(&body, "// Get%v returns the value of %v, or %v if it is not available.\n", name, f.path, def)
+		fmt.Fprintf(&body, "func (c *%v) Get%v() (v %v) {\n", t.Name(), name, g.typeExpr(f.typ))
+		fmt.Fprintf(&body, "if %v {\nreturn %v\n}\n", g.nilChecks(t, f.index), zero)
+		fmt.Fprintf(&body, "return c.%v\n}\n\n", f.path)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by env.WriteGetters. DO NOT EDIT.\n\npackage %v\n\n", packageName(t))
+	src.WriteString(g.importDecl())
+	src.Write(body.Bytes())
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format the generated code: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// codeGenerator keeps track of the state shared by the generators of Go source code.
+type codeGenerator struct {
+	// pkgPath is the import path of the package for which the code is generated.
+	pkgPath string
+	// imports maps the import paths used by the generated code to their package names.
+	imports map[string]string
+	// failure is the format of the statement returning an error of parsing a value, which is given the error
+	// expression. It is "return %v" if empty.
+	failure string
+}
+
+// methodName returns the name of the method generated for a dot-separated field path.
+func (g *codeGenerator) methodName(path string) string {
+	return strings.ReplaceAll(path, ".", "")
+}
+
+// nilChecks returns the expression checking if the receiver c, or any pointer to a struct on the way
+// to the field with the given index, is nil.
+func (g *codeGenerator) nilChecks(t reflect.Type, index []int) string {
+	checks := []string{"c == nil"}
+	expr := "c"
+	for _, x := range index[:len(index)-1] {
+		sf := t.Field(x)
+		expr += "." + sf.Name
+		t = sf.Type
+		if t.Kind() == reflect.Ptr {
+			checks = append(checks, expr+" == nil")
+			t = t.Elem()
+		}
+	}
+	return strings.Join(checks, " || ")
+}
+
+// typeExpr returns the Go expression of a type, recording the imports it requires.
+func (g *codeGenerator) typeExpr(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		if t.PkgPath() == g.pkgPath {
+			return t.Name()
+		}
+		g.imports[t.PkgPath()] = packageName(t)
+		return t.String()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + g.typeExpr(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeExpr(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%v]%v", t.Len(), g.typeExpr(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%v]%v", g.typeExpr(t.Key()), g.typeExpr(t.Elem()))
+	}
+	return t.String()
+}
+
+// importDecl returns the import declaration of the recorded imports.
+func (g *codeGenerator) importDecl() string {
+	if len(g.imports) == 0 {
+		return ""
+	}
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	b.WriteString("import (\n")
+	// standard library packages are listed first, separated from the others
+	for _, std := range []bool{true, false} {
+		group := 0
+		for _, p := range paths {
+			if isStdPackage(p) != std {
+				continue
+			}
+			if group == 0 && !std && b.Len() > len("import (\n") {
+				b.WriteString("\n")
+			}
+			group++
+			if name := g.imports[p]; name != path.Base(p) {
+				fmt.Fprintf(&b, "%v %q\n", name, p)
+			} else {
+				fmt.Fprintf(&b, "%q\n", p)
+			}
+		}
+	}
+	b.WriteString(")\n\n")
+	return b.String()
+}
+
+// isStdPackage returns whether an import path refers to a standard library package.
+func isStdPackage(importPath string) bool {
+	return !strings.Contains(strings.Split(importPath, "/")[0], ".")
+}
+
+// packageName returns the name of the package declaring a named type.
+func packageName(t reflect.Type) string {
+	return strings.TrimSuffix(t.String(), "."+t.Name())
+}
+
+// goLiteral returns the Go literal of a default value of a primary type, parsed as Load would parse it.
+// It returns false if the type is not a primary type or the value cannot be parsed.
+func goLiteral(t reflect.Type, value string) (string, bool) {
+	if t.Kind() == reflect.Ptr || valueFormat(t) == "text" {
+		return "", false
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return strconv.Quote(value), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 0, t.Bits())
+		return strconv.FormatInt(v, 10), err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 0, t.Bits())
+		return strconv.FormatUint(v, 10), err == nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		return strconv.FormatBool(v), err == nil
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, t.Bits())
+		return strconv.FormatFloat(v, 'g', -1, t.Bits()), err == nil
+	}
+	return "", false
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type getterConfig struct {
+	Host     string `default:"localhost"`
+	Port     int    `default:"0x50"`
+	Ratio    float64
+	Timeout  time.Duration
+	Level    myInt `default:"3"`
+	Tags     []string
+	Server   *Embedded `prefix:"SERVER_"`
+	Callback func()
+}
+
+func TestWriteGetters(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteGetters(&buf, &getterConfig{}))
+	assert.Equal(t, `// Code generated by env.WriteGetters. DO NOT EDIT.
+
+package env
+
+import (
+	"time"
+)
+
+// GetHost returns the value of Host, or "localhost" if it is not available.
+func (c *getterConfig) GetHost() (v string) {
+	if c == nil {
+		return "localhost"
+	}
+	return c.Host
+}
+
+// GetPort returns the value of Port, or 80 if it is not available.
+func (c *getterConfig) GetPort() (v int) {
+	if c == nil {
+		return 80
+	}
+	return c.Port
+}
+
+// GetRatio returns the value of Ratio, or the zero value if it is not available.
+func (c *getterConfig) GetRatio() (v float64) {
+	if c == nil {
+		return v
+	}
+	return c.Ratio
+}
+
+// GetTimeout returns the value of Timeout, or the zero value if it is not available.
+func (c *getterConfig) GetTimeout() (v time.Duration) {
+	if c == nil {
+		return v
+	}
+	return c.Timeout
+}
+
+// GetLevel returns the value of Level, or the zero value if it is not available.
+func (c *getterConfig) GetLevel() (v myInt) {
+	if c == nil {
+		return v
+	}
+	return c.Level
+}
+
+// GetTags returns the value of Tags, or the zero value if it is not available.
+func (c *getterConfig) GetTags() (v []string) {
+	if c == nil {
+		return v
+	}
+	return c.Tags
+}
+
+// GetServerURL returns the value of Server.URL, or the zero value if it is not available.
+func (c *getterConfig) GetServerURL() (v string) {
+	if c == nil || c.Server == nil {
+		return v
+	}
+	return c.Server.URL
+}
+
+// GetServerPort returns the value of Server.Port, or the zero value if it is not available.
+func (c *getterConfig) GetServerPort() (v int) {
+	if c == nil || c.Server == nil {
+		return v
+	}
+	return c.Server.Port
+}
+`, buf.String())
+
+	assert.Equal(t, ErrStructPointer, WriteGetters(&buf, getterConfig{}))
+	assert.NotNil(t, WriteGetters(&buf, &struct{ Host string }{}))
+}
+
+func Test_goLiteral(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value interface{}
+		def   string
+		lit   string
+		ok    bool
+	}{
+		{"t1", "", "a\"b", `"a\"b"`, true},
+		{"t2", int8(0), "-0x10", "-16", true},
+		{"t3", uint(0), "x", "", false},
+		{"t4", false, "TRUE", "true", true},
+		{"t5", float32(0), "1e6", "1e+06", true},
+		{"t6", myInt(0), "1", "", false},
+		{"t7", []string{}, "[]", "", false},
+	}
+	for _, test := range tests {
+		lit, ok := goLiteral(reflect.TypeOf(test.value), test.def)
+		assert.Equal(t, test.ok, ok, test.tag)
+		if ok {
+			assert.Equal(t, test.lit, lit, test.tag)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	// Defaulter is implemented by structs that set programmatic defaults, such as maps, slices or nested structs,
+	// which cannot be expressed with "default" tags.
+	Defaulter interface {
+		// Defaults is called before the fields of the struct are populated, so that the values of variables
+		// override the values it sets.
+		Defaults()
+	}
+
+	// AfterLoader is implemented by structs that process their fields after they are populated by Load, for
+	// example to normalize values, derive computed fields or validate fields against each other.
+	AfterLoader interface {
+		// AfterLoad is called after the fields of the struct are populated. An error aborts the loading.
+		AfterLoad() error
+	}
+)
+
+// setDefaults calls the Defaults methods of the nested structs of the struct value v holding the given fields,
+// then that of v itself (see callHooks).
+func setDefaults(v reflect.Value, fields []*fieldInfo) {
+	_ = callHooks(v, fields, func(sv reflect.Value) error {
+		if d, ok := sv.Addr().Interface().(Defaulter); ok {
+			d.Defaults()
+		}
+		return nil
+	})
+}
+
+// afterLoad calls the AfterLoad methods of the nested structs of the struct value v holding the given fields,
+// then that of v itself (see callHooks).
+func afterLoad(v reflect.Value, fields []*fieldInfo) error {
+	return callHooks(v, fields, func(sv reflect.Value) error {
+		if a, ok := sv.Addr().Interface().(AfterLoader); ok {
+			return a.AfterLoad()
+		}
+		return nil
+	})
+}
+
+// callHooks calls a hook with the nested structs of the struct value v holding the given fields, from the most
+// deeply nested ones, then with v itself. Nested structs under nil pointers are skipped, and so are embedded structs,
+// whose methods are promoted to the structs embedding them, and structs reached through unexported fields.
+// Errors of nested structs are prefixed with their paths.
+func callHooks(v reflect.Value, fields []*fieldInfo, hook func(reflect.Value) error) error {
+	var nested [][]int
+	seen := map[string]bool{}
+	for _, f := range fields {
+		for k := 1; k < len(f.index); k++ {
+			if key := fmt.Sprint(f.index[:k]); !seen[key] {
+				seen[key] = true
+				nested = append(nested, f.index[:k])
+			}
+		}
+	}
+	sort.SliceStable(nested, func(i, j int) bool {
+		return len(nested[i]) > len(nested[j])
+	})
+	for _, index := range nested {
+		sv, path, ok := nestedStruct(v, index)
+		if !ok || !sv.Addr().CanInterface() {
+			continue
+		}
+		if err := hook(sv); err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+	}
+	return hook(v)
+}
+
+// nestedStruct returns the nested struct of the struct value v with the given index, along with its path.
+// It returns false if the struct is under a nil pointer or is embedded.
+func nestedStruct(v reflect.Value, index []int) (reflect.Value, string, bool) {
+	var names []string
+	for i, x := range index {
+		sf := v.Type().Field(x)
+		if i == len(index)-1 && sf.Anonymous {
+			return reflect.Value{}, "", false
+		}
+		names = append(names, sf.Name)
+		if v = indirectValue(v.Field(x)); !v.IsValid() {
+			return reflect.Value{}, "", false
+		}
+	}
+	return v, strings.Join(names, "."), true
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hookDB struct {
+	Host string
+	Port int
+	Addr string `env:"-"`
+}
+
+func (db *hookDB) AfterLoad() error {
+	if db.Port == 0 {
+		return errors.New("port is not set")
+	}
+	db.Host = strings.ToLower(db.Host)
+	db.Addr = db.Host + ":" + strings.Repeat("x", db.Port%3)
+	return nil
+}
+
+type hookConfig struct {
+	Name    string
+	DB      hookDB  `prefix:"DB_"`
+	Replica *hookDB `prefix:"REPLICA_"`
+	Backups []hookDB
+	calls   []string
+}
+
+func (c *hookConfig) AfterLoad() error {
+	c.calls = append(c.calls, "config:"+c.DB.Addr)
+	if c.Name == "" {
+		return errors.New("name is not set")
+	}
+	return nil
+}
+
+func TestLoader_Load_afterLoad(t *testing.T) {
+	vars := map[string]string{
+		"APP_NAME":           "app",
+		"APP_DB_HOST":        "DB",
+		"APP_DB_PORT":        "4",
+		"APP_BACKUPS_0_HOST": "B",
+		"APP_BACKUPS_0_PORT": "1",
+	}
+	var cfg hookConfig
+	l := NewWithLookup("APP_", MapLookup(vars), nil, WithOptionalPointers())
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "db", cfg.DB.Host)
+		assert.Equal(t, "db:x", cfg.DB.Addr)
+		assert.Nil(t, cfg.Replica)
+		assert.Equal(t, []hookDB{{Host: "b", Port: 1, Addr: "b:x"}}, cfg.Backups)
+		assert.Equal(t, []string{"config:db:x"}, cfg.calls)
+	}
+
+	cfg = hookConfig{}
+	vars["APP_REPLICA_HOST"] = "r"
+	assert.EqualError(t, l.Load(&cfg), "Replica: port is not set")
+
+	cfg = hookConfig{}
+	delete(vars, "APP_REPLICA_HOST")
+	delete(vars, "APP_NAME")
+	assert.EqualError(t, l.Load(&cfg), "name is not set")
+
+	vars["APP_BACKUPS_0_PORT"] = "0"
+	assert.EqualError(t, l.Load(&cfg), "Backups[0]: port is not set")
+}
+
+type defaultsDB struct {
+	Host    string
+	Options map[string]string
+}
+
+func (db *defaultsDB) Defaults() {
+	db.Host = "localhost"
+	db.Options = map[string]string{"sslmode": "disable"}
+}
+
+type defaultsConfig struct {
+	DB      defaultsDB `prefix:"DB_"`
+	Servers []string
+	Port    int `default:"80"`
+}
+
+func (c *defaultsConfig) Defaults() {
+	c.DB.Host = "db"
+	c.Servers = []string{"a", "b"}
+	c.Port = 8080
+}
+
+func TestLoader_Load_defaults(t *testing.T) {
+	var cfg defaultsConfig
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_DB_OPTIONS": `{"sslmode":"require"}`}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, defaultsConfig{
+			DB:      defaultsDB{Host: "db", Options: map[string]string{"sslmode": "require"}},
+			Servers: []string{"a", "b"},
+			Port:    80,
+		}, cfg)
+	}
+}
+
+func TestLoader_Load_defaultsNilPointer(t *testing.T) {
+	var cfg struct {
+		DB    *defaultsDB `prefix:"DB_"`
+		Cache *defaultsDB `prefix:"CACHE_"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_DB_HOST": "h"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, &defaultsDB{Host: "h", Options: map[string]string{"sslmode": "disable"}}, cfg.DB)
+		assert.Equal(t, &defaultsDB{Host: "localhost", Options: map[string]string{"sslmode": "disable"}}, cfg.Cache)
+	}
+
+	// pointers left nil by WithOptionalPointers are not allocated for their hooks
+	cfg.DB, cfg.Cache = nil, nil
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_DB_HOST": "h"}), nil, WithOptionalPointers())
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, &defaultsDB{Host: "h", Options: map[string]string{"sslmode": "disable"}}, cfg.DB)
+		assert.Nil(t, cfg.Cache)
+	}
+}
+
+func TestLoader_Load_afterLoadEmbedded(t *testing.T) {
+	var cfg struct {
+		hookDB
+		Other string
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "H", "APP_PORT": "2"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "h:xx", cfg.Addr)
+	}
+}
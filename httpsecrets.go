@@ -0,0 +1,185 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+type (
+	// HTTPSecretsConfig configures an HTTP secrets lookup.
+	HTTPSecretsConfig struct {
+		// URL is the URL of the endpoint returning the secrets as a JSON object of key/value pairs.
+		URL string
+		// Token is the bearer token sent in the Authorization header, if any.
+		Token string
+		// Header lists additional headers sent with the request, such as API keys of services that do not use
+		// bearer tokens.
+		Header http.Header
+		// Key maps a variable name to a key in the JSON object. Defaults to using the variable name as is.
+		Key func(name string) string
+		// Client is the HTTP client used to fetch the secrets. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// DopplerConfig configures a Doppler lookup.
+	DopplerConfig struct {
+		// Token is the Doppler service token or personal token. Defaults to $DOPPLER_TOKEN.
+		Token string
+		// Project is the Doppler project. It is not needed with service tokens, which are bound to a config.
+		// Defaults to $DOPPLER_PROJECT.
+		Project string
+		// Config is the Doppler config (e.g. "prd"). It is not needed with service tokens. Defaults to $DOPPLER_CONFIG.
+		Config string
+		// Key maps a variable name to a secret name. Defaults to using the variable name as is.
+		Key func(name string) string
+		// Client is the HTTP client used to talk to Doppler. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// HTTPSecrets looks up values from the secrets returned by an HTTP endpoint, such as the API of a SaaS secret
+	// manager.
+	HTTPSecrets struct {
+		config HTTPSecretsConfig
+		label  string
+		mu     sync.RWMutex
+		data   map[string]string
+	}
+)
+
+// dopplerURL is the URL of the Doppler API endpoint downloading the secrets of a config.
+var dopplerURL = "https://api.doppler.com/v3/configs/config/secrets/download"
+
+// NewHTTPSecrets creates an HTTP secrets lookup. It fetches the secrets with a GET request authenticated with
+// the configured bearer token, so that any configuration or connectivity problem is reported immediately.
+// The response must be a JSON object, whose values that are not strings are used in their JSON encoding.
+//
+// Use the Lookup method as a LookupFunc, for example with WithSecretLookup:
+//
+//	secrets, err := env.NewHTTPSecrets(env.HTTPSecretsConfig{URL: "https://secrets.example.com/myapp", Token: token})
+//	...
+//	loader := env.New("APP_", log.Printf, env.WithSecretLookup(secrets.Lookup))
+func NewHTTPSecrets(config HTTPSecretsConfig) (*HTTPSecrets, error) {
+	return newHTTPSecrets(config, "http secrets")
+}
+
+// NewDoppler creates a lookup of the secrets of a Doppler config, downloaded with the Doppler API. For more details,
+// please refer to NewHTTPSecrets().
+func NewDoppler(config DopplerConfig) (*HTTPSecrets, error) {
+	if config.Token == "" {
+		config.Token = os.Getenv("DOPPLER_TOKEN")
+	}
+	if config.Token == "" {
+		return nil, errors.New("doppler: token is not specified")
+	}
+	if config.Project == "" {
+		config.Project = os.Getenv("DOPPLER_PROJECT")
+	}
+	if config.Config == "" {
+		config.Config = os.Getenv("DOPPLER_CONFIG")
+	}
+	query := url.Values{"format": {"json"}}
+	if config.Project != "" {
+		query.Set("project", config.Project)
+	}
+	if config.Config != "" {
+		query.Set("config", config.Config)
+	}
+	return newHTTPSecrets(HTTPSecretsConfig{
+		URL:    dopplerURL + "?" + query.Encode(),
+		Token:  config.Token,
+		Key:    config.Key,
+		Client: config.Client,
+	}, "doppler")
+}
+
+// newHTTPSecrets creates an HTTP secrets lookup whose errors are prefixed with the given label.
+func newHTTPSecrets(config HTTPSecretsConfig, label string) (*HTTPSecrets, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("%v: URL is not specified", label)
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	s := &HTTPSecrets{config: config, label: label}
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Refresh fetches the secrets again, replacing the values returned by Lookup.
+func (s *HTTPSecrets) Refresh() error {
+	req, err := http.NewRequest(http.MethodGet, s.config.URL, nil)
+	if err != nil {
+		return fmt.Errorf("%v: %w", s.label, err)
+	}
+	for name, values := range s.config.Header {
+		req.Header[name] = values
+	}
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%v: %w", s.label, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%v: GET %v: %v", s.label, req.URL.Redacted(), res.Status)
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%v: %w", s.label, err)
+	}
+	data, err := stringValues(result)
+	if err != nil {
+		return fmt.Errorf("%v: %w", s.label, err)
+	}
+
+	s.mu.Lock()
+	s.data = data
+	s.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the value of the secret corresponding to the given variable name.
+// It can be used as a LookupFunc.
+func (s *HTTPSecrets) Lookup(name string) (string, bool) {
+	if s.config.Key != nil {
+		name = s.config.Key(name)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[name]
+	return value, ok
+}
+
+// stringValues returns the values of a decoded JSON object as strings. Values that are not strings are
+// returned in their JSON encoding.
+func stringValues(values map[string]interface{}) (map[string]string, error) {
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			data[key] = s
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = string(b)
+	}
+	return data, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSecrets_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t1" || r.Header.Get("X-Team") != "core" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"DB_PASSWORD":"s3cret","PORT":8080,"TAGS":["a","b"]}`))
+	}))
+	defer server.Close()
+
+	s, err := NewHTTPSecrets(HTTPSecretsConfig{
+		URL:    server.URL,
+		Token:  "t1",
+		Header: http.Header{"X-Team": {"core"}},
+		Key:    func(name string) string { return strings.TrimPrefix(name, "APP_") },
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	value, ok := s.Lookup("APP_DB_PASSWORD")
+	assert.True(t, ok)
+	assert.Equal(t, "s3cret", value)
+	value, _ = s.Lookup("APP_PORT")
+	assert.Equal(t, "8080", value)
+	value, _ = s.Lookup("APP_TAGS")
+	assert.Equal(t, `["a","b"]`, value)
+	_, ok = s.Lookup("APP_HOST")
+	assert.False(t, ok)
+
+	_, err = NewHTTPSecrets(HTTPSecretsConfig{URL: server.URL, Token: "t2"})
+	assert.EqualError(t, err, "http secrets: GET "+server.URL+": 401 Unauthorized")
+	_, err = NewHTTPSecrets(HTTPSecretsConfig{})
+	assert.EqualError(t, err, "http secrets: URL is not specified")
+}
+
+func TestNewDoppler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer dp.st.x", r.Header.Get("Authorization"))
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		assert.Equal(t, "myapp", r.URL.Query().Get("project"))
+		assert.Equal(t, "prd", r.URL.Query().Get("config"))
+		_, _ = w.Write([]byte(`{"APP_DB_PASSWORD":"s3cret"}`))
+	}))
+	defer server.Close()
+	defer func(u string) { dopplerURL = u }(dopplerURL)
+	dopplerURL = server.URL
+
+	t.Setenv("DOPPLER_TOKEN", "dp.st.x")
+	t.Setenv("DOPPLER_CONFIG", "prd")
+	d, err := NewDoppler(DopplerConfig{Project: "myapp"})
+	if assert.Nil(t, err) {
+		value, ok := d.Lookup("APP_DB_PASSWORD")
+		assert.True(t, ok)
+		assert.Equal(t, "s3cret", value)
+	}
+
+	t.Setenv("DOPPLER_TOKEN", "")
+	_, err = NewDoppler(DopplerConfig{})
+	assert.EqualError(t, err, "doppler: token is not specified")
+}
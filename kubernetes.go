@@ -0,0 +1,216 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// KubernetesConfig configures a Kubernetes lookup.
+	KubernetesConfig struct {
+		// Namespace is the namespace of the ConfigMap and the Secret. Defaults to the namespace of the pod.
+		Namespace string
+		// ConfigMap is the name of the ConfigMap whose data keys are looked up, if any.
+		ConfigMap string
+		// Secret is the name of the Secret whose data keys are looked up, if any. Its keys take precedence over
+		// those of the ConfigMap.
+		Secret string
+		// Key maps a variable name to a data key. Defaults to using the variable name as is.
+		Key func(name string) string
+		// TTL is the duration after which Lookup reads the ConfigMap and the Secret again. Defaults to 0,
+		// which means they are only read again by Refresh.
+		TTL time.Duration
+		// Host is the URL of the API server. Defaults to the in-cluster address given by $KUBERNETES_SERVICE_HOST
+		// and $KUBERNETES_SERVICE_PORT.
+		Host string
+		// TokenPath is the path of the service account token. Defaults to DefaultVaultKubernetesTokenPath.
+		// The token is read before each request, as projected tokens are rotated.
+		TokenPath string
+		// Client is the HTTP client used to talk to the API server. Defaults to a client trusting the certificate
+		// authority of the service account.
+		Client *http.Client
+	}
+
+	// Kubernetes looks up values from the data of a ConfigMap and a Secret read through the Kubernetes API.
+	Kubernetes struct {
+		config  KubernetesConfig
+		mu      sync.RWMutex
+		data    map[string]string
+		updated time.Time
+		errs    lookupErrors
+	}
+)
+
+// NewKubernetes creates a Kubernetes lookup for a pod's in-cluster configuration. It reads the ConfigMap and
+// the Secret, so that any configuration or connectivity problem is reported immediately. The service account of
+// the pod must be allowed to get them.
+//
+// Use the Lookup method as a LookupFunc. With a TTL, the data are read again as they expire, so that a watched
+// struct (see Loader.Watch) picks up changes without restarting the pod:
+//
+//	k8s, err := env.NewKubernetes(env.KubernetesConfig{ConfigMap: "myapp", Secret: "myapp", TTL: time.Minute})
+//	...
+//	loader := env.NewWithLookup("APP_", env.ChainLookup(os.LookupEnv, k8s.Lookup), log.Printf)
+func NewKubernetes(config KubernetesConfig) (*Kubernetes, error) {
+	if config.ConfigMap == "" && config.Secret == "" {
+		return nil, errors.New("kubernetes: neither a ConfigMap nor a Secret is specified")
+	}
+	if config.TokenPath == "" {
+		config.TokenPath = DefaultVaultKubernetesTokenPath
+	}
+	dir := filepath.Dir(config.TokenPath)
+	if config.Namespace == "" {
+		ns, err := os.ReadFile(filepath.Join(dir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: %w", err)
+		}
+		config.Namespace = strings.TrimSpace(string(ns))
+	}
+	if config.Host == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kubernetes: not running in a cluster")
+		}
+		config.Host = "https://" + net.JoinHostPort(host, port)
+	}
+	config.Host = strings.TrimSuffix(config.Host, "/")
+	if config.Client == nil {
+		ca, err := os.ReadFile(filepath.Join(dir, "ca.crt"))
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("kubernetes: invalid CA certificate")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		config.Client = &http.Client{Transport: transport}
+	}
+
+	k := &Kubernetes{config: config}
+	if err := k.Refresh(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Refresh reads the ConfigMap and the Secret again, replacing the values returned by Lookup.
+func (k *Kubernetes) Refresh() error {
+	data := map[string]string{}
+	if k.config.ConfigMap != "" {
+		var cm struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := k.get("configmaps", k.config.ConfigMap, &cm); err != nil {
+			return err
+		}
+		for key, value := range cm.Data {
+			data[key] = value
+		}
+	}
+	if k.config.Secret != "" {
+		var secret struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := k.get("secrets", k.config.Secret, &secret); err != nil {
+			return err
+		}
+		for key, value := range secret.Data {
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("kubernetes: secret %v: key %v: %w", k.config.Secret, key, err)
+			}
+			data[key] = string(b)
+		}
+	}
+
+	k.mu.Lock()
+	k.data = data
+	k.updated = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the value of the data key corresponding to the given variable name, reading the ConfigMap and
+// the Secret again if they are older than the TTL. It can be used as a LookupFunc. If they cannot be read again,
+// the previous values are used, and the failure is reported by Err.
+func (k *Kubernetes) Lookup(name string) (string, bool) {
+	if k.config.TTL > 0 {
+		k.mu.RLock()
+		expired := time.Since(k.updated) > k.config.TTL
+		k.mu.RUnlock()
+		if expired {
+			k.errs.record("", k.Refresh())
+		}
+	}
+	if k.config.Key != nil {
+		name = k.config.Key(name)
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	value, ok := k.data[name]
+	return value, ok
+}
+
+// Err returns the failure of the most recent attempt of Lookup to read the ConfigMap and the Secret again,
+// or nil if it succeeded.
+func (k *Kubernetes) Err() error {
+	return k.errs.err()
+}
+
+// LookupStats returns the number of failed attempts of Lookup to read the ConfigMap and the Secret again, and no
+// cache hits, so that the failures are counted in the statistics of a loader (see WithStatsReporters).
+func (k *Kubernetes) LookupStats() (cacheHits, errors uint64) {
+	return k.errs.stats()
+}
+
+// get reads an object of the given resource type in the namespace and decodes it into result.
+func (k *Kubernetes) get(resource, name string, result interface{}) error {
+	token, err := os.ReadFile(k.config.TokenPath)
+	if err != nil {
+		return fmt.Errorf("kubernetes: %w", err)
+	}
+	path := "/api/v1/namespaces/" + k.config.Namespace + "/" + resource + "/" + name
+	req, err := http.NewRequest(http.MethodGet, k.config.Host+path, nil)
+	if err != nil {
+		return fmt.Errorf("kubernetes: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	res, err := k.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var status struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&status)
+		if status.Message != "" {
+			return fmt.Errorf("kubernetes: GET %v: %v", path, status.Message)
+		}
+		return fmt.Errorf("kubernetes: GET %v: %v", path, res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("kubernetes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetes_Lookup(t *testing.T) {
+	var host atomic.Value
+	host.Store("db")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k8s-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/apps/configmaps/myapp":
+			_, _ = w.Write([]byte(`{"data":{"APP_HOST":"` + host.Load().(string) + `","APP_PASSWORD":"plain"}}`))
+		case "/api/v1/namespaces/apps/secrets/myapp":
+			_, _ = w.Write([]byte(`{"data":{"APP_PASSWORD":"czNjcmV0"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	assert.Nil(t, os.WriteFile(tokenPath, []byte("k8s-token\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "namespace"), []byte("apps"), 0600))
+
+	k, err := NewKubernetes(KubernetesConfig{
+		ConfigMap: "myapp",
+		Secret:    "myapp",
+		Host:      server.URL,
+		TokenPath: tokenPath,
+		TTL:       time.Millisecond,
+		Client:    server.Client(),
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	value, ok := k.Lookup("APP_HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "db", value)
+	value, _ = k.Lookup("APP_PASSWORD")
+	assert.Equal(t, "s3cret", value)
+	_, ok = k.Lookup("APP_PORT")
+	assert.False(t, ok)
+
+	host.Store("db2")
+	time.Sleep(5 * time.Millisecond)
+	value, _ = k.Lookup("APP_HOST")
+	assert.Equal(t, "db2", value)
+	assert.Nil(t, k.Err())
+
+	assert.Nil(t, os.Remove(tokenPath))
+	time.Sleep(5 * time.Millisecond)
+	value, _ = k.Lookup("APP_HOST")
+	assert.Equal(t, "db2", value)
+	assert.NotNil(t, k.Err())
+	_, failures := k.LookupStats()
+	assert.NotZero(t, failures)
+
+	assert.Nil(t, os.WriteFile(tokenPath, []byte("k8s-token"), 0600))
+	_, err = NewKubernetes(KubernetesConfig{ConfigMap: "other", Host: server.URL, TokenPath: tokenPath, Client: server.Client()})
+	assert.EqualError(t, err, "kubernetes: GET /api/v1/namespaces/apps/configmaps/other: not found")
+	_, err = NewKubernetes(KubernetesConfig{})
+	assert.EqualError(t, err, "kubernetes: neither a ConfigMap nor a Secret is specified")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err = NewKubernetes(KubernetesConfig{Secret: "myapp", TokenPath: tokenPath})
+	assert.EqualError(t, err, "kubernetes: not running in a cluster")
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "os"
+
+// Layer makes the loader read variables from the given layers of sources instead of its lookup function and the
+// sources specified before. The sources are listed in order of increasing precedence: a variable found in a source
+// overrides the same variable in the sources before it. A typical order is defaults, files, then the process
+// environment:
+//
+//	loader := env.New("APP_", log.Printf, env.Layer(
+//		env.MapSource("defaults", map[string]string{"APP_PORT": "8080"}),
+//		fileSource, // see FileSource
+//		env.EnvSource(),
+//	))
+//
+// The label of the source supplying each field is reported in FieldReport.Source. Sources without Name functions
+// are also consulted, by precedence, wherever the loader's lookup function is used directly, such as by LoadTree.
+func Layer(sources ...Source) Option {
+	return func(l *Loader) {
+		l.layered = true
+		l.sources = make([]Source, 0, len(sources))
+		for i := len(sources) - 1; i >= 0; i-- {
+			l.sources = append(l.sources, sources[i])
+		}
+		l.lookup = func(name string) (string, bool) {
+			for _, source := range l.sources {
+				if source.Name != nil {
+					continue
+				}
+				if value, ok := source.Lookup(name); ok {
+					return value, true
+				}
+			}
+			return "", false
+		}
+	}
+}
+
+// EnvSource returns a Source labeled "env" that looks up the process environment variables.
+func EnvSource() Source {
+	return Source{Label: "env", Lookup: os.LookupEnv}
+}
+
+// MapSource returns a Source with the given label that looks up the variables in the given map, keyed by their
+// names including the loader prefix.
+func MapSource(label string, vars map[string]string) Source {
+	return Source{Label: label, Lookup: MapLookup(vars)}
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayer(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile, dotenvFile := filepath.Join(dir, "config.yaml"), filepath.Join(dir, "local.env")
+	assert.Nil(t, os.WriteFile(yamlFile, []byte("host: file.example.com\nport: 8081\nnested:\n  url: http://file\n"), 0o600))
+	assert.Nil(t, os.WriteFile(dotenvFile, []byte("APP_PORT=8082\n"), 0o600))
+	yamlSource, err := FileSource(yamlFile)
+	assert.Nil(t, err)
+	dotenvSource, err := FileSource(dotenvFile)
+	assert.Nil(t, err)
+	t.Setenv("APP_HOST", "env.example.com")
+
+	type config struct {
+		Host   string
+		Port   int
+		Mode   string `default:"dev"`
+		Nested struct {
+			URL string
+		} `prefix:"NESTED_"`
+	}
+	var cfg config
+	l := New("APP_", nil, Layer(
+		MapSource("defaults", map[string]string{"APP_PORT": "8080", "APP_NESTED_URL": "http://defaults", "APP_MODE": "prod"}),
+		yamlSource,
+		dotenvSource,
+		EnvSource(),
+	))
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "env.example.com", cfg.Host)
+		assert.Equal(t, 8082, cfg.Port)
+		assert.Equal(t, "prod", cfg.Mode)
+		assert.Equal(t, "http://file", cfg.Nested.URL)
+		var sources []string
+		for _, f := range report.Fields {
+			sources = append(sources, f.Source)
+		}
+		assert.Equal(t, []string{"env", dotenvFile, "defaults", yamlFile}, sources)
+	}
+
+	// the lookup function of the loader is not used, while sources without name functions are
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_MODE": "test"}), nil, Layer(MapSource("map", map[string]string{"APP_PLUGIN_A": "1"})))
+	cfg = config{}
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "dev", cfg.Mode)
+	}
+	value, ok := l.lookup("APP_PLUGIN_A")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+}
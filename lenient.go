@@ -0,0 +1,26 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+// WithLenientErrors makes Load populate every field it can instead of stopping at the first problem, so that
+// applications can start in a degraded mode, or report all the problems of their configuration through their own
+// health checks. A field that cannot be populated, such as one whose variable cannot be parsed or a required one
+// whose variable is not set, keeps the value it had before loading, and its error is recorded in its FieldReport.
+// The errors of unknown variables (see WithStrict) and of post-load hooks are collected likewise.
+//
+// Load then returns all the errors together as a joined error, which can be inspected with errors.Is and errors.As,
+// while LoadReport returns the report along with it:
+//
+//	report, err := loader.LoadReport(&cfg)
+//	for _, f := range report.Fields {
+//		if f.Err != nil {
+//			health.Degrade(f.Var, f.Err)
+//		}
+//	}
+func WithLenientErrors() Option {
+	return func(l *Loader) {
+		l.lenientErrors = true
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLenientErrors(t *testing.T) {
+	type config struct {
+		Host    string
+		Port    int
+		Timeout int    `default:"x"`
+		Token   string `required:"true"`
+		Debug   bool
+	}
+	vars := map[string]string{"APP_HOST": "localhost", "APP_PORT": "abc", "APP_DEBUG": "true"}
+	t.Setenv("APP_EXTRA", "1")
+	l := NewWithLookup("APP_", MapLookup(vars), nil, WithLenientErrors(), WithStrict(true))
+	cfg := config{Port: 80}
+	report, err := l.LoadReport(&cfg)
+	assert.Equal(t, config{Host: "localhost", Port: 80, Debug: true}, cfg)
+	assert.EqualError(t, err, `$APP_PORT: strconv.ParseInt: parsing "abc": invalid syntax
+$APP_TIMEOUT: strconv.ParseInt: parsing "x": invalid syntax
+$APP_TOKEN: required variable is not set
+unknown variables: $APP_EXTRA`)
+	assert.ErrorIs(t, err, ErrParse)
+	assert.ErrorIs(t, err, ErrMissingRequired)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Port", fe.Field)
+	}
+
+	if assert.NotNil(t, report) && assert.Len(t, report.Fields, 5) {
+		assert.Equal(t, FieldReport{Field: "Host", Var: "APP_HOST", Overridden: true, Source: "lookup", Value: "localhost"}, report.Fields[0])
+		assert.Equal(t, FieldReport{Field: "Port", Var: "APP_PORT", Value: "80", Err: report.Fields[1].Err}, report.Fields[1])
+		assert.ErrorIs(t, report.Fields[1].Err, ErrParse)
+		assert.Equal(t, "Timeout", report.Fields[2].Field)
+		assert.False(t, report.Fields[2].DefaultUsed)
+		assert.ErrorIs(t, report.Fields[3].Err, ErrMissingRequired)
+		assert.Nil(t, report.Fields[4].Err)
+	}
+
+	// the errors are not collected by default
+	cfg = config{}
+	report, err = NewWithLookup("APP_", MapLookup(vars), nil).LoadReport(&cfg)
+	assert.Nil(t, report)
+	assert.EqualError(t, err, `$APP_PORT: strconv.ParseInt: parsing "abc": invalid syntax`)
+
+	// a valid configuration has no errors
+	var valid struct{ Port int }
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "80"}), nil, WithLenientErrors())
+	assert.Nil(t, l.Load(&valid))
+	assert.Equal(t, 80, valid.Port)
+}
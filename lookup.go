@@ -0,0 +1,187 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MapLookup returns a LookupFunc that looks up names in the given map.
+func MapLookup(vars map[string]string) LookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+// WithVariableNames specifies a function listing the names of the variables that the loader's lookup function can
+// find. The loader lists names, instead of looking them up one by one, to populate map fields tagged with the "prefix"
+// option, keyed map fields and map targets, to build trees with LoadTree, and to find unknown variables in strict
+// mode. By default, it lists the names of the process environment variables, which suits lookups reading the
+// environment but not those reading a map or a file:
+//
+//	env.NewWithLookup("APP_", env.MapLookup(vars), nil, env.WithVariableNames(env.MapNames(vars)))
+func WithVariableNames(names func() []string) Option {
+	return func(l *Loader) {
+		l.names = names
+	}
+}
+
+// MapNames returns a function listing the names of the variables in the given map, for use with WithVariableNames.
+func MapNames(vars map[string]string) func() []string {
+	return func() []string {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		return names
+	}
+}
+
+// ChainLookup returns a LookupFunc that tries the given lookup functions in order and returns the first value found.
+// Lookups listed earlier take precedence, so a chain like the following lets process environment variables override
+// values from a .env file, which in turn override values stored in Vault:
+//
+//	env.ChainLookup(os.LookupEnv, dotenv, vault.Lookup)
+//
+// Nil lookup functions are skipped.
+func ChainLookup(lookups ...LookupFunc) LookupFunc {
+	return func(name string) (string, bool) {
+		for _, lookup := range lookups {
+			if lookup == nil {
+				continue
+			}
+			if value, ok := lookup(name); ok {
+				return value, true
+			}
+		}
+		return "", false
+	}
+}
+
+// CachedLookup returns a LookupFunc that caches the results of the given lookup function for the given duration,
+// so that repeated Load calls, for example in a reload loop, do not query a remote store every time.
+// Names that are not found are cached as well. Concurrent lookups of the same name that is not cached
+// share a single call to the inner lookup function. Use NewLookupCache instead to count the lookups served
+// from the cache in the statistics of a loader.
+func CachedLookup(inner LookupFunc, ttl time.Duration) LookupFunc {
+	return NewLookupCache(inner, ttl).Lookup
+}
+
+type (
+	// LookupCache caches the results of a lookup function, as described by CachedLookup, and counts the lookups
+	// it serves from the cache.
+	LookupCache struct {
+		inner   LookupFunc
+		ttl     time.Duration
+		mu      sync.Mutex
+		entries map[string]*cacheEntry
+		hits    atomic.Uint64
+	}
+
+	// cacheEntry is the cached result of looking up a name. done is closed once the lookup has completed.
+	cacheEntry struct {
+		done    chan struct{}
+		value   string
+		found   bool
+		expires time.Time
+	}
+)
+
+// NewLookupCache creates a LookupCache that caches the results of the given lookup function for the given duration.
+// Use the Lookup method as a LookupFunc, and report the hits in the statistics of a loader with WithStatsReporters:
+//
+//	cache := env.NewLookupCache(consul.Lookup, time.Minute)
+//	loader := env.NewWithLookup("APP_", cache.Lookup, log.Printf, env.WithStatsReporters(cache, consul))
+func NewLookupCache(inner LookupFunc, ttl time.Duration) *LookupCache {
+	return &LookupCache{inner: inner, ttl: ttl, entries: map[string]*cacheEntry{}}
+}
+
+// Lookup returns the cached value of the given name, looking it up with the inner lookup function if it is not
+// cached or has expired.
+func (c *LookupCache) Lookup(name string) (string, bool) {
+	c.mu.Lock()
+	e := c.entries[name]
+	if e != nil {
+		select {
+		case <-e.done:
+			if time.Now().After(e.expires) {
+				e = nil
+			}
+		default:
+			// another lookup of the name is in progress
+		}
+	}
+	if e != nil {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		<-e.done
+		return e.value, e.found
+	}
+	e = &cacheEntry{done: make(chan struct{})}
+	c.entries[name] = e
+	c.mu.Unlock()
+
+	defer close(e.done)
+	e.value, e.found = c.inner(name)
+	e.expires = time.Now().Add(c.ttl)
+	return e.value, e.found
+}
+
+// LookupStats returns the number of lookups served from the cache, including those that waited for a concurrent
+// lookup of the same name. A cache reports no errors.
+func (c *LookupCache) LookupStats() (cacheHits, errors uint64) {
+	return c.hits.Load(), 0
+}
+
+// lookupErrors records the failures of the most recent lookups of each name.
+type lookupErrors struct {
+	mu   sync.Mutex
+	errs map[string]error
+	// count is the total number of failures recorded.
+	count uint64
+}
+
+// record records the result of looking up name. A nil err clears any previous failure of the name.
+func (e *lookupErrors) record(name string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		delete(e.errs, name)
+		return
+	}
+	if e.errs == nil {
+		e.errs = map[string]error{}
+	}
+	e.errs[name] = err
+	e.count++
+}
+
+// stats returns the total number of failures recorded, as reported by the LookupStats method of the lookups.
+func (e *lookupErrors) stats() (cacheHits, errors uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return 0, e.count
+}
+
+// err returns the recorded failures joined in the order of the names, or nil if there is none.
+func (e *lookupErrors) err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, 0, len(e.errs))
+	for name := range e.errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := make([]error, len(names))
+	for i, name := range names {
+		errs[i] = e.errs[name]
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapLookup(t *testing.T) {
+	lookup := MapLookup(map[string]string{"A": "1", "B": ""})
+	value, ok := lookup("A")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+	_, ok = lookup("B")
+	assert.True(t, ok)
+	_, ok = lookup("C")
+	assert.False(t, ok)
+}
+
+func TestWithVariableNames(t *testing.T) {
+	t.Setenv("APP_FEATURE_ENV", "on")
+	vars := map[string]string{"APP_FEATURE_SEARCH": "on", "APP_PORT": "80"}
+	var cfg struct {
+		Features map[string]string `env:",prefix=FEATURE_"`
+	}
+	l := NewWithLookup("APP_", MapLookup(vars), nil, WithVariableNames(MapNames(vars)), WithStrict(true))
+	assert.EqualError(t, l.Load(&cfg), "unknown variables: $APP_PORT")
+	assert.Equal(t, map[string]string{"SEARCH": "on"}, cfg.Features)
+
+	names := MapNames(vars)()
+	sort.Strings(names)
+	assert.Equal(t, []string{"APP_FEATURE_SEARCH", "APP_PORT"}, names)
+}
+
+func TestChainLookup(t *testing.T) {
+	first := MapLookup(map[string]string{"A": "1", "B": ""})
+	second := MapLookup(map[string]string{"A": "2", "B": "2", "C": "2"})
+	lookup := ChainLookup(first, nil, second)
+
+	tests := []struct {
+		tag   string
+		name  string
+		value string
+		found bool
+	}{
+		{"t1", "A", "1", true},
+		{"t2", "B", "", true},
+		{"t3", "C", "2", true},
+		{"t4", "D", "", false},
+	}
+	for _, test := range tests {
+		value, ok := lookup(test.name)
+		assert.Equal(t, test.found, ok, test.tag)
+		assert.Equal(t, test.value, value, test.tag)
+	}
+
+	_, ok := ChainLookup()("A")
+	assert.False(t, ok)
+
+	var cfg Config1
+	l := NewWithLookup("", ChainLookup(MapLookup(map[string]string{"HOST": "example.com"}), mockLookup), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "example.com", cfg.Host)
+		assert.Equal(t, 8080, cfg.Port)
+	}
+}
+
+func TestCachedLookup(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	inner := func(name string) (string, bool) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return mockLookup(name)
+	}
+	lookup := CachedLookup(inner, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, ok := lookup("HOST")
+			assert.True(t, ok)
+			assert.Equal(t, "localhost", value)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, ok := lookup("MISSING")
+	assert.False(t, ok)
+	_, ok = lookup("MISSING")
+	assert.False(t, ok)
+	value, _ := lookup("HOST")
+	assert.Equal(t, "localhost", value)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	time.Sleep(60 * time.Millisecond)
+	value, _ = lookup("HOST")
+	assert.Equal(t, "localhost", value)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLookupCache(t *testing.T) {
+	cache := NewLookupCache(MapLookup(map[string]string{"HOST": "localhost"}), time.Minute)
+	hits, failures := cache.LookupStats()
+	assert.Equal(t, uint64(0), hits)
+	assert.Equal(t, uint64(0), failures)
+
+	value, ok := cache.Lookup("HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost", value)
+	_, ok = cache.Lookup("PORT")
+	assert.False(t, ok)
+	value, _ = cache.Lookup("HOST")
+	assert.Equal(t, "localhost", value)
+	_, ok = cache.Lookup("PORT")
+	assert.False(t, ok)
+	hits, _ = cache.LookupStats()
+	assert.Equal(t, uint64(2), hits)
+}
+
+func Test_lookupErrors(t *testing.T) {
+	var errs lookupErrors
+	assert.Nil(t, errs.err())
+	errs.record("B", errors.New("b"))
+	errs.record("A", errors.New("a"))
+	errs.record("C", errors.New("c"))
+	errs.record("C", nil)
+	assert.EqualError(t, errs.err(), "a\nb")
+	_, count := errs.stats()
+	assert.Equal(t, uint64(3), count)
+}
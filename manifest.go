@@ -0,0 +1,378 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+type (
+	// Manifest describes the variables read by a configuration struct. A manifest can be exported to JSON
+	// and used to validate a set of variables without having the Go struct available.
+	Manifest struct {
+		// Vars lists the variables in the order of the corresponding struct fields.
+		Vars []ManifestVar `json:"vars"`
+	}
+
+	// ManifestVar describes a variable in a manifest.
+	ManifestVar struct {
+		// Name is the variable name, including the loader prefix. For a map field tagged with the "prefix"
+		// option, it is the prefix of the variables followed by "*" (e.g. "APP_FEATURE_*").
+		Name string `json:"name"`
+		// Aliases lists the alternative names of the variable, including the loader prefix (see the "alias" tag
+		// option). They are used when the variable is not set.
+		Aliases []string `json:"aliases,omitempty"`
+		// Deprecated lists the former names of the variable, including the loader prefix (see the "deprecated"
+		// tag). They are used when the variable and its aliases are not set.
+		Deprecated []string `json:"deprecated,omitempty"`
+		// Field is the dot-separated path of the struct field populated by the variable.
+		Field string `json:"field"`
+		// Type is the format used to parse the variable value. It is one of the primary Go type names
+		// (e.g. "string", "int64", "bool", "float64"), "bytes", "array" (an array whose elements are described by
+		// Elem), "json" (a JSON-encoded value), "query" (a query
+		// string parsed into url.Values), "url" (a URL parsed into url.URL), "ip" (an IP address), "cidr" (a CIDR
+		// block), "regexp" (a regular expression), "bigint", "bigfloat" or "bigrat" (numbers parsed into big.Int,
+		// big.Float or big.Rat), "base64" or "hex" (encoded bytes), "bytesize" (a byte size such as "10MiB"), "enum"
+		// (one of the names listed by Enum), or "text" (a value parsed by a custom Setter or unmarshaler, which cannot
+		// be validated).
+		Type string `json:"type"`
+		// Enum lists the names accepted by a variable of the "enum" type, from the "enum" tag of the field.
+		Enum []string `json:"enum,omitempty"`
+		// Elem is the format of the elements of a variable of the "array" type, which are given as a JSON array or
+		// as a comma-separated list.
+		Elem string `json:"elem,omitempty"`
+		// Length is the number of elements of an array field, which the value of the variable must have. For a byte
+		// array, it is the number of bytes, after decoding for the "base64" and "hex" types.
+		Length int `json:"length,omitempty"`
+		// Secret indicates whether the variable holds sensitive information.
+		Secret bool `json:"secret,omitempty"`
+		// Default is the value used when the variable is not set, as specified by the "default" tag.
+		Default string `json:"default,omitempty"`
+		// Required indicates whether the variable must be set when it has no default.
+		Required bool `json:"required,omitempty"`
+		// Description describes the variable, as specified by the "desc" tag.
+		Description string `json:"description,omitempty"`
+	}
+)
+
+var (
+	setterType            = reflect.TypeOf((*Setter)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	urlValuesType         = reflect.TypeOf(url.Values{})
+	urlType               = reflect.TypeOf(url.URL{})
+	ipType                = reflect.TypeOf(net.IP{})
+	ipNetType             = reflect.TypeOf(net.IPNet{})
+	addrType              = reflect.TypeOf(netip.Addr{})
+	prefixType            = reflect.TypeOf(netip.Prefix{})
+	regexpType            = reflect.TypeOf(regexp.Regexp{})
+	bigIntType            = reflect.TypeOf(big.Int{})
+	bigFloatType          = reflect.TypeOf(big.Float{})
+	bigRatType            = reflect.TypeOf(big.Rat{})
+	rawMessageType        = reflect.TypeOf(json.RawMessage{})
+
+	// valueStructs lists the struct types that are parsed from a single value rather than loaded as nested structs.
+	valueStructs = map[reflect.Type]bool{urlType: true, ipNetType: true, addrType: true, prefixType: true, regexpType: true}
+
+	// manifestTypes maps the primary types that can be validated by parsing to their reflection types.
+	manifestTypes = map[string]reflect.Type{}
+)
+
+func init() {
+	for _, v := range []interface{}{
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), false,
+	} {
+		t := reflect.TypeOf(v)
+		manifestTypes[t.Name()] = t
+	}
+}
+
+// ExportManifest returns the manifest of the variables read by the package-level Load function for the given struct.
+func ExportManifest(structPtr interface{}) (*Manifest, error) {
+	return loader.ExportManifest(structPtr)
+}
+
+// ExportManifest returns the manifest of the variables read by Load for the given struct.
+// The struct must be specified as a pointer. Fields that are not loaded in the loader's environment
+// (see WithEnvironment) are excluded.
+func (l *Loader) ExportManifest(structPtr interface{}) (*Manifest, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+
+	fields, err := l.getFields(value.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{Vars: []ManifestVar{}}
+	for _, f := range fields {
+		if !l.active(f) {
+			continue
+		}
+		v := ManifestVar{
+			Name:        l.varName(f),
+			Aliases:     l.prefixNames(f.aliases),
+			Deprecated:  l.prefixNames(f.deprecated),
+			Field:       f.path,
+			Type:        l.manifestType(f),
+			Enum:        f.enumNames(),
+			Secret:      f.secret,
+			Default:     f.def,
+			Required:    f.required && !f.hasDefault,
+			Description: f.desc,
+		}
+		if t := indirectType(f.typ); t.Kind() == reflect.Array && v.Type != "text" && v.Type != "enum" {
+			v.Length = t.Len()
+			if v.Type == "array" {
+				v.Elem = valueFormat(t.Elem())
+			}
+		}
+		m.Vars = append(m.Vars, v)
+	}
+	return m, nil
+}
+
+// ReadManifest reads a JSON-encoded manifest, such as the one produced by encoding the result of ExportManifest.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks if the given variables are valid according to the manifest.
+// Each variable described by the manifest that is present in vars must be parsable according to its type,
+// and each required variable must be present.
+// All problems found are returned together as a joined error.
+func (m *Manifest) Validate(vars map[string]string) error {
+	var errs []error
+	for _, v := range m.Vars {
+		if prefix, ok := strings.CutSuffix(v.Name, "*"); ok {
+			if v.Required && !hasPrefixedVar(vars, prefix) {
+				errs = append(errs, fmt.Errorf("$%v: required variables are not set", v.Name))
+			}
+			continue
+		}
+		value, ok := vars[v.Name]
+		for _, alias := range append(append([]string{}, v.Aliases...), v.Deprecated...) {
+			if ok {
+				break
+			}
+			value, ok = vars[alias]
+		}
+		if !ok {
+			if v.Required {
+				errs = append(errs, fmt.Errorf("$%v: required variable is not set", v.Name))
+			}
+			continue
+		}
+		if v.Length > 0 {
+			if err := validateArray(v.Type, v.Elem, v.Length, value); err != nil {
+				errs = append(errs, fmt.Errorf("$%v: %w", v.Name, err))
+			}
+			continue
+		}
+		if v.Type == "enum" {
+			if !containsFold(v.Enum, value) {
+				errs = append(errs, fmt.Errorf("$%v: invalid value %q: must be one of %v", v.Name, value, strings.Join(v.Enum, ", ")))
+			}
+			continue
+		}
+		if err := validateValue(v.Type, value); err != nil {
+			errs = append(errs, fmt.Errorf("$%v: %w", v.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasPrefixedVar returns whether vars contains a variable whose name starts with the given prefix and is longer.
+func hasPrefixedVar(vars map[string]string, prefix string) bool {
+	for name := range vars {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile checks if the variables defined in a .env file are valid according to the manifest.
+func (m *Manifest) ValidateFile(file string) error {
+	vars, err := readDotenvFile(file)
+	if err != nil {
+		return err
+	}
+	return m.Validate(vars)
+}
+
+// manifestType returns the type of the variable of a field in a manifest. Fields parsed by a named parser or
+// a parser registered for their types (see WithNamedParser and RegisterParser) are of the "text" type.
+func (l *Loader) manifestType(f *fieldInfo) string {
+	if len(f.enum) > 0 {
+		return "enum"
+	}
+	if f.encoding == "bytes" {
+		return "bytesize"
+	}
+	if f.encoding != "" {
+		return f.encoding
+	}
+	if p, _ := l.parser(f.typ); p != nil || f.parser != "" {
+		return "text"
+	}
+	return valueFormat(f.typ)
+}
+
+// valueFormat returns the format used by setValue to parse a string into a value of the given type.
+func valueFormat(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case urlType:
+		return "url"
+	case ipType, addrType:
+		return "ip"
+	case ipNetType, prefixType:
+		return "cidr"
+	case rawMessageType:
+		return "json"
+	case regexpType:
+		return "regexp"
+	case bigIntType:
+		return "bigint"
+	case bigFloatType:
+		return "bigfloat"
+	case bigRatType:
+		return "bigrat"
+	}
+	pt := reflect.PtrTo(t)
+	if pt.Implements(setterType) || pt.Implements(textUnmarshalerType) || pt.Implements(binaryUnmarshalerType) {
+		return "text"
+	}
+	if t == urlValuesType {
+		return "query"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Bool, reflect.Float32, reflect.Float64:
+		return t.Kind().String()
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "array"
+	}
+	return "json"
+}
+
+// validateArray checks if a string value can be parsed into an array of the given length, whose elements have the
+// given format for the "array" type. Byte arrays are checked like setArray does, after decoding for encoded types.
+func validateArray(format, elem string, n int, value string) error {
+	switch format {
+	case "bytes":
+		return setArray(reflect.New(reflect.ArrayOf(n, reflect.TypeOf(byte(0)))).Elem(), value)
+	case "base64", "hex":
+		b, err := decodeValue(format, value)
+		if err == nil && len(b) != n {
+			err = fmt.Errorf("the value has %v bytes, but the array holds %v", len(b), n)
+		}
+		return err
+	}
+	if format != "array" {
+		return validateValue(format, value)
+	}
+
+	var items []string
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(value), &raw); err != nil {
+			return err
+		}
+		for _, item := range raw {
+			// JSON strings are validated by their content, other elements and JSON values by their JSON text
+			var s string
+			if elem == "json" || json.Unmarshal(item, &s) != nil {
+				s = string(item)
+			}
+			items = append(items, s)
+		}
+	} else if strings.TrimSpace(value) != "" {
+		for _, item := range strings.Split(value, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+	if len(items) != n {
+		return fmt.Errorf("the value has %v elements, but the array holds %v", len(items), n)
+	}
+	for i, item := range items {
+		if err := validateValue(elem, item); err != nil {
+			return fmt.Errorf("element %v: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateValue checks if a string value can be parsed according to the given format.
+func validateValue(format, value string) error {
+	switch format {
+	case "string", "bytes", "text":
+		return nil
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return errors.New("invalid JSON value")
+		}
+		return nil
+	case "query":
+		_, err := url.ParseQuery(value)
+		return err
+	case "url":
+		return setValue(reflect.New(urlType), value)
+	case "ip":
+		return setValue(reflect.New(ipType), value)
+	case "cidr":
+		return setValue(reflect.New(ipNetType), value)
+	case "regexp":
+		return setValue(reflect.New(regexpType), value)
+	case "bigint":
+		return setValue(reflect.New(bigIntType), value)
+	case "bigfloat":
+		return setValue(reflect.New(bigFloatType), value)
+	case "bigrat":
+		return setValue(reflect.New(bigRatType), value)
+	case "base64", "hex":
+		_, err := decodeValue(format, value)
+		return err
+	case "bytesize":
+		_, err := parseByteSize(value)
+		return err
+	}
+	t, ok := manifestTypes[format]
+	if !ok {
+		return fmt.Errorf("unknown type %q", format)
+	}
+	return setValue(reflect.New(t), value)
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type manifestConfig struct {
+	Host    string
+	Port    int    `default:"80"`
+	Debug   bool   `env:"DEBUG_MODE"`
+	Key     []byte `env:",secret"`
+	Tags    []string
+	Level   myInt
+	Params  url.Values
+	Proxy   *url.URL
+	Bind    netip.Addr
+	Allowed *net.IPNet
+	Match   *regexp.Regexp
+	Supply  *big.Int
+	Nested  *Embedded `prefix:"NESTED_"`
+	Ignored string    `env:"-"`
+}
+
+func TestLoader_ExportManifest(t *testing.T) {
+	l := NewWithLookup("APP_", mockLookup, nil)
+	m, err := l.ExportManifest(&manifestConfig{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []ManifestVar{
+			{Name: "APP_HOST", Field: "Host", Type: "string"},
+			{Name: "APP_PORT", Field: "Port", Type: "int", Default: "80"},
+			{Name: "APP_DEBUG_MODE", Field: "Debug", Type: "bool"},
+			{Name: "APP_KEY", Field: "Key", Type: "bytes", Secret: true},
+			{Name: "APP_TAGS", Field: "Tags", Type: "json"},
+			{Name: "APP_LEVEL", Field: "Level", Type: "text"},
+			{Name: "APP_PARAMS", Field: "Params", Type: "query"},
+			{Name: "APP_PROXY", Field: "Proxy", Type: "url"},
+			{Name: "APP_BIND", Field: "Bind", Type: "ip"},
+			{Name: "APP_ALLOWED", Field: "Allowed", Type: "cidr"},
+			{Name: "APP_MATCH", Field: "Match", Type: "regexp"},
+			{Name: "APP_SUPPLY", Field: "Supply", Type: "bigint"},
+			{Name: "APP_NESTED_URL", Field: "Nested.URL", Type: "string"},
+			{Name: "APP_NESTED_PORT", Field: "Nested.Port", Type: "int"},
+		}, m.Vars)
+	}
+
+	_, err = l.ExportManifest(manifestConfig{})
+	assert.Equal(t, ErrStructPointer, err)
+
+	m, err = ExportManifest(&Config1{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "APP_HOST", m.Vars[0].Name)
+	}
+
+	type envsConfig struct {
+		Host string `required:"true" desc:"The host name."`
+		Port int    `required:"true" default:"80"`
+		URL  string `envs:"development"`
+	}
+	m, err = NewWithLookup("APP_", mockLookup, nil, WithEnvironment("production")).ExportManifest(&envsConfig{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []ManifestVar{
+			{Name: "APP_HOST", Field: "Host", Type: "string", Required: true, Description: "The host name."},
+			{Name: "APP_PORT", Field: "Port", Type: "int", Default: "80"},
+		}, m.Vars)
+	}
+}
+
+func TestManifest_Validate(t *testing.T) {
+	m, _ := NewWithLookup("APP_", mockLookup, nil).ExportManifest(&manifestConfig{})
+	var buf bytes.Buffer
+	assert.Nil(t, json.NewEncoder(&buf).Encode(m))
+	m, err := ReadManifest(&buf)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Nil(t, m.Validate(map[string]string{
+		"APP_HOST":        "localhost",
+		"APP_PORT":        "8080",
+		"APP_DEBUG_MODE":  "true",
+		"APP_KEY":         "abc",
+		"APP_TAGS":        `["a","b"]`,
+		"APP_LEVEL":       "anything",
+		"APP_PARAMS":      "sslmode=disable&timeout=5",
+		"APP_NESTED_PORT": "80",
+		"APP_OTHER":       "x",
+	}))
+
+	err = m.Validate(map[string]string{
+		"APP_PORT":        "80a",
+		"APP_DEBUG_MODE":  "yes",
+		"APP_TAGS":        `["a",`,
+		"APP_PARAMS":      "a=%zz",
+		"APP_NESTED_PORT": "x",
+	})
+	if assert.NotNil(t, err) {
+		msg := err.Error()
+		assert.Contains(t, msg, `$APP_PORT: strconv.ParseInt: parsing "80a": invalid syntax`)
+		assert.Contains(t, msg, `$APP_DEBUG_MODE: strconv.ParseBool: parsing "yes": invalid syntax`)
+		assert.Contains(t, msg, `$APP_TAGS: invalid JSON value`)
+		assert.Contains(t, msg, `$APP_PARAMS: invalid URL escape "%zz"`)
+		assert.Contains(t, msg, `$APP_NESTED_PORT: strconv.ParseInt: parsing "x": invalid syntax`)
+	}
+
+	m = &Manifest{Vars: []ManifestVar{{Name: "A", Type: "string", Required: true}}}
+	assert.EqualError(t, m.Validate(map[string]string{}), "$A: required variable is not set")
+
+	m = &Manifest{Vars: []ManifestVar{{Name: "A", Type: "complex64"}}}
+	assert.EqualError(t, m.Validate(map[string]string{"A": "1"}), `$A: unknown type "complex64"`)
+
+	_, err = ReadManifest(strings.NewReader("{"))
+	assert.NotNil(t, err)
+}
+
+func TestManifest_ValidateFile(t *testing.T) {
+	m, _ := NewWithLookup("APP_", mockLookup, nil).ExportManifest(&manifestConfig{})
+	file := filepath.Join(t.TempDir(), ".env")
+	assert.Nil(t, os.WriteFile(file, []byte("APP_HOST=localhost\nAPP_PORT=80\n"), 0600))
+	assert.Nil(t, m.ValidateFile(file))
+
+	assert.Nil(t, os.WriteFile(file, []byte("APP_PORT=abc\n"), 0600))
+	assert.NotNil(t, m.ValidateFile(file))
+
+	assert.NotNil(t, m.ValidateFile(file+"x"))
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isMapTarget returns whether a value passed to Load is a non-nil pointer to a map with string keys and string or
+// interface{} values.
+func isMapTarget(v reflect.Value) bool {
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Map {
+		return false
+	}
+	t := v.Elem().Type()
+	return t.Key().Kind() == reflect.String && (t.Elem().Kind() == reflect.String || t.Elem() == interfaceType)
+}
+
+// interfaceType is the type of interface{} values.
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// loadMap fills a map target of Load with every variable under the loader prefix, allocating the map if it is nil.
+// The returned report lists a field per variable, named after its key.
+func (l *Loader) loadMap(m reflect.Value) (*Report, error) {
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	report := &Report{}
+	for _, name := range l.collectNames(l.prefix) {
+		value, ok := l.lookup(name)
+		if !ok {
+			continue
+		}
+		value, err := l.transform(&fieldInfo{}, value)
+		if err != nil {
+			return nil, fmt.Errorf("$%v: %w", name, err)
+		}
+		key := strings.TrimPrefix(name, l.prefix)
+		v := reflect.ValueOf(value).Convert(m.Type().Elem())
+		if m.Type().Elem() == interfaceType {
+			v = reflect.ValueOf(treeValue(value))
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(m.Type().Key()), v)
+		if l.log != nil && l.logMode == LogFields {
+			l.log("set %v with $%v", key, name)
+		}
+		report.Fields = append(report.Fields, FieldReport{Field: key, Var: name, Overridden: true, Source: l.lookupLabel, Value: value})
+	}
+	return report, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Load_map(t *testing.T) {
+	t.Setenv("PLUGIN_DIR", "/opt/plugins")
+	t.Setenv("PLUGIN_WORKERS", "4")
+	t.Setenv("PLUGIN_DEBUG", "true")
+	l := New("PLUGIN_", nil)
+
+	vars := map[string]string{"OTHER": "x"}
+	report, err := l.LoadReport(&vars)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"DIR": "/opt/plugins", "WORKERS": "4", "DEBUG": "true", "OTHER": "x"}, vars)
+		assert.Equal(t, []string{"DEBUG", "DIR", "WORKERS"}, report.Overridden())
+	}
+
+	var values map[string]interface{}
+	if assert.Nil(t, l.Load(&values)) {
+		assert.Equal(t, map[string]interface{}{"DIR": "/opt/plugins", "WORKERS": int64(4), "DEBUG": true}, values)
+	}
+
+	type names map[string]string
+	var named names
+	l = New("PLUGIN_", nil, WithTransformers(func(value string) (string, error) {
+		return "<" + value + ">", nil
+	}))
+	if assert.Nil(t, l.Load(&named)) {
+		assert.Equal(t, "</opt/plugins>", named["DIR"])
+	}
+
+	assert.Equal(t, ErrStructPointer, l.Load(&map[string]int{}))
+	assert.Equal(t, ErrStructPointer, l.Load(map[string]string{}))
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalMasked returns the JSON document of a struct with its secret fields masked using the package-level loader.
+// For more details, please refer to Loader.MarshalMasked().
+func MarshalMasked(structPtr interface{}) ([]byte, error) {
+	return loader.MarshalMasked(structPtr)
+}
+
+// MarshalMaskedYAML returns the YAML document of a struct with its secret fields masked using the package-level
+// loader. For more details, please refer to Loader.MarshalMaskedYAML().
+func MarshalMaskedYAML(structPtr interface{}) ([]byte, error) {
+	return loader.MarshalMaskedYAML(structPtr)
+}
+
+// MarshalMasked returns an indented JSON document of the current values of a struct, typically once it is loaded,
+// with the values of fields tagged as secret masked as specified with WithRedactor. It is meant for debug endpoints
+// such as /debug/config, and for startup dumps that are safe to log. The struct must be specified as a pointer.
+//
+// The document is an object whose keys are the names of the struct fields, with an object per nested struct.
+// Booleans and numbers are JSON booleans and numbers, fields whose variables are JSON-encoded, such as slices and
+// maps, are JSON values, and other fields are strings formatted like the values of their variables (see Dump).
+// Fields with nil pointer values are null. Fields of unsupported kinds, and fields that are not loaded in
+// the loader's environment, are omitted.
+func (l *Loader) MarshalMasked(structPtr interface{}) ([]byte, error) {
+	tree, err := l.maskedTree(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(tree, "", "  ")
+}
+
+// MarshalMaskedYAML is like MarshalMasked, but returns a YAML document.
+func (l *Loader) MarshalMaskedYAML(structPtr interface{}) ([]byte, error) {
+	data, err := l.MarshalMasked(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, so the document is converted by re-encoding its nodes in block style,
+	// which keeps the literals of numbers as they are
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	clearStyle(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// clearStyle resets the style of a YAML node and its descendants, so that they are encoded in the default style.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// maskedTree returns the values of the fields of a struct with the secret ones masked, as nested maps keyed by
+// field names (see MarshalMasked).
+func (l *Loader) maskedTree(structPtr interface{}) (map[string]interface{}, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+	fields, err := l.getFields(value.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]interface{}{}
+	for _, f := range fields {
+		if !l.active(f) || !supportedKind(elemKind(f.typ)) {
+			continue
+		}
+		v, err := l.maskedValue(f, indirectValue(reflect.ValueOf(fieldValue(value.Elem(), f.index))))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.path, err)
+		}
+		node := tree
+		names := strings.Split(f.path, ".")
+		for _, name := range names[:len(names)-1] {
+			child, ok := node[name].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[name] = child
+			}
+			node = child
+		}
+		node[names[len(names)-1]] = v
+	}
+	return tree, nil
+}
+
+// maskedValue returns the value of a field for MarshalMasked, given the value it points to, if any.
+func (l *Loader) maskedValue(f *fieldInfo, v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	s, err := formatVar(v)
+	if err != nil {
+		return nil, err
+	}
+	s = f.encode(s)
+	if f.secret {
+		return l.redact(l.varName(f), s), nil
+	}
+	if f.encoding != "" || len(f.enum) > 0 {
+		return s, nil
+	}
+	switch valueFormat(v.Type()) {
+	case "bool", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return v.Interface(), nil
+	case "json", "array":
+		return json.RawMessage(s), nil
+	}
+	return s, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type maskedConfig struct {
+	Name     string
+	Port     int
+	Debug    bool
+	Password string `env:",secret"`
+	Key      []byte `env:",base64"`
+	Tags     []string
+	Level    *myInt
+	Code     string
+	Big      uint64
+	Nested   Embedded `prefix:"NESTED_"`
+	Ignored  string   `env:"-"`
+}
+
+func TestLoader_MarshalMasked(t *testing.T) {
+	cfg := maskedConfig{
+		Name:     "app",
+		Port:     8080,
+		Debug:    true,
+		Password: "s3cret",
+		Key:      []byte("abc"),
+		Tags:     []string{"a", "b"},
+		Code:     "0123",
+		Big:      18446744073709551615,
+		Nested:   Embedded{URL: "http://x", Port: 1},
+	}
+	l := NewWithLookup("APP_", nil, nil)
+	data, err := l.MarshalMasked(&cfg)
+	if assert.Nil(t, err) {
+		assert.JSONEq(t, `{
+			"Name": "app",
+			"Port": 8080,
+			"Debug": true,
+			"Password": "***",
+			"Key": "YWJj",
+			"Tags": ["a", "b"],
+			"Level": null,
+			"Code": "0123",
+			"Big": 18446744073709551615,
+			"Nested": {"URL": "http://x", "Port": 1}
+		}`, string(data))
+	}
+
+	data, err = MarshalMaskedYAML(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, `Big: 18446744073709551615
+Code: "0123"
+Debug: true
+Key: YWJj
+Level: null
+Name: app
+Nested:
+    Port: 1
+    URL: http://x
+Password: '***'
+Port: 8080
+Tags:
+    - a
+    - b
+`, string(data))
+	}
+
+	_, err = MarshalMasked(cfg)
+	assert.Equal(t, ErrStructPointer, err)
+	_, err = l.MarshalMaskedYAML(cfg)
+	assert.Equal(t, ErrStructPointer, err)
+}
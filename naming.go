@@ -0,0 +1,96 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// NameMapper generates the name of the variable of a struct field that has no name in its tag. The name is
+// appended to the loader prefix and the "prefix" tags of the enclosing structs.
+//
+// fieldPath lists the name of the field, preceded by the names of the enclosing nested struct fields up to the
+// nearest one with a non-empty prefix (e.g. ["Database", "MaxConn"] for the MaxConn field of an untagged nested
+// struct field named Database). Embedded struct fields are not listed.
+type NameMapper func(fieldPath []string) string
+
+// UpperSnakeCase is the default NameMapper. It converts the name of the field from camelCase into UPPER_SNAKE_CASE
+// (e.g. MaxConn into MAX_CONN), ignoring the names of the enclosing structs.
+func UpperSnakeCase(fieldPath []string) string {
+	return camelCaseToUpperSnakeCase(fieldPath[len(fieldPath)-1])
+}
+
+// WithNameMapper specifies how the names of variables are generated from the names of struct fields, for example
+// to read kebab-case or dot-separated names from a configuration source. Names given in field tags are used as is.
+//
+// The code generated by WriteLoadFunc does not use the name mapper.
+func WithNameMapper(m NameMapper) Option {
+	return func(l *Loader) {
+		l.nameMapper = &m
+		l.plans = new(sync.Map)
+	}
+}
+
+// WithLegacyNaming makes the loader convert field names into UPPER_SNAKE_CASE with the rules of earlier versions,
+// which do not recognize acronyms (e.g. APIKey becomes APIKEY instead of API_KEY, and MyURLName becomes MY_URLNAME
+// instead of MY_URL_NAME). It helps migrate deployments that rely on the old names. It has no effect on the names
+// generated by a name mapper (see WithNameMapper).
+func WithLegacyNaming() Option {
+	return func(l *Loader) {
+		l.legacyNaming = true
+	}
+}
+
+// WithTagFallback makes the loader take the names of variables from the given tags, in order, for struct fields
+// without a name in their "env" tags. This allows loading structs already annotated for other purposes, such as
+// JSON, without repeating every name. The names found in those tags are converted into UPPER_SNAKE_CASE, with
+// hyphens and dots replaced by underscores (e.g. `json:"max-conn,omitempty"` gives MAX_CONN), and prefixed as usual.
+// A tag whose name is empty or "-" is ignored, so fields excluded from JSON can still be loaded.
+func WithTagFallback(tags ...string) Option {
+	return func(l *Loader) {
+		l.fallbackTags = strings.Join(tags, ",")
+	}
+}
+
+// fallbackName returns the variable name derived from the first fallback tag of a field with a name, if any
+// (see WithTagFallback).
+func (o planOptions) fallbackName(tag reflect.StructTag) (string, bool) {
+	if o.fallbackTags == "" {
+		return "", false
+	}
+	for _, key := range strings.Split(o.fallbackTags, ",") {
+		name, _, _ := strings.Cut(tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return o.snakeCase(fallbackNameReplacer.Replace(name)), true
+		}
+	}
+	return "", false
+}
+
+// fallbackNameReplacer replaces the characters of the names in fallback tags that are not valid in variable names.
+var fallbackNameReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// mapName returns the name generated by the name mapper of the plan options for the given field path.
+func (o planOptions) mapName(fieldPath []string) string {
+	if o.nameMapper == nil {
+		return o.snakeCase(fieldPath[len(fieldPath)-1])
+	}
+	return (*o.nameMapper)(fieldPath)
+}
+
+// snakeCase converts a field name into UPPER_SNAKE_CASE according to the plan options.
+func (o planOptions) snakeCase(name string) string {
+	if o.legacyNaming {
+		return legacyCamelCaseToUpperSnakeCase(name)
+	}
+	return camelCaseToUpperSnakeCase(name)
+}
+
+// hasTagName returns whether a field tag specifies the variable name.
+func hasTagName(tag string) bool {
+	return !strings.HasPrefix(tag, ",") && tag != ""
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpperSnakeCase(t *testing.T) {
+	assert.Equal(t, "MAX_CONN", UpperSnakeCase([]string{"Database", "MaxConn"}))
+	assert.Equal(t, "HOST", UpperSnakeCase([]string{"Host"}))
+}
+
+func TestWithNameMapper(t *testing.T) {
+	type config struct {
+		Host     string
+		MaxConn  int
+		Password string `env:",secret"`
+		Token    string `env:"API_TOKEN"`
+		Database struct {
+			Host string
+		}
+		Cache struct {
+			Host string
+		} `prefix:"redis."`
+		Embedded
+	}
+	dotted := func(fieldPath []string) string {
+		return strings.ToLower(strings.Join(fieldPath, "."))
+	}
+	lookup := MapLookup(map[string]string{
+		"app.host":          "localhost",
+		"app.maxconn":       "10",
+		"app.password":      "secret",
+		"app.API_TOKEN":     "token",
+		"app.database.host": "db",
+		"app.redis.host":    "redis",
+		"app.url":           "http://example.com",
+	})
+
+	var cfg config
+	if assert.Nil(t, NewWithLookup("app.", lookup, nil, WithNameMapper(dotted)).Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, 10, cfg.MaxConn)
+		assert.Equal(t, "secret", cfg.Password)
+		assert.Equal(t, "token", cfg.Token)
+		assert.Equal(t, "db", cfg.Database.Host)
+		assert.Equal(t, "redis", cfg.Cache.Host)
+		assert.Equal(t, "http://example.com", cfg.URL)
+	}
+
+	// plans are not shared between loaders with different mappers
+	exact := func(fieldPath []string) string {
+		return fieldPath[len(fieldPath)-1]
+	}
+	cfg = config{}
+	lookup = MapLookup(map[string]string{"Host": "localhost", "MaxConn": "10"})
+	if assert.Nil(t, NewWithLookup("", lookup, nil, WithNameMapper(exact)).Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, 10, cfg.MaxConn)
+		assert.Equal(t, "localhost", cfg.Database.Host)
+	}
+}
+
+func TestWithLegacyNaming(t *testing.T) {
+	type config struct {
+		APIKey    string
+		HTTPProxy struct {
+			URL string
+		}
+	}
+	lookup := MapLookup(map[string]string{
+		"APP_API_KEY":        "new",
+		"APP_APIKEY":         "old",
+		"APP_HTTP_PROXY_URL": "http://new",
+		"APP_HTTPPROXY_URL":  "http://old",
+	})
+
+	var cfg config
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithAutoPrefix()).Load(&cfg)) {
+		assert.Equal(t, "new", cfg.APIKey)
+		assert.Equal(t, "http://new", cfg.HTTPProxy.URL)
+	}
+	cfg = config{}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithAutoPrefix(), WithLegacyNaming()).Load(&cfg)) {
+		assert.Equal(t, "old", cfg.APIKey)
+		assert.Equal(t, "http://old", cfg.HTTPProxy.URL)
+	}
+}
+
+func TestWithTagFallback(t *testing.T) {
+	type config struct {
+		Host     string `json:"hostName"`
+		MaxConn  int    `json:"max-conn,omitempty"`
+		Port     int    `json:"port" env:"SERVER_PORT"`
+		Password string `json:"-" env:",secret"`
+		Mode     string `yaml:"run_mode" json:",omitempty"`
+		Ignored  string `json:"ignored" env:"-"`
+	}
+	lookup := MapLookup(map[string]string{
+		"APP_HOST_NAME":   "localhost",
+		"APP_MAX_CONN":    "10",
+		"APP_SERVER_PORT": "8080",
+		"APP_PASSWORD":    "secret",
+		"APP_RUN_MODE":    "dev",
+		"APP_IGNORED":     "x",
+	})
+
+	var cfg config
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithTagFallback("json", "yaml")).Load(&cfg)) {
+		assert.Equal(t, config{Host: "localhost", MaxConn: 10, Port: 8080, Password: "secret", Mode: "dev"}, cfg)
+	}
+
+	// without the option, the field names are used
+	cfg = config{}
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil).Load(&cfg)) {
+		assert.Equal(t, "", cfg.Host)
+	}
+}
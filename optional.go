@@ -0,0 +1,114 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithOptionalPointers makes nil pointers to nested structs stay nil unless at least one variable of the fields
+// under them is set, so that a pointer field can represent an optional section of the configuration. By default,
+// Load allocates such pointers regardless of the variables. The fields under a nil pointer that stays nil are
+// skipped: their defaults are not applied and their "required" tags are not enforced. Pointers that are not nil
+// before loading are populated as usual.
+//
+// The code generated by WriteLoadFunc always allocates pointers to nested structs.
+func WithOptionalPointers() Option {
+	return func(l *Loader) {
+		l.optionalPointers = true
+	}
+}
+
+// absentFields returns the set of fields of the struct value v that are under a nil pointer to a nested struct
+// that must stay nil, because no variable of the fields under it is set (see WithOptionalPointers).
+func (l *Loader) absentFields(v reflect.Value, fields []*fieldInfo) map[*fieldInfo]bool {
+	if !l.optionalPointers {
+		return nil
+	}
+	paths := make(map[*fieldInfo][]string, len(fields))
+	present := map[string]bool{}
+	for _, f := range fields {
+		if !l.active(f) {
+			continue
+		}
+		if paths[f] = nilPointers(v, f.index); len(paths[f]) > 0 && l.isSet(f) {
+			for _, p := range paths[f] {
+				present[p] = true
+			}
+		}
+	}
+	absent := map[*fieldInfo]bool{}
+	for f, ps := range paths {
+		for _, p := range ps {
+			if !present[p] {
+				absent[f] = true
+				break
+			}
+		}
+	}
+	return absent
+}
+
+// nilPointers returns the keys identifying the nil pointers to nested structs on the way to the field of the struct
+// value v with the given index, including those under another nil pointer.
+func nilPointers(v reflect.Value, index []int) []string {
+	var keys []string
+	isNil := false
+	t := v.Type()
+	for i, x := range index[:len(index)-1] {
+		sf := t.Field(x)
+		if !isNil {
+			v = v.Field(x)
+		}
+		t = sf.Type
+		if t.Kind() != reflect.Ptr {
+			continue
+		}
+		if !isNil && v.IsNil() {
+			isNil = true
+		}
+		if isNil {
+			keys = append(keys, fmt.Sprint(index[:i+1]))
+		} else {
+			v = v.Elem()
+		}
+		t = t.Elem()
+	}
+	return keys
+}
+
+// isSet returns whether a variable of a field is set, without logging anything.
+func (l *Loader) isSet(f *fieldInfo) bool {
+	if f.collect {
+		return len(l.collectNames(l.prefix+f.name)) > 0
+	}
+	if _, _, ok := l.lookupValue(l.prefix, f); ok {
+		return true
+	}
+	if _, _, _, ok := l.findProfile(f); ok {
+		return true
+	}
+	for _, alias := range f.alternatives() {
+		if _, _, ok := l.lookupValue(l.prefix, f.alias(alias)); ok {
+			return true
+		}
+	}
+	if l.legacyPrefix != "" {
+		if _, _, ok := l.lookupValue(l.legacyPrefix, f); ok {
+			return true
+		}
+	}
+	if f.keyed {
+		keys, err := l.elementKeys(f)
+		return err == nil && len(keys) > 0
+	}
+	if f.indexed && l.elementDepth < maxElementDepth {
+		el := l.elementLoader(f, "0")
+		fields, err := el.getFields(indirectType(indirectType(f.typ).Elem()))
+		return err == nil && el.anySet(fields)
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type optionalConfig struct {
+	Host  string
+	Cache *struct {
+		Host string `required:"true"`
+		TTL  int    `default:"60"`
+	} `prefix:"CACHE_"`
+	TLS *struct {
+		Cert   string
+		Client *struct {
+			CA string
+		} `prefix:"CLIENT_"`
+	} `prefix:"TLS_"`
+}
+
+func TestWithOptionalPointers(t *testing.T) {
+	lookup := MapLookup(map[string]string{"APP_HOST": "localhost", "APP_TLS_CERT": "cert.pem"})
+
+	var cfg optionalConfig
+	report, err := NewWithLookup("APP_", lookup, nil, WithOptionalPointers()).LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Nil(t, cfg.Cache)
+		if assert.NotNil(t, cfg.TLS) {
+			assert.Equal(t, "cert.pem", cfg.TLS.Cert)
+			assert.Nil(t, cfg.TLS.Client)
+		}
+		assert.Equal(t, []string{"Host", "TLS.Cert"}, report.Overridden())
+		assert.Len(t, report.Fields, 2)
+	}
+
+	// a variable under a nested pointer allocates all pointers on the way
+	cfg = optionalConfig{}
+	lookup = MapLookup(map[string]string{"APP_TLS_CLIENT_CA": "ca.pem", "APP_CACHE_HOST": "redis"})
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithOptionalPointers()).Load(&cfg)) {
+		assert.Equal(t, "ca.pem", cfg.TLS.Client.CA)
+		assert.Equal(t, "redis", cfg.Cache.Host)
+		assert.Equal(t, 60, cfg.Cache.TTL)
+	}
+
+	// pointers that are not nil are populated as usual
+	cfg = optionalConfig{}
+	cfg.TLS = &struct {
+		Cert   string
+		Client *struct {
+			CA string
+		} `prefix:"CLIENT_"`
+	}{Cert: "old.pem"}
+	lookup = MapLookup(map[string]string{"APP_CACHE_HOST": "redis", "APP_TLS_CERT": "new.pem"})
+	if assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithOptionalPointers()).Load(&cfg)) {
+		assert.Equal(t, "new.pem", cfg.TLS.Cert)
+		assert.Nil(t, cfg.TLS.Client)
+	}
+
+	// without the option, pointers are always allocated
+	cfg = optionalConfig{}
+	lookup = MapLookup(map[string]string{})
+	assert.EqualError(t, NewWithLookup("APP_", lookup, nil).Load(&cfg), "$APP_CACHE_HOST: required variable is not set")
+
+	var buf bytes.Buffer
+	cfg = optionalConfig{}
+	assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithOptionalPointers()).Check(&buf, &cfg))
+	assert.Nil(t, cfg.Cache)
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer of the package.
+const tracerName = "github.com/garaekz/go-env"
+
+// WithTracerProvider instruments the loader with OpenTelemetry tracing, so that slow startups caused by secret
+// backends can be diagnosed. The following spans are created with tracers of the given provider:
+//   - "env.Load" for every struct loaded, with the "env.struct" (the struct type), "env.prefix", "env.fields"
+//     (the number of fields loaded) and "env.fields.set" (the number of fields set from variables) attributes
+//   - "env.Lookup" for every variable looked up in a backend other than the process environment, such as the
+//     lookup function of NewWithLookup, the secret lookup or a source, with the "env.var", "env.found" and
+//     "env.source" attributes; it is a child of the span of the load
+//   - "env.Reload" for every reload of a watched struct (see Watch), with the "env.changes" attribute; it is
+//     the parent of the span of the load
+//
+// Failed loads and reloads record their errors and have the error status.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(l *Loader) {
+		l.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// startLoadSpan starts the span of loading the given struct and returns a copy of the loader whose lookups create
+// child spans of it, along with the function that ends the span.
+func (l *Loader) startLoadSpan(structPtr interface{}) (*Loader, func(*Report, error)) {
+	ctx := l.spanContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := l.tracer.Start(ctx, "env.Load", trace.WithAttributes(
+		attribute.String("env.struct", fmt.Sprint(reflect.TypeOf(structPtr))),
+		attribute.String("env.prefix", l.prefix),
+	))
+	traced := *l
+	traced.spanContext = ctx
+	return &traced, func(report *Report, err error) {
+		if report != nil {
+			set := 0
+			for _, f := range report.Fields {
+				if f.Overridden {
+					set++
+				}
+			}
+			span.SetAttributes(attribute.Int("env.fields", len(report.Fields)), attribute.Int("env.fields.set", set))
+		}
+		endSpan(span, err)
+	}
+}
+
+// traceLookup creates the span of a lookup that started at the given time, unless the variable was read from
+// the process environment.
+func (l *Loader) traceLookup(start time.Time, name string, found bool, source string) {
+	if source == "env" || !found && l.lookupLabel == "env" && l.secretLookup == nil && len(l.sources) == 0 {
+		return
+	}
+	ctx := l.spanContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := l.tracer.Start(ctx, "env.Lookup", trace.WithTimestamp(start), trace.WithAttributes(
+		attribute.String("env.var", name),
+		attribute.Bool("env.found", found),
+		attribute.String("env.source", source),
+	))
+	span.End()
+}
+
+// endSpan ends a span, recording the error if any.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
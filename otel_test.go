@@ -0,0 +1,151 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttributes returns the attributes of a span as a map.
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]interface{} {
+	attrs := map[attribute.Key]interface{}{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value.AsInterface()
+	}
+	return attrs
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	type config struct {
+		Host     string
+		Port     int
+		Password string `env:",secret"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "localhost"}), nil,
+		WithSecretLookup(MapLookup(map[string]string{"APP_PASSWORD": "s3cret"})),
+		WithLookupLabel("vault"),
+		WithTracerProvider(tp))
+	var cfg config
+	assert.Nil(t, l.Load(&cfg))
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	load := spans[3]
+	assert.Equal(t, "env.Load", load.Name())
+	assert.Equal(t, map[attribute.Key]interface{}{
+		"env.struct":     "*env.config",
+		"env.prefix":     "APP_",
+		"env.fields":     int64(3),
+		"env.fields.set": int64(2),
+	}, spanAttributes(load))
+	for i, expected := range []map[attribute.Key]interface{}{
+		{"env.var": "APP_HOST", "env.found": true, "env.source": "vault"},
+		{"env.var": "APP_PORT", "env.found": false, "env.source": ""},
+		{"env.var": "APP_PASSWORD", "env.found": true, "env.source": "secret"},
+	} {
+		assert.Equal(t, "env.Lookup", spans[i].Name())
+		assert.Equal(t, expected, spanAttributes(spans[i]))
+		assert.Equal(t, load.SpanContext().SpanID(), spans[i].Parent().SpanID())
+	}
+
+	// failed loads have the error status
+	recorder = tracetest.NewSpanRecorder()
+	tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "x"}), nil, WithTracerProvider(tp))
+	assert.NotNil(t, l.Load(&cfg))
+	spans = recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, codes.Error, spans[2].Status().Code)
+		assert.Len(t, spans[2].Events(), 1)
+	}
+
+	// lookups in the process environment are not traced
+	recorder = tracetest.NewSpanRecorder()
+	tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Setenv("APP_HOST", "localhost")
+	assert.Nil(t, New("APP_", nil, WithTracerProvider(tp)).Load(&cfg))
+	spans = recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "env.Load", spans[0].Name())
+	}
+}
+
+func TestWithTracerProvider_bookkeeping(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var logs []string
+	logFunc := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "localhost"}), logFunc,
+		WithTracerProvider(tp), WithLogMode(LogSummary), WithConfigHash())
+	var cfg struct {
+		Host string
+		Port int
+	}
+	assert.Nil(t, l.Load(&cfg))
+
+	stats := l.Stats()
+	assert.Equal(t, uint64(1), stats.Loads)
+	assert.Equal(t, uint64(1), stats.FieldsSet)
+	assert.NotEmpty(t, stats.ConfigHash)
+	assert.Equal(t, []string{"loaded 1 fields, 0 defaulted, 1 missing"}, logs)
+	assert.Len(t, recorder.Ended(), 3)
+}
+
+func TestWithTracerProvider_reload(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var mu sync.Mutex
+	port := "80"
+	lookup := func(name string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return port, name == "APP_PORT"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var cfg struct{ Port int }
+	w, err := NewWithLookup("APP_", lookup, nil, WithTracerProvider(tp)).Watch(ctx, &cfg, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	mu.Lock()
+	port = "8080"
+	mu.Unlock()
+	<-w.Events()
+	_, _ = w.Freeze(context.Background())
+
+	var reload sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "env.Reload" && spanAttributes(span)["env.changes"] == int64(1) {
+			reload = span
+		}
+	}
+	if !assert.NotNil(t, reload) {
+		return
+	}
+	var children []string
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == reload.SpanContext().SpanID() {
+			children = append(children, span.Name())
+		}
+	}
+	assert.Equal(t, []string{"env.Load"}, children)
+}
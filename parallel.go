@@ -0,0 +1,102 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "sync"
+
+type (
+	// lookupCandidate is a variable that find may consult for a field: the field, or an alias of it, with a prefix.
+	lookupCandidate struct {
+		prefix string
+		f      *fieldInfo
+	}
+
+	// prefetchKey identifies the result of looking up a variable, which depends on whether its field is secret.
+	prefetchKey struct {
+		name   string
+		secret bool
+	}
+
+	// prefetchResult is the result of looking up a variable in advance.
+	prefetchResult struct {
+		value, source string
+		ok            bool
+	}
+)
+
+// WithParallelLookups makes the loader look up the variables of a struct concurrently, with at most n lookups in
+// flight, before populating its fields. This cuts the time to load structs with many variables read from a slow
+// backend, such as Vault or a secret manager, which would otherwise be looked up one after the other. Values of
+// n less than 2 disable parallel lookups, which is the default.
+//
+// All the variables that may be consulted for a field are looked up in advance, including the aliases and
+// deprecated names of variables that turn out to be set, and the fields are then populated in order from
+// the results. Variables consulted otherwise, such as those referenced by values with the "expand" option or
+// those of the elements of slices and maps of nested structs, are still looked up when they are needed.
+// The lookup functions and sources of the loader must be safe for concurrent use.
+func WithParallelLookups(n int) Option {
+	return func(l *Loader) {
+		l.parallelism = n
+	}
+}
+
+// candidates returns the variables that find may consult for a field, in the order it consults them.
+func (l *Loader) candidates(f *fieldInfo) []lookupCandidate {
+	var candidates []lookupCandidate
+	if prefix := l.profilePrefix(); prefix != "" {
+		candidates = append(candidates, lookupCandidate{prefix, f})
+		for _, name := range f.alternatives() {
+			candidates = append(candidates, lookupCandidate{prefix, f.alias(name)})
+		}
+	}
+	candidates = append(candidates, lookupCandidate{l.prefix, f})
+	for _, name := range f.alternatives() {
+		candidates = append(candidates, lookupCandidate{l.prefix, f.alias(name)})
+	}
+	if l.legacyPrefix != "" {
+		candidates = append(candidates, lookupCandidate{l.legacyPrefix, f})
+	}
+	return candidates
+}
+
+// prefetch looks up the variables that may be consulted for the active fields concurrently, with at most
+// l.parallelism lookups in flight, and returns the results.
+func (l *Loader) prefetch(fields []*fieldInfo) map[prefetchKey]prefetchResult {
+	results := map[prefetchKey]prefetchResult{}
+	seen := map[prefetchKey]bool{}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, l.parallelism)
+	)
+	for _, f := range fields {
+		if !l.active(f) || f.collect {
+			continue
+		}
+		for _, c := range l.candidates(f) {
+			key := prefetchKey{name: c.prefix + c.f.name, secret: c.f.secret}
+			// variables shared by several fields are looked up once
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(c lookupCandidate, key prefetchKey) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				var r prefetchResult
+				r.value, r.source, r.ok = l.lookupName(c.prefix, c.f)
+				mu.Lock()
+				results[key] = r
+				mu.Unlock()
+			}(c, key)
+		}
+	}
+	wg.Wait()
+	return results
+}
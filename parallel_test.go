@@ -0,0 +1,91 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithParallelLookups(t *testing.T) {
+	type config struct {
+		Host     string `env:"HOST,alias=HOSTNAME"`
+		Port     int
+		User     string
+		Password string `env:",secret"`
+		Region   string
+		Name     string `default:"app"`
+	}
+	vars := map[string]string{
+		"APP_HOSTNAME": "localhost",
+		"APP_PORT":     "80",
+		"APP_USER":     "admin",
+		"APP_REGION":   "eu",
+	}
+	var (
+		mu            sync.Mutex
+		calls         = map[string]int{}
+		active, peak  int32
+		secretLookups int32
+	)
+	lookup := func(name string) (string, bool) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		calls[name]++
+		mu.Unlock()
+		value, ok := vars[name]
+		return value, ok
+	}
+	var traces []string
+	l := NewWithLookup("APP_", lookup, nil, WithParallelLookups(3),
+		WithSecretLookup(func(name string) (string, bool) {
+			atomic.AddInt32(&secretLookups, 1)
+			return "s3cret", name == "APP_PASSWORD"
+		}),
+		WithTrace(func(name string, found bool, source string) {
+			traces = append(traces, name)
+		}))
+
+	var cfg config
+	assert.Nil(t, l.Load(&cfg))
+	assert.Equal(t, config{Host: "localhost", Port: 80, User: "admin", Password: "s3cret", Region: "eu", Name: "app"}, cfg)
+	assert.Equal(t, int32(3), peak)
+	assert.Equal(t, int32(1), secretLookups)
+	for name, n := range calls {
+		assert.Equal(t, 1, n, name)
+	}
+	// the lookups are traced in the order of the fields
+	assert.Equal(t, []string{"APP_HOST", "APP_HOSTNAME", "APP_PORT", "APP_USER", "APP_PASSWORD", "APP_REGION", "APP_NAME"}, traces)
+
+	// the loader is not modified by the prefetched results
+	assert.Nil(t, l.prefetched)
+}
+
+func TestWithParallelLookups_disabled(t *testing.T) {
+	var active, peak int32
+	lookup := func(name string) (string, bool) {
+		if n := atomic.AddInt32(&active, 1); n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		defer atomic.AddInt32(&active, -1)
+		return "1", true
+	}
+	var cfg struct{ A, B, C int }
+	assert.Nil(t, NewWithLookup("APP_", lookup, nil, WithParallelLookups(1)).Load(&cfg))
+	assert.Equal(t, int32(1), peak)
+	assert.Equal(t, 1, cfg.C)
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// ParserFunc parses a variable value into a value of the type it is registered for.
+	ParserFunc func(value string) (interface{}, error)
+
+	// parserSet maps types to the parsers registered for them.
+	parserSet map[reflect.Type]ParserFunc
+)
+
+var (
+	// parsers holds the parsers registered with RegisterParser.
+	parsers   = parserSet{}
+	parsersMu sync.RWMutex
+	// planGeneration is incremented by RegisterParser, so that the plans cached before are no longer used.
+	planGeneration atomic.Uint64
+)
+
+// RegisterParser registers a parser for the fields of type t, or pointers to it, used by every loader. It allows
+// loading types declared in other packages, which cannot be given a Set or UnmarshalText method. The parser must
+// return a value assignable to t:
+//
+//	env.RegisterParser(reflect.TypeOf(decimal.Decimal{}), func(value string) (interface{}, error) {
+//		return decimal.NewFromString(value)
+//	})
+//
+// A parser takes precedence over the built-in parsing rules, and struct types with a parser are not loaded as
+// nested structs. Parsers are typically registered in an init function, before any struct is loaded.
+// The parsers registered with WithParser take precedence over those registered globally.
+func RegisterParser(t reflect.Type, parser func(value string) (interface{}, error)) {
+	parsersMu.Lock()
+	parsers[t] = parser
+	parsersMu.Unlock()
+	// struct types with a parser are no longer nested structs, so the cached plans may be outdated
+	planGeneration.Add(1)
+	plans.Range(func(key, _ interface{}) bool {
+		plans.Delete(key)
+		return true
+	})
+}
+
+// WithParser registers a parser for the fields of type t, or pointers to it, used by the loader only.
+// For more details, please refer to RegisterParser().
+func WithParser(t reflect.Type, parser func(value string) (interface{}, error)) Option {
+	return func(l *Loader) {
+		// the set is copied rather than modified, since the plans cached for it would be outdated
+		set := parserSet{t: parser}
+		if l.parsers != nil {
+			for t, p := range *l.parsers {
+				if _, ok := set[t]; !ok {
+					set[t] = p
+				}
+			}
+		}
+		l.parsers = &set
+		l.plans = new(sync.Map)
+	}
+}
+
+// hasParser returns whether a parser is registered for the type t, globally or with the plan options.
+func (o planOptions) hasParser(t reflect.Type) bool {
+	return findParser(o.parsers, t) != nil
+}
+
+// findParser returns the parser for the type t in the given set, falling back to the parsers registered globally.
+// It returns nil if there is none.
+func findParser(set *parserSet, t reflect.Type) ParserFunc {
+	if set != nil {
+		if p, ok := (*set)[t]; ok {
+			return p
+		}
+	}
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	return parsers[t]
+}
+
+// parser returns the parser for a field type, or for the type it points to, along with the type it is registered
+// for. It returns nil if there is none.
+func (l *Loader) parser(t reflect.Type) (ParserFunc, reflect.Type) {
+	for {
+		if p := findParser(l.parsers, t); p != nil {
+			return p, t
+		}
+		if t.Kind() != reflect.Ptr {
+			return nil, nil
+		}
+		t = t.Elem()
+	}
+}
+
+// WithNamedParser registers a parser under the given name, so that it can be selected for individual fields with
+// a "parser" tag, e.g. `parser:"durationMs"`. This allows parsing fields of the same type differently, such as
+// a time.Duration given in milliseconds in one field and in seconds in another:
+//
+//	env.WithNamedParser("durationMs", func(value string) (interface{}, error) {
+//		ms, err := strconv.ParseInt(value, 10, 64)
+//		return time.Duration(ms) * time.Millisecond, err
+//	})
+//
+// The parser must return a value assignable to the field, or to the type it points to. A named parser takes
+// precedence over the parsers registered for the field type.
+func WithNamedParser(name string, parser func(value string) (interface{}, error)) Option {
+	return func(l *Loader) {
+		if l.namedParsers == nil {
+			l.namedParsers = map[string]ParserFunc{}
+		}
+		l.namedParsers[name] = parser
+	}
+}
+
+// setValue assigns a string value to a field like the package-level setValue, using the named parser in the
+// field's "parser" tag or the parser registered for the field type, if any. Extended boolean spellings are
+// accepted for bool fields if the loader is configured so (see WithExtendedBools).
+func (l *Loader) setValue(field reflect.Value, f *fieldInfo, value string) error {
+	var p ParserFunc
+	if f.parser != "" {
+		var ok bool
+		if p, ok = l.namedParsers[f.parser]; !ok {
+			return fmt.Errorf("unknown parser %q", f.parser)
+		}
+	} else if p, _ = l.parser(field.Type()); p == nil {
+		if l.extendedBools && indirectType(field.Type()).Kind() == reflect.Bool {
+			value = normalizeBool(value)
+		}
+		return setValue(field, value)
+	}
+	v, err := p(value)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && !rv.Type().AssignableTo(field.Type()) && field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	if !rv.IsValid() || !rv.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("the parser returned a value of type %T, which cannot be assigned to %v", v, field.Type())
+	}
+	field.Set(rv)
+	return nil
+}
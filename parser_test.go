@@ -0,0 +1,157 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// money is a third-party-like type with exported fields and no parsing methods.
+type money struct {
+	Amount   int64
+	Currency string
+}
+
+func parseMoney(value string) (interface{}, error) {
+	amount, currency, ok := strings.Cut(value, " ")
+	if !ok {
+		return nil, errors.New("missing currency")
+	}
+	n, err := strconv.ParseInt(amount, 10, 64)
+	return money{n, currency}, err
+}
+
+func TestRegisterParser(t *testing.T) {
+	type parserConfig struct {
+		Price money  `default:"5 USD"`
+		Limit *money `env:"MAX"`
+	}
+	var cfg parserConfig
+	vars := map[string]string{"APP_MAX": "100 EUR"}
+
+	l := NewWithLookup("APP_", MapLookup(vars), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		// without a parser, the struct is nested and its fields are loaded separately
+		assert.Equal(t, money{}, cfg.Price)
+	}
+	// a loader with its own parsers caches its plans, which are outdated as well
+	own := NewWithLookup("APP_", MapLookup(vars), nil, WithParser(reflect.TypeOf(net.IP{}), func(value string) (interface{}, error) {
+		return net.ParseIP(value), nil
+	}))
+	assert.Nil(t, own.Load(&parserConfig{}))
+
+	RegisterParser(reflect.TypeOf(money{}), parseMoney)
+	defer func() {
+		parsersMu.Lock()
+		delete(parsers, reflect.TypeOf(money{}))
+		parsersMu.Unlock()
+		planGeneration.Add(1)
+		plans.Range(func(key, _ interface{}) bool {
+			plans.Delete(key)
+			return true
+		})
+	}()
+
+	cfg = parserConfig{}
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, money{5, "USD"}, cfg.Price)
+		assert.Equal(t, &money{100, "EUR"}, cfg.Limit)
+	}
+	cfg = parserConfig{}
+	if assert.Nil(t, own.Load(&cfg)) {
+		assert.Equal(t, money{5, "USD"}, cfg.Price)
+	}
+
+	vars["APP_MAX"] = "100"
+	assert.EqualError(t, l.Load(&cfg), "$APP_MAX: missing currency")
+
+	m, err := l.ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "text", m.Vars[0].Type)
+	}
+
+	err = WriteLoadFunc(&bytes.Buffer{}, &cfg)
+	assert.EqualError(t, err, "Price: fields of types with registered parsers are not supported")
+}
+
+func TestWithParser(t *testing.T) {
+	type cents int64
+	var cfg struct {
+		Price cents
+		Count cents `env:"COUNT"`
+	}
+	parseCents := func(value string) (interface{}, error) {
+		f, err := strconv.ParseFloat(value, 64)
+		return cents(f * 100), err
+	}
+	vars := map[string]string{"APP_PRICE": "1.25", "APP_COUNT": "3"}
+
+	l := NewWithLookup("APP_", MapLookup(vars), nil, WithParser(reflect.TypeOf(cents(0)), parseCents))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, cents(125), cfg.Price)
+		assert.Equal(t, cents(300), cfg.Count)
+	}
+
+	// other loaders are not affected
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_COUNT": "3"}), nil)
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, cents(3), cfg.Count)
+	}
+
+	l = NewWithLookup("APP_", MapLookup(vars), nil, WithParser(reflect.TypeOf(cents(0)), func(string) (interface{}, error) {
+		return 1, nil
+	}))
+	assert.EqualError(t, l.Load(&cfg), "$APP_PRICE: the parser returned a value of type int, which cannot be assigned to env.cents")
+}
+
+func TestWithNamedParser(t *testing.T) {
+	type durationConfig struct {
+		Timeout  time.Duration  `parser:"ms"`
+		Interval *time.Duration `parser:"sec" default:"2"`
+		TTL      time.Duration
+		Window   money `parser:"money"`
+	}
+	parseUnit := func(unit time.Duration) func(string) (interface{}, error) {
+		return func(value string) (interface{}, error) {
+			n, err := strconv.ParseInt(value, 10, 64)
+			return time.Duration(n) * unit, err
+		}
+	}
+	var cfg durationConfig
+	vars := map[string]string{"APP_TIMEOUT": "1500", "APP_TTL": "1000", "APP_WINDOW": "7 USD"}
+	l := NewWithLookup("APP_", MapLookup(vars), nil,
+		WithNamedParser("ms", parseUnit(time.Millisecond)),
+		WithNamedParser("sec", parseUnit(time.Second)),
+		WithNamedParser("money", parseMoney))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, 1500*time.Millisecond, cfg.Timeout)
+		assert.Equal(t, 2*time.Second, *cfg.Interval)
+		assert.Equal(t, time.Duration(1000), cfg.TTL)
+		assert.Equal(t, money{7, "USD"}, cfg.Window)
+	}
+
+	m, err := l.ExportManifest(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "text", m.Vars[0].Type)
+		assert.Equal(t, "int64", m.Vars[2].Type)
+	}
+
+	l = NewWithLookup("APP_", MapLookup(vars), nil)
+	assert.EqualError(t, l.Load(&cfg), `$APP_TIMEOUT: unknown parser "ms"`)
+
+	var invalid struct {
+		Timeout time.Duration `parser:""`
+	}
+	assert.EqualError(t, l.Load(&invalid), "Timeout: invalid parser tag: empty name")
+}
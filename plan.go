@@ -0,0 +1,85 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "reflect"
+
+// PlannedField describes how Load would populate a struct field, as returned by Plan.
+type PlannedField struct {
+	// Field is the dot-separated path of the struct field (e.g. "Server.Port").
+	Field string
+	// Vars lists the names of the variables consulted for the field, including the loader prefix, in the order
+	// they are looked up: the variables of the active profile (see WithProfile), the variable of the field,
+	// its aliases, its deprecated names and its name with the legacy prefix (see WithLegacyPrefix). The first
+	// variable set populates the field. A name ending with "*" stands for all the variables starting with it,
+	// as read for map fields tagged with the "prefix" option and for slices and maps of nested structs.
+	Vars []string
+	// Type is the format used to parse the values, as in ManifestVar.Type.
+	Type string
+	// Secret indicates whether the field is tagged as secret.
+	Secret bool
+	// Default is the value of the "default" tag, which is used when HasDefault is true.
+	Default    string
+	HasDefault bool
+	// Required indicates whether loading fails if none of the variables is set and the field has no default.
+	Required bool
+}
+
+// Plan returns how the package-level Load function would populate a struct. For more details, please refer to
+// Loader.Plan().
+func Plan(structPtr interface{}) ([]PlannedField, error) {
+	return loader.Plan(structPtr)
+}
+
+// Plan returns how Load would populate the given struct, listing the variables that would be consulted for each
+// field in the order the fields are declared, without looking up any variable or modifying the struct. This is
+// useful to document a configuration, to debug why a variable is not picked up, or to check a deployment before
+// it happens. The struct must be specified as a pointer. Fields that are not loaded in the loader's environment
+// (see WithEnvironment) are excluded.
+//
+// The names are those read by the loader's lookup function. Sources with their own naming (see WithSources)
+// are consulted for the same keys when none of the variables is found.
+func (l *Loader) Plan(structPtr interface{}) ([]PlannedField, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+	fields, err := l.getFields(value.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+
+	plan := []PlannedField{}
+	for _, f := range fields {
+		if !l.active(f) {
+			continue
+		}
+		plan = append(plan, PlannedField{
+			Field:      f.path,
+			Vars:       l.plannedVars(f),
+			Type:       l.manifestType(f),
+			Secret:     f.secret,
+			Default:    f.def,
+			HasDefault: f.hasDefault,
+			Required:   f.required && !f.hasDefault,
+		})
+	}
+	return plan, nil
+}
+
+// plannedVars returns the names of the variables consulted for a field, in the order they are looked up by find.
+func (l *Loader) plannedVars(f *fieldInfo) []string {
+	if f.collect {
+		return []string{l.varName(f)}
+	}
+	var vars []string
+	for _, c := range l.candidates(f) {
+		vars = append(vars, c.prefix+c.f.name)
+	}
+	if f.indexed || f.keyed {
+		vars = append(vars, l.prefix+f.name+"_*")
+	}
+	return vars
+}
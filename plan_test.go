@@ -0,0 +1,43 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Plan(t *testing.T) {
+	type config struct {
+		Host     string            `env:"HOST,alias=HOSTNAME" deprecated:"SERVER" default:"localhost"`
+		Port     int               `required:"true"`
+		Password string            `env:",secret"`
+		Features map[string]string `env:",prefix=FEATURE_"`
+		Servers  []Embedded
+		Debug    bool     `envs:"dev"`
+		Nested   Embedded `prefix:"NESTED_"`
+	}
+	plan, err := New("APP_", nil, WithProfile("prod"), WithLegacyPrefix("OLD_")).Plan(&config{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []PlannedField{
+			{Field: "Host", Vars: []string{"APP_PROD_HOST", "APP_PROD_HOSTNAME", "APP_PROD_SERVER", "APP_HOST", "APP_HOSTNAME", "APP_SERVER", "OLD_HOST"}, Type: "string", Default: "localhost", HasDefault: true},
+			{Field: "Port", Vars: []string{"APP_PROD_PORT", "APP_PORT", "OLD_PORT"}, Type: "int", Required: true},
+			{Field: "Password", Vars: []string{"APP_PROD_PASSWORD", "APP_PASSWORD", "OLD_PASSWORD"}, Type: "string", Secret: true},
+			{Field: "Features", Vars: []string{"APP_FEATURE_*"}, Type: "json"},
+			{Field: "Servers", Vars: []string{"APP_PROD_SERVERS", "APP_SERVERS", "OLD_SERVERS", "APP_SERVERS_*"}, Type: "json"},
+			{Field: "Nested.URL", Vars: []string{"APP_PROD_NESTED_URL", "APP_NESTED_URL", "OLD_NESTED_URL"}, Type: "string"},
+			{Field: "Nested.Port", Vars: []string{"APP_PROD_NESTED_PORT", "APP_NESTED_PORT", "OLD_NESTED_PORT"}, Type: "int"},
+		}, plan)
+	}
+
+	plan, err = Plan(&struct{ Port int }{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []PlannedField{{Field: "Port", Vars: []string{"APP_PORT"}, Type: "int"}}, plan)
+	}
+
+	_, err = Plan(config{})
+	assert.Equal(t, ErrStructPointer, err)
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "strings"
+
+// WithProfile activates a profile, so that a variable named after the loader prefix followed by the profile name in
+// upper case takes precedence over the regular variable of a field. For example, with the prefix "APP_" and the
+// "prod" profile, $APP_PROD_DB_HOST overrides $APP_DB_HOST. This allows a single environment to carry the values
+// of several deployment targets, the profile being typically selected by another variable:
+//
+//	env.New("APP_", log.Printf, env.WithProfile(os.Getenv("APP_ENV")))
+//
+// An empty name activates no profile. Profiles apply to the fields populated from a single variable, including
+// their aliases and deprecated names, but not to map fields tagged with the "prefix" option nor to the elements of
+// slice and map fields. In strict mode, the variables of inactive profiles are reported as unknown.
+func WithProfile(name string) Option {
+	return func(l *Loader) {
+		l.profile = strings.ToUpper(name)
+	}
+}
+
+// profilePrefix returns the prefix of the variables of the active profile (e.g. "APP_PROD_"), or an empty string
+// if no profile is active.
+func (l *Loader) profilePrefix() string {
+	if l.profile == "" {
+		return ""
+	}
+	return l.prefix + l.profile + "_"
+}
+
+// findProfile looks up the profile variable of a field, falling back to its aliases and deprecated names with
+// the profile prefix. It returns the full name of the variable found, its value and the label of its source.
+func (l *Loader) findProfile(f *fieldInfo) (string, string, string, bool) {
+	prefix := l.profilePrefix()
+	if prefix == "" {
+		return "", "", "", false
+	}
+	if value, source, ok := l.lookupValue(prefix, f); ok {
+		return prefix + f.name, value, source, true
+	}
+	for _, name := range f.alternatives() {
+		if value, source, ok := l.lookupValue(prefix, f.alias(name)); ok {
+			return prefix + name, value, source, true
+		}
+	}
+	return "", "", "", false
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProfile(t *testing.T) {
+	type config struct {
+		Host  string `env:"DB_HOST"`
+		Port  int    `env:"DB_PORT,alias=PORT"`
+		Debug bool   `default:"true"`
+		User  string `required:"true"`
+	}
+	vars := map[string]string{
+		"APP_DB_HOST":      "localhost",
+		"APP_PROD_DB_HOST": "db.prod",
+		"APP_DB_PORT":      "5432",
+		"APP_PROD_PORT":    "6432",
+		"APP_PROD_USER":    "admin",
+		"APP_STAGE_USER":   "stage",
+	}
+
+	var cfg config
+	l := NewWithLookup("APP_", MapLookup(vars), nil, WithProfile("prod"))
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, config{Host: "db.prod", Port: 6432, Debug: true, User: "admin"}, cfg)
+		assert.Equal(t, "APP_PROD_DB_HOST", report.Fields[0].Var)
+		assert.Equal(t, "APP_PROD_PORT", report.Fields[1].Var)
+	}
+
+	cfg = config{}
+	l = NewWithLookup("APP_", MapLookup(vars), nil, WithProfile(""))
+	err = l.Load(&cfg)
+	assert.EqualError(t, err, "$APP_USER: required variable is not set")
+	assert.Equal(t, "localhost", cfg.Host)
+}
+
+func TestWithProfile_strict(t *testing.T) {
+	t.Setenv("APP_HOST", "localhost")
+	t.Setenv("APP_PROD_HOST", "prod")
+	t.Setenv("APP_STAGE_HOST", "stage")
+	var cfg struct {
+		Host string
+	}
+	l := New("APP_", nil, WithProfile("prod"), WithStrict(true))
+	assert.EqualError(t, l.Load(&cfg), "unknown variables: $APP_STAGE_HOST")
+	assert.Equal(t, "prod", cfg.Host)
+}
@@ -0,0 +1,200 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+const (
+	// randomChars are the characters used in randomly generated strings.
+	randomChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// maxRandomDepth is the maximum nesting of pointers, slices and maps in randomly generated values.
+	maxRandomDepth = 4
+)
+
+// Random populates a struct with a random valid configuration using the package-level loader.
+// For more details, please refer to Loader.Random().
+func Random(structPtr interface{}, r *rand.Rand) (map[string]string, error) {
+	return loader.Random(structPtr, r)
+}
+
+// Random populates a struct with a random configuration that satisfies the constraints declared by its tags,
+// and returns the variables that Load reads to produce the same configuration. It is meant for fuzz and integration
+// tests that explore the configuration space. The struct must be specified as a pointer, and its fields are
+// overwritten.
+//
+// Required fields are always set. Other fields are randomly left unset, in which case they take the values of their
+// "default" tags, if any. Fields of types implementing Setter, TextUnmarshaler or BinaryUnmarshaler cannot be
+// generated, and are left unset, except for URLs, IP addresses, CIDR blocks, regular expressions and big numbers.
+// Fields with constraints such as "oneof" or "min" tags take values satisfying them, except for slices, lengths of
+// values other than strings and patterns of "match" tags without "oneof" tags, which are left unset. Fields tagged
+// with "enum" take one of the values it names.
+// Fields that are not loaded in the loader's environment are left at zero.
+// Generated strings consist of letters and digits, so any transformer used by the loader must preserve such values.
+func (l *Loader) Random(structPtr interface{}, r *rand.Rand) (map[string]string, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+	value = value.Elem()
+	fields, err := l.getFields(value.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	value.Set(reflect.Zero(value.Type()))
+	vars := map[string]string{}
+	for _, f := range fields {
+		field := fieldByIndex(value, f.index)
+		if !l.active(f) || !supportedKind(elemKind(f.typ)) {
+			continue
+		}
+
+		if len(f.enum) > 0 && (f.required || r.Intn(4) > 0) {
+			v := f.enum[r.Intn(len(f.enum))]
+			if err := l.setValue(field, f, v.value); err != nil {
+				return nil, fmt.Errorf("%v: %w", f.path, err)
+			}
+			vars[l.prefix+f.name] = v.name
+			continue
+		}
+		generatable := len(f.enum) == 0 && l.manifestType(f) != "text" && f.cons.generatable(f.typ)
+		if generatable && (f.required || r.Intn(4) > 0) {
+			if s, ok := f.cons.random(f.typ, r); ok {
+				if err := l.setValue(field, f, s); err != nil {
+					return nil, fmt.Errorf("%v: %w", f.path, err)
+				}
+				vars[l.prefix+f.name] = f.encode(s)
+				continue
+			}
+			v := randomValue(f.typ, r, 0)
+			s, err := formatVar(reflect.Indirect(v))
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", f.path, err)
+			}
+			field.Set(v)
+			if f.collect {
+				collectedVars(vars, l.prefix+f.name, v)
+			} else {
+				vars[l.prefix+f.name] = f.encode(s)
+			}
+			continue
+		}
+		if f.hasDefault {
+			def, err := l.defaultValue(f)
+			if err == nil {
+				err = l.setValue(field, f, def)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%v: invalid default: %w", f.path, err)
+			}
+		} else if f.required {
+			return nil, fmt.Errorf("%v: cannot generate a value of type %v", f.path, f.typ)
+		}
+	}
+	return vars, nil
+}
+
+// randomValue returns a random value of the given type. Values of types implementing Setter, TextUnmarshaler
+// or BinaryUnmarshaler, other than URLs, IP addresses, CIDR blocks, regular expressions and big numbers, are left
+// at zero, and so are values nested in more than maxRandomDepth levels of pointers, slices and maps, which guards
+// against recursive types.
+func randomValue(t reflect.Type, r *rand.Rand, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if t.Kind() != reflect.Ptr {
+		switch valueFormat(t) {
+		case "text":
+			return v
+		case "url":
+			v.Set(reflect.ValueOf(url.URL{Scheme: "https", Host: strings.ToLower(string(randomBytes(r))) + ".example.com"}))
+			return v
+		case "ip":
+			_ = setValue(v, fmt.Sprintf("10.%d.%d.%d", r.Intn(256), r.Intn(256), r.Intn(256)))
+			return v
+		case "cidr":
+			_ = setValue(v, fmt.Sprintf("10.%d.0.0/16", r.Intn(256)))
+			return v
+		case "regexp":
+			_ = setValue(v, "^"+string(randomBytes(r))+"$")
+			return v
+		case "bigint", "bigfloat", "bigrat":
+			digits := make([]byte, 20+r.Intn(20))
+			for i := range digits {
+				digits[i] = byte('1' + r.Intn(9))
+			}
+			_ = setValue(v, string(digits))
+			return v
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if depth >= maxRandomDepth {
+			return v
+		}
+		depth++
+	}
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(string(randomBytes(r)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(r.Int63() >> (64 - t.Bits()))
+		if r.Intn(2) == 0 {
+			v.SetInt(-v.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(r.Uint64() >> (64 - t.Bits()))
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 0)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(r.NormFloat64() * 1000)
+	case reflect.Ptr:
+		v.Set(randomValue(t.Elem(), r, depth).Addr())
+	case reflect.Slice:
+		if t == rawMessageType {
+			b, _ := json.Marshal(string(randomBytes(r)))
+			v.SetBytes(b)
+			break
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(randomBytes(r))
+			break
+		}
+		v.Set(reflect.MakeSlice(t, 1+r.Intn(3), 3))
+		for i := 0; i < v.Len(); i++ {
+			v.Index(i).Set(randomValue(t.Elem(), r, depth))
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			v.Index(i).Set(randomValue(t.Elem(), r, depth))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		for i := 1 + r.Intn(3); i > 0; i-- {
+			v.SetMapIndex(randomValue(t.Key(), r, depth), randomValue(t.Elem(), r, depth))
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if sf := t.Field(i); sf.PkgPath == "" && sf.Tag.Get("json") != "-" {
+				v.Field(i).Set(randomValue(sf.Type, r, depth))
+			}
+		}
+	}
+	return v
+}
+
+// randomBytes returns a random non-empty sequence of letters and digits.
+func randomBytes(r *rand.Rand) []byte {
+	b := make([]byte, 1+r.Intn(16))
+	for i := range b {
+		b[i] = randomChars[r.Intn(len(randomChars))]
+	}
+	return b
+}
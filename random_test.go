@@ -0,0 +1,70 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type randomConfig struct {
+	Host     string `required:"true"`
+	Port     uint16 `default:"80"`
+	Level    int8
+	Ratio    float32
+	Debug    bool
+	Key      []byte `env:",secret"`
+	Timeout  *time.Duration
+	Tags     []string
+	Labels   map[string]int
+	IP       net.IP    `default:"127.0.0.1"`
+	Nested   *Embedded `prefix:"NESTED_"`
+	Items    []randomItem
+	Dev      string `envs:"development"`
+	Callback func()
+}
+
+type randomItem struct {
+	Name   string
+	Next   *randomItem
+	Hidden string `json:"-"`
+}
+
+func TestLoader_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	l := NewWithLookup("APP_", mockLookup, nil)
+	for i := 0; i < 100; i++ {
+		var cfg randomConfig
+		vars, err := l.Random(&cfg, r)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.NotEmpty(t, vars["APP_HOST"])
+		assert.NotContains(t, vars, "APP_DEV")
+		if _, ok := vars["APP_PORT"]; !ok {
+			assert.Equal(t, uint16(80), cfg.Port)
+		}
+		if _, ok := vars["APP_IP"]; !ok {
+			assert.Equal(t, "127.0.0.1", cfg.IP.String())
+		}
+
+		var loaded randomConfig
+		if assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&loaded)) {
+			assert.Equal(t, cfg, loaded)
+		}
+	}
+
+	var cfg struct {
+		Level myInt `required:"true"`
+	}
+	_, err := l.Random(&cfg, r)
+	assert.EqualError(t, err, "Level: cannot generate a value of type env.myInt")
+	_, err = Random(cfg, r)
+	assert.Equal(t, ErrStructPointer, err)
+}
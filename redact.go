@@ -0,0 +1,50 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Redactor returns the form of the value of a secret variable that can be logged or displayed.
+// The name is the full name of the variable.
+type Redactor func(name, value string) string
+
+// WithRedactor specifies how the values of secret fields are masked in logs, reports, checks, diffs and
+// redacted dumps. By default, they are replaced with "***".
+func WithRedactor(redactor Redactor) Option {
+	return func(l *Loader) {
+		l.redactor = redactor
+	}
+}
+
+// RedactKeepLast returns a Redactor that only shows the last n characters of a value, e.g. "***1234".
+// Values of at most 2n characters are fully masked, so that short secrets are not mostly revealed.
+func RedactKeepLast(n int) Redactor {
+	return func(name, value string) string {
+		runes := []rune(value)
+		if len(runes) <= 2*n {
+			return "***"
+		}
+		return "***" + string(runes[len(runes)-n:])
+	}
+}
+
+// RedactHash is a Redactor that replaces a value with the first 12 hexadecimal digits of its SHA-256 hash,
+// e.g. "sha256:9f86d081884c", so that changes can be detected without revealing the value. Note that the
+// hashes of low-entropy secrets, such as short passwords, can be reversed by brute force.
+func RedactHash(name, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// redact returns the masked form of the value of a secret variable.
+func (l *Loader) redact(name, value string) string {
+	if l.redactor == nil {
+		return "***"
+	}
+	return l.redactor(name, value)
+}
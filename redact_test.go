@@ -0,0 +1,61 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactKeepLast(t *testing.T) {
+	redact := RedactKeepLast(4)
+	assert.Equal(t, "***5678", redact("KEY", "secret-12345678"))
+	assert.Equal(t, "***", redact("KEY", "12345678"))
+	assert.Equal(t, "***", redact("KEY", ""))
+	assert.Equal(t, "***éèêë", redact("KEY", "abcdeféèêë"))
+}
+
+func TestRedactHash(t *testing.T) {
+	assert.Equal(t, "sha256:9f86d081884c", RedactHash("KEY", "test"))
+	assert.NotEqual(t, RedactHash("KEY", "test"), RedactHash("KEY", "test2"))
+}
+
+func TestWithRedactor(t *testing.T) {
+	type config struct {
+		Host     string
+		Password string `env:",secret"`
+	}
+	var names []string
+	redactor := func(name, value string) string {
+		names = append(names, name)
+		return "<" + value[:1] + ">"
+	}
+	lookup := MapLookup(map[string]string{"APP_HOST": "localhost", "APP_PASSWORD": "xyz"})
+
+	var logger myLogger
+	l := NewWithLookup("APP_", lookup, logger.Log, WithRedactor(redactor))
+	var cfg config
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{`set Host with $APP_HOST="localhost"`, `set Password with $APP_PASSWORD="<x>"`}, logger.logs)
+		assert.Equal(t, "<x>", report.Fields[1].Value)
+		assert.Equal(t, []string{"APP_PASSWORD", "APP_PASSWORD"}, names)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, l.Check(&buf, &config{}))
+	assert.Contains(t, buf.String(), "$APP_PASSWORD  <x>")
+
+	diffs, err := l.Compare(&config{}, lookup, MapLookup(map[string]string{"APP_HOST": "localhost", "APP_PASSWORD": "abc"}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, []FieldDiff{{Field: "Password", Var: "APP_PASSWORD", Secret: true, Left: "<x>", Right: "<a>"}}, diffs)
+	}
+
+	buf.Reset()
+	assert.Nil(t, l.RunCommand(&buf, &config{}, []string{"dump", "--redacted"}))
+	assert.Equal(t, "APP_HOST=localhost\nAPP_PASSWORD=<x>\n", buf.String())
+}
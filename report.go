@@ -0,0 +1,98 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import "reflect"
+
+type (
+	// Report describes how the fields of a struct were populated by a Load call.
+	Report struct {
+		// Fields lists the populated struct fields in the order they are declared.
+		Fields []FieldReport
+
+		// problems lists the errors that are not about a single field, such as unknown variables and errors of
+		// post-load hooks, collected by loaders with lenient errors.
+		problems []error
+	}
+
+	// FieldReport describes how a struct field was populated.
+	FieldReport struct {
+		// Field is the dot-separated path of the struct field (e.g. "Nested.URL").
+		Field string
+		// Var is the name of the variable consulted for the field, including the loader prefix. If the variable
+		// was found with the legacy prefix (see WithLegacyPrefix), it is the name with the legacy prefix.
+		Var string
+		// Secret indicates whether the field is tagged as secret.
+		Secret bool
+		// Overridden indicates whether the variable was found and the field was set from it. If false, the field
+		// is still at its default, which is either the value of its "default" tag or the value it had before loading.
+		Overridden bool
+		// DefaultUsed indicates whether the field was set from its "default" tag because its variable is not set.
+		DefaultUsed bool
+		// Source tells where the value of the field came from, answering where a value in production was set:
+		// the label of the source the variable was read from (see Source.Label), the label of the loader's lookup
+		// function, which is "env" for the process environment (see WithLookupLabel), "secret" for the secret
+		// lookup (see WithSecretLookup), or "default" if the field was set from its "default" tag. It is empty if
+		// the field was left unchanged or set from the elements of a slice or map of nested structs.
+		Source string
+		// Value is the final value of the field, formatted for display. The values of secret fields are masked
+		// (see WithRedactor).
+		Value string
+		// Err is the error populating the field, which is only reported by loaders with lenient errors
+		// (see WithLenientErrors). The field keeps the value it had before loading in this case.
+		Err error
+	}
+)
+
+// Overridden returns the paths of the fields that were set from variables, in the order they are declared.
+func (r *Report) Overridden() []string {
+	var fields []string
+	for _, f := range r.Fields {
+		if f.Overridden {
+			fields = append(fields, f.Field)
+		}
+	}
+	return fields
+}
+
+// Defaulted returns the paths of the fields that were left at their defaults, in the order they are declared.
+func (r *Report) Defaulted() []string {
+	var fields []string
+	for _, f := range r.Fields {
+		if !f.Overridden {
+			fields = append(fields, f.Field)
+		}
+	}
+	return fields
+}
+
+// SetFields reports which fields of a struct are set from variables using the package-level loader.
+// For more details, please refer to Loader.SetFields().
+func SetFields(structPtr interface{}) (map[string]bool, error) {
+	return loader.SetFields(structPtr)
+}
+
+// SetFields returns a map from the dot-separated paths of the fields of the given struct to whether Load would
+// set them from variables, that is, whether their variables, or those of their aliases, deprecated names or legacy
+// prefix, are currently set. This distinguishes fields explicitly set to their zero values from fields left
+// untouched or set from their "default" tags. The struct must be specified as a pointer and is not modified.
+// Fields that are not loaded in the loader's environment are omitted.
+func (l *Loader) SetFields(structPtr interface{}) (map[string]bool, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+	fields, err := l.getFields(value.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if l.active(f) {
+			set[f.path] = l.isSet(f)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reportConfig struct {
+	Host     string
+	Port     int      `default:"9090"`
+	Mode     string   `default:"dev"`
+	Password string   `env:",secret"`
+	Nested   Embedded `prefix:"NESTED_"`
+}
+
+func TestLoader_LoadReport(t *testing.T) {
+	l := NewWithLookup("APP_", mockLookup2, nil)
+	cfg := reportConfig{Host: "example.com"}
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, "dev", cfg.Mode)
+		assert.Equal(t, []FieldReport{
+			{Field: "Host", Var: "APP_HOST", Overridden: true, Source: "lookup", Value: "localhost"},
+			{Field: "Port", Var: "APP_PORT", Overridden: true, Source: "lookup", Value: "8080"},
+			{Field: "Mode", Var: "APP_MODE", DefaultUsed: true, Source: "default", Value: "dev"},
+			{Field: "Password", Var: "APP_PASSWORD", Secret: true, Overridden: true, Source: "lookup", Value: "***"},
+			{Field: "Nested.URL", Var: "APP_NESTED_URL", Overridden: true, Source: "lookup", Value: "http://example.com"},
+			{Field: "Nested.Port", Var: "APP_NESTED_PORT", Overridden: true, Source: "lookup", Value: "8080"},
+		}, report.Fields)
+		assert.Equal(t, []string{"Mode"}, report.Defaulted())
+		assert.Equal(t, []string{"Host", "Port", "Password", "Nested.URL", "Nested.Port"}, report.Overridden())
+	}
+
+	l = NewWithLookup("APP_", mockLookup3, nil)
+	cfg = reportConfig{}
+	report, err = l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 9090, cfg.Port)
+		assert.Empty(t, report.Overridden())
+	}
+
+	_, err = l.LoadReport(cfg)
+	assert.Equal(t, ErrStructPointer, err)
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"OLD_HOST": "example.com"}), nil, WithLegacyPrefix("OLD_"))
+	cfg = reportConfig{}
+	report, err = l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, FieldReport{Field: "Host", Var: "OLD_HOST", Overridden: true, Source: "lookup", Value: "example.com"}, report.Fields[0])
+		assert.Equal(t, FieldReport{Field: "Password", Var: "APP_PASSWORD", Secret: true, Value: "***"}, report.Fields[3])
+	}
+
+	var cfg2 struct {
+		Port int `default:"abc"`
+	}
+	_, err = l.LoadReport(&cfg2)
+	assert.NotNil(t, err)
+}
+
+func TestLoader_SetFields(t *testing.T) {
+	var cfg struct {
+		Host  string
+		Port  int    `default:"80"`
+		Debug bool   `env:"DEBUG_MODE,alias=DEBUG"`
+		Dev   string `envs:"development"`
+	}
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "", "APP_DEBUG": "false"}), nil)
+	set, err := l.SetFields(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]bool{"Host": true, "Port": false, "Debug": true}, set)
+	}
+
+	_, err = SetFields(cfg)
+	assert.Equal(t, ErrStructPointer, err)
+}
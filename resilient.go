@@ -0,0 +1,148 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// ContextLookupFunc looks up a name like LookupFunc, but it can be canceled with ctx and reports the failures
+	// to look up the name, such as the outages of a remote store, as errors. The LookupContext methods of remote
+	// backends such as GCPSecretManager are ContextLookupFuncs.
+	ContextLookupFunc func(ctx context.Context, name string) (string, bool, error)
+
+	// RetryPolicy specifies how ResilientLookup retries failed lookups. The zero value retries twice with
+	// the default backoff, without a timeout nor a circuit breaker.
+	RetryPolicy struct {
+		// Attempts is the maximum number of attempts of a lookup, including the first one. It defaults to 3.
+		Attempts int
+		// Backoff is the time to wait before the second attempt, which is doubled before each further attempt up to
+		// MaxBackoff. It defaults to 100ms.
+		Backoff time.Duration
+		// MaxBackoff is the maximum time to wait between two attempts. It defaults to 5s.
+		MaxBackoff time.Duration
+		// Timeout is the maximum duration of an attempt, after which the context passed to the inner lookup function
+		// is canceled. Zero means no timeout.
+		Timeout time.Duration
+		// BreakerThreshold is the number of consecutive lookups failing after all their attempts that opens
+		// the circuit breaker. While the breaker is open, lookups fail immediately with ErrCircuitOpen instead of
+		// calling the inner lookup function. Zero disables the breaker.
+		BreakerThreshold int
+		// BreakerCooldown is how long the breaker stays open. The next lookup is then attempted, closing the breaker
+		// if it succeeds or opening it again if it fails. It defaults to 30s.
+		BreakerCooldown time.Duration
+		// OnError, if not nil, is called with the error of every lookup that fails after all its attempts or is
+		// rejected by the open breaker, as such lookups are treated as not found.
+		OnError func(name string, err error)
+	}
+
+	// breaker is a circuit breaker counting the consecutive failed lookups.
+	breaker struct {
+		mu       sync.Mutex
+		failures int
+		openTill time.Time
+	}
+)
+
+// ErrCircuitOpen is the error of the lookups rejected by the open circuit breaker of a ResilientLookup.
+var ErrCircuitOpen = errors.New("the circuit breaker of the lookup is open")
+
+// ResilientLookup returns a LookupFunc that looks up names with the given context-aware lookup function, retrying
+// the failed attempts with exponential backoff, so that transient outages of a secret store do not immediately
+// fail the startup of a service:
+//
+//	lookup := env.ResilientLookup(sm.LookupContext, env.RetryPolicy{Attempts: 5, Timeout: 2 * time.Second})
+//	loader := env.NewWithLookup("APP_", env.ChainLookup(os.LookupEnv, lookup), log.Printf)
+//
+// Names that are not found are not retried. A lookup that still fails after all its attempts is treated as not
+// found, and its error is passed to the OnError function of the policy, if any.
+func ResilientLookup(inner ContextLookupFunc, policy RetryPolicy) LookupFunc {
+	if policy.Attempts <= 0 {
+		policy.Attempts = 3
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 5 * time.Second
+	}
+	if policy.BreakerCooldown <= 0 {
+		policy.BreakerCooldown = 30 * time.Second
+	}
+	b := &breaker{}
+	return func(name string) (string, bool) {
+		value, ok, err := policy.lookup(inner, b, name)
+		if err != nil && policy.OnError != nil {
+			policy.OnError(name, err)
+		}
+		return value, ok
+	}
+}
+
+// lookup looks up a name with the inner lookup function according to the policy, recording the result in
+// the breaker.
+func (p RetryPolicy) lookup(inner ContextLookupFunc, b *breaker, name string) (string, bool, error) {
+	if p.BreakerThreshold > 0 && !b.allow() {
+		return "", false, fmt.Errorf("$%v: %w", name, ErrCircuitOpen)
+	}
+	backoff := p.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var (
+			value string
+			ok    bool
+		)
+		value, ok, err = p.attempt(inner, name)
+		if err == nil {
+			b.record(true, p)
+			return value, ok, nil
+		}
+		if attempt >= p.Attempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, p.MaxBackoff)
+	}
+	b.record(false, p)
+	return "", false, fmt.Errorf("$%v: lookup failed after %v attempts: %w", name, p.Attempts, err)
+}
+
+// attempt calls the inner lookup function once, with the timeout of the policy if any.
+func (p RetryPolicy) attempt(inner ContextLookupFunc, name string) (string, bool, error) {
+	ctx := context.Background()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	return inner(ctx, name)
+}
+
+// allow returns whether the breaker lets a lookup through, which is when it is not open.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !time.Now().Before(b.openTill)
+}
+
+// record records the success or failure of a lookup, opening the breaker when the consecutive failures reach
+// the threshold of the policy.
+func (b *breaker) record(success bool, p RetryPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if p.BreakerThreshold > 0 && b.failures >= p.BreakerThreshold {
+		b.openTill = time.Now().Add(p.BreakerCooldown)
+	}
+}
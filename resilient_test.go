@@ -0,0 +1,100 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResilientLookup(t *testing.T) {
+	calls := 0
+	failures := 2
+	inner := func(ctx context.Context, name string) (string, bool, error) {
+		calls++
+		if calls <= failures {
+			return "", false, errors.New("unavailable")
+		}
+		if name == "MISSING" {
+			return "", false, nil
+		}
+		return "value", true, nil
+	}
+	var errs []error
+	policy := RetryPolicy{Backoff: time.Millisecond, OnError: func(name string, err error) {
+		errs = append(errs, err)
+	}}
+	lookup := ResilientLookup(inner, policy)
+
+	value, ok := lookup("HOST")
+	assert.Equal(t, "value", value)
+	assert.True(t, ok)
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, errs)
+
+	calls = 0
+	_, ok = lookup("MISSING")
+	assert.False(t, ok)
+	assert.Equal(t, 3, calls)
+
+	calls, failures = 0, 10
+	_, ok = lookup("HOST")
+	assert.False(t, ok)
+	assert.Equal(t, 3, calls)
+	if assert.Len(t, errs, 1) {
+		assert.EqualError(t, errs[0], "$HOST: lookup failed after 3 attempts: unavailable")
+	}
+}
+
+func TestResilientLookup_timeout(t *testing.T) {
+	inner := func(ctx context.Context, name string) (string, bool, error) {
+		<-ctx.Done()
+		return "", false, ctx.Err()
+	}
+	var err error
+	lookup := ResilientLookup(inner, RetryPolicy{Attempts: 1, Timeout: time.Millisecond, OnError: func(name string, e error) {
+		err = e
+	}})
+	_, ok := lookup("HOST")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestResilientLookup_breaker(t *testing.T) {
+	calls := 0
+	fail := true
+	inner := func(ctx context.Context, name string) (string, bool, error) {
+		calls++
+		if fail {
+			return "", false, errors.New("unavailable")
+		}
+		return "value", true, nil
+	}
+	var err error
+	lookup := ResilientLookup(inner, RetryPolicy{Attempts: 1, BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond, OnError: func(name string, e error) {
+		err = e
+	}})
+	lookup("A")
+	lookup("B")
+	assert.Equal(t, 2, calls)
+
+	// the breaker is open
+	_, ok := lookup("C")
+	assert.False(t, ok)
+	assert.Equal(t, 2, calls)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// the breaker lets a lookup through after the cooldown
+	time.Sleep(30 * time.Millisecond)
+	fail = false
+	value, ok := lookup("C")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, 3, calls)
+}
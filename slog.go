@@ -0,0 +1,26 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// WithSlog specifies a structured logger that replaces the loader's LogFunc. The population of each field is logged
+// at the info level with the "field", "variable", "value" and "masked" attributes, where the value of a secret
+// field is masked. Reading a variable with a legacy prefix is logged at the warn level, and other messages are
+// logged at the info level. A nil logger disables logging.
+func WithSlog(logger *slog.Logger) Option {
+	return func(l *Loader) {
+		l.logger = logger
+		l.log = nil
+		if logger != nil {
+			l.log = func(format string, args ...interface{}) {
+				logger.Info(fmt.Sprintf(format, args...))
+			}
+		}
+	}
+}
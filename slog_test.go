@@ -0,0 +1,62 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSlog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	var cfg struct {
+		Host     string
+		Password string `env:",secret"`
+		Port     int
+	}
+	lookup := MapLookup(map[string]string{"APP_HOST": "localhost", "APP_PASSWORD": "xyz", "OLD_PORT": "80"})
+	logger2 := &myLogger{}
+	l := NewWithLookup("APP_", lookup, logger2.Log, WithSlog(logger), WithLegacyPrefix("OLD_"))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, `level=INFO msg="set field" field=Host variable=APP_HOST value=localhost masked=false
+level=INFO msg="set field" field=Password variable=APP_PASSWORD value=*** masked=true
+level=WARN msg="deprecated variable" variable=OLD_PORT replacement=APP_PORT
+level=INFO msg="set field" field=Port variable=OLD_PORT value=80 masked=false
+`, buf.String())
+		assert.Empty(t, logger2.logs)
+	}
+
+	buf.Reset()
+	flags := NewWithLookup("APP_", MapLookup(map[string]string{"APP_FF_BETA": "true"}), nil, WithSlog(logger))
+	_, err := flags.LoadFlags(map[string]bool{"BETA": false})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "level=INFO msg=\"set flag BETA to true\"\n", buf.String())
+	}
+
+	buf.Reset()
+	l = NewWithLookup("APP_", lookup, nil, WithSlog(logger), WithLogMode(LogSummary))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "level=INFO msg=\"loaded configuration\" loaded=2 defaulted=0 missing=1\n", buf.String())
+	}
+
+	buf.Reset()
+	l = NewWithLookup("APP_", lookup, logger2.Log, WithSlog(nil))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Empty(t, buf.String())
+		assert.Empty(t, logger2.logs)
+	}
+}
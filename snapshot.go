@@ -0,0 +1,50 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Snapshot returns a copy of the process environment as "NAME=VALUE" strings, which Restore sets back. Together they
+// make it safe to run tests or tools that change many environment variables:
+//
+//	defer env.Restore(env.Snapshot())
+func Snapshot() []string {
+	return os.Environ()
+}
+
+// Restore replaces the process environment with the given "NAME=VALUE" strings, such as those returned by Snapshot:
+// the variables not in the list are unset, and the others are set to their values if they differ. It returns the
+// errors of setting and unsetting the variables, if any.
+func Restore(snapshot []string) error {
+	want := environMap(snapshot)
+	var errs []error
+	for name := range environMap(os.Environ()) {
+		if _, ok := want[name]; !ok {
+			errs = append(errs, os.Unsetenv(name))
+		}
+	}
+	for name, value := range want {
+		if current, ok := os.LookupEnv(name); !ok || current != value {
+			errs = append(errs, os.Setenv(name, value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// environMap returns a map of the given "NAME=VALUE" strings keyed by name. The first character of a name may be
+// "=", as for the variables holding the current directories of drives on Windows.
+func environMap(environ []string) map[string]string {
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv[min(len(kv), 1):], '='); i >= 0 {
+			vars[kv[:i+1]] = kv[i+2:]
+		}
+	}
+	return vars
+}
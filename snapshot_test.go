@@ -0,0 +1,34 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Setenv("APP_SNAPSHOT_HOST", "localhost")
+	t.Setenv("APP_SNAPSHOT_PORT", "80")
+	snapshot := Snapshot()
+
+	_ = os.Setenv("APP_SNAPSHOT_HOST", "example.com")
+	_ = os.Unsetenv("APP_SNAPSHOT_PORT")
+	_ = os.Setenv("APP_SNAPSHOT_NEW", "x")
+	defer os.Unsetenv("APP_SNAPSHOT_NEW")
+
+	assert.Nil(t, Restore(snapshot))
+	assert.Equal(t, "localhost", os.Getenv("APP_SNAPSHOT_HOST"))
+	assert.Equal(t, "80", os.Getenv("APP_SNAPSHOT_PORT"))
+	_, ok := os.LookupEnv("APP_SNAPSHOT_NEW")
+	assert.False(t, ok)
+	assert.ElementsMatch(t, snapshot, os.Environ())
+}
+
+func Test_environMap(t *testing.T) {
+	assert.Equal(t, map[string]string{"A": "1", "B": "x=y", "C": "", "=C:": `C:\`}, environMap([]string{"A=1", "B=x=y", "C=", "=C:=C:\\", "D", ""}))
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"strings"
+)
+
+type (
+	// Key identifies a variable by the canonical path of the field it populates, independently of the naming
+	// convention of the source it is read from.
+	Key struct {
+		// Prefix is the prefix of the loader reading the variable (e.g. "APP_").
+		Prefix string
+		// Path lists the segments of the variable name without the prefix: the "prefix" tags of the nested structs
+		// containing the field, without their trailing underscores, followed by the name of the field's variable.
+		// For example, the path of the Host field of a struct nested with `prefix:"DB_"` is ["DB", "HOST"].
+		Path []string
+	}
+
+	// Source is a lookup function that names variables according to its own convention. Sources let a single struct
+	// be read from backends with different naming rules, such as environment variables (APP_DB_HOST),
+	// Consul keys (app/db/host) and Vault secret keys (db_host).
+	Source struct {
+		// Label identifies the source in load reports (see FieldReport.Source), e.g. "env" or a file name.
+		Label string
+		// Lookup looks up the names returned by Name.
+		Lookup LookupFunc
+		// Name translates the key of a variable into the name looked up in the source.
+		// If nil, the name of the variable as read by the loader's lookup function is used.
+		Name func(key Key) string
+	}
+)
+
+// WithSources specifies additional sources from which variables are read when the loader's lookup function
+// does not find them. The sources are queried in the given order, and each translates the key of a variable
+// with its own Name function, for example:
+//
+//	env.New("APP_", log.Printf, env.WithSources(
+//		env.Source{Lookup: consul.Lookup, Name: func(k env.Key) string { return k.Join("/") }},
+//		env.Source{Lookup: vault.Lookup, Name: func(k env.Key) string { return strings.ToLower(strings.Join(k.Path, "_")) }},
+//	))
+func WithSources(sources ...Source) Option {
+	return func(l *Loader) {
+		l.sources = append(l.sources, sources...)
+	}
+}
+
+// WithLookupLabel specifies the label identifying the loader's lookup function in load reports (see FieldReport.Source)
+// and traces (see WithTrace), such as "vault" or ".env". It defaults to "env" for the loaders created by New, which
+// read the process environment, and to "lookup" for those created by NewWithLookup. To tell apart the sources of
+// chained lookups, use Layer instead of ChainLookup.
+func WithLookupLabel(label string) Option {
+	return func(l *Loader) {
+		l.lookupLabel = label
+	}
+}
+
+// String returns the prefix followed by the segments of the path joined with underscores (e.g. "APP_DB_HOST").
+// This is the name read by the loader's lookup function, unless a "prefix" tag of the path has no trailing
+// underscore (e.g. `prefix:"DB"`, which makes the loader read APP_DBHOST).
+func (k Key) String() string {
+	return k.Prefix + strings.Join(k.Path, "_")
+}
+
+// Join returns the lower-case segments of the key, starting with the prefix without its trailing underscore,
+// joined with the given separator. For example, Join("/") returns "app/db/host" for the variable APP_DB_HOST.
+func (k Key) Join(sep string) string {
+	segments := k.Path
+	if prefix := strings.TrimSuffix(k.Prefix, "_"); prefix != "" {
+		segments = append([]string{prefix}, segments...)
+	}
+	return strings.ToLower(strings.Join(segments, sep))
+}
+
+// lookupSources looks up a variable in the loader's sources, in order: by the given name, which is the one read by
+// the loader's lookup function, in the sources without a Name function, and by the name translated from the key in
+// the others. It returns the label of the source the variable was found in.
+func (l *Loader) lookupSources(name string, key Key) (string, string, bool) {
+	for _, source := range l.sources {
+		sourceName := name
+		if source.Name != nil {
+			sourceName = source.Name(key)
+		}
+		if value, ok := source.Lookup(sourceName); ok {
+			return value, source.Label, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		tag  string
+		key  Key
+		name string
+		path string
+	}{
+		{"t1", Key{"APP_", []string{"DB", "HOST"}}, "APP_DB_HOST", "app/db/host"},
+		{"t2", Key{"", []string{"MAX_CONNS"}}, "MAX_CONNS", "max_conns"},
+		{"t3", Key{"APP", []string{"PORT"}}, "APPPORT", "app/port"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.name, test.key.String(), test.tag)
+		assert.Equal(t, test.path, test.key.Join("/"), test.tag)
+	}
+}
+
+func TestWithSources(t *testing.T) {
+	type config struct {
+		Host string
+		DB   struct {
+			Host     string
+			Port     int
+			Password string `env:",secret"`
+			User     string `env:"USER_NAME"`
+		} `prefix:"DB_"`
+	}
+
+	consul := MapLookup(map[string]string{
+		"app/host":    "consul",
+		"app/db/host": "db.consul",
+		"app/db/port": "5432",
+	})
+	vault := MapLookup(map[string]string{
+		"db_password":  "secret",
+		"db_user_name": "admin",
+	})
+	var keys []Key
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOST": "env"}), nil, WithSources(
+		Source{Lookup: consul, Name: func(k Key) string {
+			keys = append(keys, k)
+			return k.Join("/")
+		}},
+		Source{Lookup: vault, Name: func(k Key) string { return strings.ToLower(strings.Join(k.Path, "_")) }},
+	))
+
+	var cfg config
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "env", cfg.Host)
+		assert.Equal(t, "db.consul", cfg.DB.Host)
+		assert.Equal(t, 5432, cfg.DB.Port)
+		assert.Equal(t, "secret", cfg.DB.Password)
+		assert.Equal(t, "admin", cfg.DB.User)
+	}
+	assert.Equal(t, []Key{
+		{"APP_", []string{"DB", "HOST"}},
+		{"APP_", []string{"DB", "PORT"}},
+		{"APP_", []string{"DB", "PASSWORD"}},
+		{"APP_", []string{"DB", "USER_NAME"}},
+	}, keys)
+
+	// sources without a Name function look up the variable names
+	l = NewWithLookup("APP_", MapLookup(nil), nil, WithSources(Source{Lookup: MapLookup(map[string]string{"APP_DB_PORT": "3306"})}))
+	cfg = config{}
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, 3306, cfg.DB.Port)
+	}
+
+	// sources are queried with the legacy prefix as well
+	l = NewWithLookup("NEW_", MapLookup(nil), nil, WithLegacyPrefix("APP_"), WithSources(Source{Lookup: consul, Name: func(k Key) string {
+		return k.Join("/")
+	}}))
+	cfg = config{}
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "consul", cfg.Host)
+	}
+}
+
+func TestWithSources_prefixWithoutUnderscore(t *testing.T) {
+	type config struct {
+		DB struct {
+			Host string
+		} `prefix:"DB"`
+		Port int
+	}
+	vars := map[string]string{"APP_DBHOST": "h", "APP_PORT": "1"}
+	expected := config{Port: 1}
+	expected.DB.Host = "h"
+
+	var cfg config
+	assert.Nil(t, NewWithLookup("APP_", MapLookup(vars), nil).Load(&cfg))
+	assert.Equal(t, expected, cfg)
+
+	cfg = config{}
+	assert.Nil(t, NewWithLookup("APP_", MapLookup(nil), nil, WithSources(Source{Lookup: MapLookup(vars)})).Load(&cfg))
+	assert.Equal(t, expected, cfg)
+
+	cfg = config{}
+	assert.Nil(t, NewWithLookup("APP_", MapLookup(nil), nil, Layer(MapSource("map", vars))).Load(&cfg))
+	assert.Equal(t, expected, cfg)
+}
+
+func TestWithLookupLabel(t *testing.T) {
+	type config struct {
+		Host     string
+		Port     int
+		Mode     string `default:"dev"`
+		Password string `env:",secret"`
+		Token    string
+	}
+	t.Setenv("APP_HOST", "localhost")
+	l := New("APP_", nil,
+		WithSecretLookup(MapLookup(map[string]string{"APP_PASSWORD": "xyz"})),
+		WithSources(MapSource("vault", map[string]string{"APP_TOKEN": "abc"})))
+	var cfg config
+	report, err := l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		var sources []string
+		for _, f := range report.Fields {
+			sources = append(sources, f.Source)
+		}
+		assert.Equal(t, []string{"env", "", "default", "secret", "vault"}, sources)
+	}
+
+	l = NewWithLookup("APP_", MapLookup(map[string]string{"APP_PORT": "80"}), nil, WithLookupLabel(".env"))
+	report, err = l.LoadReport(&cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, ".env", report.Fields[1].Source)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Stats describes the activity of a Loader since it was created.
+	Stats struct {
+		// Loads is the number of structs loaded, including those that failed to load.
+		Loads uint64
+		// Errors is the number of loads that failed.
+		Errors uint64
+		// FieldsSet is the total number of fields set from variables.
+		FieldsSet uint64
+		// FieldsDefaulted is the total number of fields left at their defaults because their variables were not set.
+		FieldsDefaulted uint64
+		// FieldsMissing is the number of the fields counted in FieldsDefaulted that have no "default" tag either,
+		// so that they kept the values they had before loading.
+		FieldsMissing uint64
+		// Reloads is the number of reloads performed by the watchers of the loader (see Watch and WatchFiles).
+		Reloads uint64
+		// ReloadErrors is the number of reloads that failed, keeping the current configuration.
+		ReloadErrors uint64
+		// CacheHits is the number of lookups served from a cache, as reported by the lookups given to
+		// WithStatsReporters, such as LookupCache.
+		CacheHits uint64
+		// LookupErrors is the number of lookups that failed to read a remote store, as reported by the lookups given
+		// to WithStatsReporters, such as Consul. Such failures are otherwise indistinguishable from unset variables.
+		LookupErrors uint64
+		// ConfigHash is a hash of the values of the most recently loaded struct, as returned by Dump with the values
+		// of secret fields masked by the redactor of the loader (see WithRedactor), so that publishing the hash,
+		// such as with Expvar, does not expose secrets to brute-force attacks. Replicas loading the same struct type
+		// compare their hashes to detect configuration drift, which includes changes of secrets only with a
+		// redactor keeping a trace of the values, such as RedactHash. It is computed only by loaders created with
+		// WithConfigHash, as it costs a Dump on every load, and it is empty otherwise or if no struct has been
+		// loaded successfully.
+		ConfigHash string
+		// LastLoad is the time of the most recent load. It is zero if no struct has been loaded.
+		LastLoad time.Time
+		// LastError is the error returned by the most recent failed load, or nil if no load has failed.
+		LastError error
+	}
+
+	// StatsReporter is implemented by the lookups that count their activity, such as LookupCache and the lookups of
+	// remote stores, so that it is included in the statistics of the loaders using them (see WithStatsReporters).
+	StatsReporter interface {
+		// LookupStats returns the number of lookups served from a cache and the number of lookups that failed
+		// since the lookup was created.
+		LookupStats() (cacheHits, errors uint64)
+	}
+
+	// loaderStats collects the statistics of a Loader. It is shared by the copies of the Loader used internally.
+	loaderStats struct {
+		mu    sync.Mutex
+		stats Stats
+	}
+)
+
+// WithConfigHash makes a loader hash the values of every struct it loads successfully, as reported by
+// Stats.ConfigHash, so that replicas can detect configuration drift.
+func WithConfigHash() Option {
+	return func(l *Loader) {
+		l.hashConfig = true
+	}
+}
+
+// WithStatsReporters makes the statistics of a loader include the cache hits and lookup errors counted by the given
+// lookups (see Stats.CacheHits and Stats.LookupErrors), which are typically those the loader looks variables up with.
+// Lookup functions cannot be inspected, so the lookups must be given explicitly:
+//
+//	cache := env.NewLookupCache(consul.Lookup, time.Minute)
+//	loader := env.NewWithLookup("APP_", cache.Lookup, log.Printf, env.WithStatsReporters(cache, consul))
+func WithStatsReporters(reporters ...StatsReporter) Option {
+	return func(l *Loader) {
+		l.statsReporters = append(l.statsReporters, reporters...)
+	}
+}
+
+// Stats returns the statistics of the loader, so that long-running services can include the health of their
+// configuration in their own diagnostics. Loads performed by Watch are included.
+func (l *Loader) Stats() Stats {
+	l.stats.mu.Lock()
+	stats := l.stats.stats
+	l.stats.mu.Unlock()
+	for _, r := range l.statsReporters {
+		hits, errors := r.LookupStats()
+		stats.CacheHits += hits
+		stats.LookupErrors += errors
+	}
+	return stats
+}
+
+// record records the result of a load, along with the hash of the loaded struct if any.
+func (s *loaderStats) record(report *Report, hash string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Loads++
+	s.stats.LastLoad = time.Now()
+	if err != nil {
+		s.stats.Errors++
+		s.stats.LastError = err
+	}
+	if report != nil {
+		for _, f := range report.Fields {
+			if f.Overridden {
+				s.stats.FieldsSet++
+				continue
+			}
+			s.stats.FieldsDefaulted++
+			if !f.DefaultUsed {
+				s.stats.FieldsMissing++
+			}
+		}
+	}
+	if hash != "" {
+		s.stats.ConfigHash = hash
+	}
+}
+
+// recordReload records the result of a reload performed by a watcher.
+func (s *loaderStats) recordReload(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Reloads++
+	if err != nil {
+		s.stats.ReloadErrors++
+	}
+}
+
+// configHash returns the hex-encoded SHA-256 hash of the variables that would populate a struct with its current
+// values, with the values of secret fields masked, or an empty string if they cannot be determined, such as for map
+// targets.
+func (l *Loader) configHash(structPtr interface{}) string {
+	vars, err := l.Dump(structPtr)
+	if err != nil {
+		return ""
+	}
+	fields, err := l.getFields(reflect.TypeOf(structPtr).Elem())
+	if err != nil {
+		return ""
+	}
+	for _, f := range fields {
+		if !f.secret {
+			continue
+		}
+		for name, value := range vars {
+			if name == l.prefix+f.name || f.collect && strings.HasPrefix(name, l.prefix+f.name) {
+				vars[name] = l.redact(name, value)
+			}
+		}
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		// the lengths delimit the names and values unambiguously
+		_, _ = fmt.Fprintf(h, "%d:%v=%d:%v\n", len(name), name, len(vars[name]), vars[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
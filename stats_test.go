@@ -0,0 +1,142 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Stats(t *testing.T) {
+	l := NewWithLookup("", mockLookup, nil)
+	assert.Equal(t, Stats{}, l.Stats())
+
+	var cfg Config1
+	assert.Nil(t, l.Load(&cfg))
+	var cfg2 Config2
+	assert.Nil(t, l.Load(&cfg2))
+	assert.NotNil(t, l.Load(cfg))
+
+	stats := l.Stats()
+	assert.Equal(t, uint64(3), stats.Loads)
+	assert.Equal(t, uint64(1), stats.Errors)
+	assert.Equal(t, uint64(6), stats.FieldsSet)
+	assert.Equal(t, uint64(0), stats.FieldsDefaulted)
+	assert.Equal(t, ErrStructPointer, stats.LastError)
+	assert.False(t, stats.LastLoad.IsZero())
+	assert.Empty(t, stats.ConfigHash)
+
+	l = NewWithLookup("", MapLookup(map[string]string{"HOST": "localhost"}), nil, WithConfigHash())
+	assert.Nil(t, l.Load(&cfg))
+	stats = l.Stats()
+	assert.Equal(t, uint64(1), stats.FieldsSet)
+	assert.Equal(t, uint64(3), stats.FieldsDefaulted)
+	assert.Equal(t, uint64(3), stats.FieldsMissing)
+	assert.Len(t, stats.ConfigHash, 64)
+
+	// the hash depends only on the values of the loaded struct
+	hash := stats.ConfigHash
+	assert.Nil(t, l.Load(&cfg))
+	assert.Equal(t, hash, l.Stats().ConfigHash)
+	l2 := NewWithLookup("", MapLookup(map[string]string{"HOST": "remote"}), nil, WithConfigHash())
+	assert.Nil(t, l2.Load(&cfg))
+	assert.NotEqual(t, hash, l2.Stats().ConfigHash)
+
+	var defaulted struct {
+		Name string `default:"app"`
+	}
+	assert.Nil(t, l.Load(&defaulted))
+	stats = l.Stats()
+	assert.Equal(t, uint64(7), stats.FieldsDefaulted)
+	assert.Equal(t, uint64(6), stats.FieldsMissing)
+}
+
+// countingReporter is a StatsReporter returning fixed counts.
+type countingReporter struct {
+	hits, errors uint64
+}
+
+func (r countingReporter) LookupStats() (cacheHits, errors uint64) {
+	return r.hits, r.errors
+}
+
+func TestWithStatsReporters(t *testing.T) {
+	cache := NewLookupCache(MapLookup(map[string]string{"HOST": "localhost"}), time.Minute)
+	l := NewWithLookup("", cache.Lookup, nil, WithStatsReporters(cache), WithStatsReporters(countingReporter{1, 2}))
+	var cfg struct {
+		Host string
+	}
+	assert.Nil(t, l.Load(&cfg))
+	stats := l.Stats()
+	assert.Equal(t, uint64(1), stats.CacheHits)
+	assert.Equal(t, uint64(2), stats.LookupErrors)
+
+	// the second load is served from the cache
+	assert.Nil(t, l.Load(&cfg))
+	stats = l.Stats()
+	assert.Equal(t, uint64(2), stats.CacheHits)
+	assert.Equal(t, uint64(2), stats.LookupErrors)
+
+	assert.Zero(t, NewWithLookup("", cache.Lookup, nil).Stats().CacheHits)
+}
+
+func TestLoader_Stats_configHashSecrets(t *testing.T) {
+	type config struct {
+		Host     string
+		Password string            `env:",secret"`
+		Tokens   map[string]string `env:",prefix=TOKEN_,secret"`
+	}
+	hash := func(password string, opts ...Option) string {
+		vars := map[string]string{"APP_HOST": "localhost", "APP_PASSWORD": password}
+		l := NewWithLookup("APP_", MapLookup(vars), nil, append(opts, WithConfigHash())...)
+		var cfg config
+		assert.Nil(t, l.Load(&cfg))
+		return l.Stats().ConfigHash
+	}
+
+	// the hash of masked secrets does not depend on them
+	assert.Equal(t, hash("s3cr3t"), hash("other"))
+	assert.NotEqual(t, hash("s3cr3t"), hash("s3cr3t", WithRedactor(RedactKeepLast(2))))
+	assert.NotEqual(t, hash("s3cr3t", WithRedactor(RedactHash)), hash("other", WithRedactor(RedactHash)))
+
+	// the secrets of collected maps are masked as well
+	l := NewWithLookup("APP_", MapLookup(nil), nil, WithConfigHash())
+	t.Setenv("APP_TOKEN_A", "s3cr3t")
+	var cfg config
+	assert.Nil(t, l.Load(&cfg))
+	first := l.Stats().ConfigHash
+	t.Setenv("APP_TOKEN_A", "other")
+	assert.Nil(t, l.Load(&cfg))
+	assert.Equal(t, first, l.Stats().ConfigHash)
+}
+
+func TestLoader_Stats_reloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	port := make(chan string, 1)
+	port <- "80"
+	current := "80"
+	l := NewWithLookup("APP_", func(name string) (string, bool) {
+		select {
+		case current = <-port:
+		default:
+		}
+		return current, name == "APP_PORT"
+	}, nil)
+	var cfg struct{ Port int }
+	w, err := l.Watch(ctx, &cfg, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	port <- "x"
+	change := <-w.Events()
+	assert.NotNil(t, change.Err)
+	stats := l.Stats()
+	assert.GreaterOrEqual(t, stats.Reloads, uint64(1))
+	assert.GreaterOrEqual(t, stats.ReloadErrors, uint64(1))
+}
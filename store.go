@@ -0,0 +1,68 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// Store holds a value, typically a pointer to a configuration struct, that many goroutines can read while it is
+// replaced atomically, without locks of their own. The zero value holds the zero value of T.
+//
+// Watchers keep their current copy of a struct in a Store, and NewWatchedStore creates a Store that they keep
+// up to date:
+//
+//	w, err := loader.Watch(ctx, &Config{}, time.Minute)
+//	...
+//	store, err := env.NewWatchedStore[*Config](w)
+//	...
+//	port := store.Get().Port
+type Store[T any] struct {
+	value atomic.Pointer[T]
+}
+
+// NewStore creates a Store holding the given value.
+func NewStore[T any](value T) *Store[T] {
+	s := &Store[T]{}
+	s.value.Store(&value)
+	return s
+}
+
+// Get returns the value most recently stored.
+func (s *Store[T]) Get() T {
+	if p := s.value.Load(); p != nil {
+		return *p
+	}
+	var zero T
+	return zero
+}
+
+// Swap replaces the value held by the store and returns the previous one.
+func (s *Store[T]) Swap(value T) T {
+	if p := s.value.Swap(&value); p != nil {
+		return *p
+	}
+	var zero T
+	return zero
+}
+
+// NewWatchedStore creates a Store holding the current copy of the struct watched by w, which replaces it whenever
+// a reload changes any field, before calling the OnChange callbacks. T must be the type of the pointers to the
+// struct returned by Watcher.Current (e.g. *Config). The stored struct is shared and must not be modified.
+func NewWatchedStore[T any](w *Watcher) (*Store[T], error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	current, ok := w.current.Get().Interface().(T)
+	if !ok {
+		return nil, fmt.Errorf("a store of %v cannot hold the watched %v", reflect.TypeOf((*T)(nil)).Elem(), w.current.Get().Type())
+	}
+	s := NewStore(current)
+	w.stores = append(w.stores, func(v reflect.Value) {
+		s.Swap(v.Interface().(T))
+	})
+	return s, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	var s Store[int]
+	assert.Equal(t, 0, s.Get())
+	assert.Equal(t, 0, s.Swap(1))
+	assert.Equal(t, 1, s.Get())
+
+	s2 := NewStore("a")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s2.Swap("b")
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Contains(t, []string{"a", "b"}, s2.Get())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "b", s2.Get())
+}
+
+func TestNewWatchedStore(t *testing.T) {
+	var mu sync.Mutex
+	vars := map[string]string{"APP_PORT": "80"}
+	lookup := func(name string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := vars[name]
+		return value, ok
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type config struct {
+		Port int
+	}
+	w, err := NewWithLookup("APP_", lookup, nil).Watch(ctx, &config{}, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	store, err := NewWatchedStore[*config](w)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 80, store.Get().Port)
+
+	mu.Lock()
+	vars["APP_PORT"] = "8080"
+	mu.Unlock()
+	change := <-w.Events()
+	assert.Equal(t, change.Config, store.Get())
+	assert.Equal(t, 8080, store.Get().Port)
+
+	_, err = NewWatchedStore[config](w)
+	assert.EqualError(t, err, "a store of env.config cannot hold the watched *env.config")
+
+	// reloads do not wait for the events to be received
+	for _, port := range []string{"2", "3", "4"} {
+		mu.Lock()
+		vars["APP_PORT"] = port
+		mu.Unlock()
+		expected, _ := strconv.Atoi(port)
+		assert.Eventually(t, func() bool {
+			return store.Get().Port == expected
+		}, time.Second, time.Millisecond)
+	}
+	change = <-w.Events()
+	assert.Equal(t, 4, change.Config.(*config).Port)
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithStrict makes Load look for variables in the process environment that start with the loader prefix but do
+// not populate any field of the struct being loaded, which catches typos such as $APP_PROT instead of $APP_PORT.
+// If fail is true, Load returns an error listing such variables. Otherwise they are logged. The variables are
+// listed from the process environment unless specified otherwise with WithVariableNames.
+//
+// Variables of fields that are not loaded in the loader's environment, and feature flags (see LoadFlags), are not
+// reported. Strict mode requires a loader prefix, and is only meaningful if a single struct is loaded with it.
+func WithStrict(fail bool) Option {
+	return func(l *Loader) {
+		l.strict = true
+		l.strictFail = fail
+	}
+}
+
+// checkUnknown reports the variables under the loader prefix that do not populate any of the given fields,
+// according to the strict mode of the loader.
+func (l *Loader) checkUnknown(fields []*fieldInfo) error {
+	if !l.strict || l.prefix == "" {
+		return nil
+	}
+	names := l.unknownVars(fields)
+	if len(names) == 0 {
+		return nil
+	}
+	if l.strictFail {
+		return fmt.Errorf("unknown variables: $%v", strings.Join(names, ", $"))
+	}
+	if l.log != nil {
+		for _, name := range names {
+			l.log("unknown variable $%v", name)
+		}
+	}
+	return nil
+}
+
+// unknownVars returns the sorted names of the variables listed by the loader (see WithVariableNames) that start with
+// the loader prefix and do not populate any of the given fields.
+func (l *Loader) unknownVars(fields []*fieldInfo) []string {
+	// names are compared case-insensitively if the loader matches them so (see WithCaseInsensitive)
+	fold := func(name string) string { return name }
+	if l.foldedEnv != nil {
+		fold = strings.ToUpper
+	}
+	prefix := fold(l.prefix)
+	known := make(map[string]bool, len(fields))
+	// collected lists the prefixes of the variables populating the map fields tagged with the "prefix" option
+	// and the elements of slice and map fields
+	var collected []string
+	for _, f := range fields {
+		if f.collect {
+			collected = append(collected, fold(l.prefix+f.name))
+		} else {
+			known[fold(l.prefix+f.name)] = true
+		}
+		if f.indexed || f.keyed {
+			collected = append(collected, fold(l.prefix+f.name+"_"))
+		}
+		for _, alias := range f.alternatives() {
+			known[fold(l.prefix+alias)] = true
+		}
+		if pp := l.profilePrefix(); pp != "" && !f.collect {
+			known[fold(pp+f.name)] = true
+			for _, alias := range f.alternatives() {
+				known[fold(pp+alias)] = true
+			}
+		}
+	}
+	var names []string
+	for _, name := range l.environNames() {
+		if folded := fold(name); strings.HasPrefix(folded, prefix) && !strings.HasPrefix(folded, prefix+"FF_") && !known[folded] && !hasAnyPrefix(folded, collected) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasAnyPrefix returns whether a name starts with any of the given prefixes.
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithStrict(t *testing.T) {
+	type config struct {
+		Host string
+		Port int
+		URL  string `envs:"development"`
+	}
+	t.Setenv("STRICT_HOST", "localhost")
+	t.Setenv("STRICT_PROT", "8080")
+	t.Setenv("STRICT_URL", "http://example.com")
+	t.Setenv("STRICT_FF_BETA", "true")
+	t.Setenv("STRICT_NESTED_X", "x")
+
+	var cfg config
+	l := New("STRICT_", nil, WithStrict(true))
+	assert.EqualError(t, l.Load(&cfg), "unknown variables: $STRICT_NESTED_X, $STRICT_PROT")
+
+	var logger myLogger
+	l = New("STRICT_", logger.Log, WithStrict(false), WithLogMode(LogSummary))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "localhost", cfg.Host)
+		assert.Equal(t, []string{
+			"unknown variable $STRICT_NESTED_X",
+			"unknown variable $STRICT_PROT",
+			"loaded 1 fields, 0 defaulted, 1 missing",
+		}, logger.logs)
+	}
+
+	// strict mode is ignored without a prefix
+	assert.Nil(t, New("", nil, WithStrict(true)).Load(&cfg))
+	assert.Nil(t, New("STRICT_", nil).Load(&cfg))
+}
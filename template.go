@@ -0,0 +1,63 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// WithTemplates enables rendering variable values as text/templates before they are parsed.
+// Only values containing "{{" are rendered. Templates may use the following functions, plus those in funcs:
+//   - env NAME: the value of another variable, looked up with the loader's lookup function (without prefix)
+//   - file PATH: the content of a file, without trailing line breaks
+//   - b64dec VALUE: the base64-decoded value
+//
+// For example, `{{ file "/run/secrets/token" }}` loads a field from a mounted secret file.
+// The rendering is applied after the transformers added by WithTransformers before this option.
+func WithTemplates(funcs template.FuncMap) Option {
+	return func(l *Loader) {
+		l.transformers = append(l.transformers, TemplateTransformer(l.lookup, funcs))
+	}
+}
+
+// TemplateTransformer returns a Transformer that renders values containing "{{" as text/templates.
+// The "env" function of the templates uses the given lookup function. For more details, see WithTemplates.
+func TemplateTransformer(lookup LookupFunc, funcs template.FuncMap) Transformer {
+	fm := template.FuncMap{
+		"env": func(name string) string {
+			value, _ := lookup(name)
+			return value
+		},
+		"file": func(path string) (string, error) {
+			data, err := os.ReadFile(path)
+			return strings.TrimRight(string(data), "\r\n"), err
+		},
+		"b64dec": func(value string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(value)
+			return string(data), err
+		},
+	}
+	for name, fn := range funcs {
+		fm[name] = fn
+	}
+
+	return func(value string) (string, error) {
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+		t, err := template.New("value").Option("missingkey=error").Funcs(fm).Parse(value)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		if err := t.Execute(&sb, nil); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+}
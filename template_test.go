@@ -0,0 +1,59 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateTransformer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(file, []byte("s3cret\n"), 0600))
+	lookup := MapLookup(map[string]string{"HOST": "localhost"})
+	transform := TemplateTransformer(lookup, template.FuncMap{"upper": strings.ToUpper})
+
+	tests := []struct {
+		tag      string
+		value    string
+		expected string
+		err      bool
+	}{
+		{"t1", "plain", "plain", false},
+		{"t2", `http://{{ env "HOST" }}:80`, "http://localhost:80", false},
+		{"t3", `{{ env "MISSING" }}`, "", false},
+		{"t4", `{{ file "` + file + `" }}`, "s3cret", false},
+		{"t5", `{{ b64dec "eHl6" | upper }}`, "XYZ", false},
+		{"t6", `{{ file "` + file + `x" }}`, "", true},
+		{"t7", `{{ b64dec "!" }}`, "", true},
+		{"t8", `{{ exec "ls" }}`, "", true},
+		{"t9", `{{ .Field }}`, "", true},
+	}
+	for _, test := range tests {
+		value, err := transform(test.value)
+		if test.err {
+			assert.NotNil(t, err, test.tag)
+		} else if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, test.expected, value, test.tag)
+		}
+	}
+}
+
+func TestWithTemplates(t *testing.T) {
+	lookup := MapLookup(map[string]string{
+		"HOST": "localhost",
+		"URL":  `http://{{ env "HOST" }}`,
+	})
+	var cfg Config1
+	l := NewWithLookup("", lookup, nil, WithTemplates(nil))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "http://localhost", cfg.URL)
+	}
+}
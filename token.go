@@ -0,0 +1,96 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenFunc returns an OAuth 2.0 access token used to authenticate requests sent to a cloud secret store.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// tokenCache caches an access token until shortly before it expires.
+type tokenCache struct {
+	fetch  func(ctx context.Context) (string, time.Duration, error)
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newCachedToken returns a TokenFunc that caches the tokens returned by fetch until they are about to expire.
+func newCachedToken(fetch func(ctx context.Context) (string, time.Duration, error)) TokenFunc {
+	c := &tokenCache{fetch: fetch}
+	return c.get
+}
+
+// get returns the cached token, fetching a new one if the cached one is missing or about to expire.
+func (c *tokenCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+	token, ttl, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiry = token, time.Now().Add(ttl-time.Minute)
+	return token, nil
+}
+
+// requestToken sends a token request and returns the access token in the response and its lifetime.
+// If form is nil, a GET request is sent; otherwise the form is posted.
+func requestToken(ctx context.Context, client *http.Client, endpoint string, form url.Values, header http.Header) (string, time.Duration, error) {
+	method, body := http.MethodGet, ""
+	if form != nil {
+		method, body = http.MethodPost, form.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, strings.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		AccessToken      string      `json:"access_token"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil && res.StatusCode == http.StatusOK {
+		return "", 0, err
+	}
+	if res.StatusCode != http.StatusOK || result.AccessToken == "" {
+		if result.Error != "" {
+			return "", 0, fmt.Errorf("token request failed: %v: %v", result.Error, result.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("token request failed: %v", res.Status)
+	}
+
+	expiresIn, _ := strconv.Atoi(result.ExpiresIn.String())
+	if expiresIn <= 0 {
+		expiresIn = 3600
+	}
+	return result.AccessToken, time.Duration(expiresIn) * time.Second, nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_requestToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			_, _ = w.Write([]byte(`{"access_token":"t1","expires_in":"86399"}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	calls := 0
+	token := newCachedToken(func(ctx context.Context) (string, time.Duration, error) {
+		calls++
+		return requestToken(ctx, http.DefaultClient, server.URL+"/ok", nil, nil)
+	})
+	for i := 0; i < 2; i++ {
+		value, err := token(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, "t1", value)
+	}
+	assert.Equal(t, 1, calls)
+
+	_, _, err := requestToken(context.Background(), http.DefaultClient, server.URL+"/fail", nil, nil)
+	assert.EqualError(t, err, "token request failed: 500 Internal Server Error")
+}
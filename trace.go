@@ -0,0 +1,22 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+// WithTrace specifies a function that is called for every variable the loader attempts to read when populating the
+// fields of a struct, whether the variable is found or not, so that operators can see which variables were missing
+// and where the others came from. The variables of the active profile, aliases, deprecated names and names with
+// the legacy prefix are reported as they are attempted, each with its name including the prefix.
+//
+// The source is the label of the source the variable was found in, as in FieldReport.Source, which is empty if it
+// was not found:
+//
+//	env.New("APP_", log.Printf, env.WithTrace(func(name string, found bool, source string) {
+//		log.Printf("lookup $%v: found=%v source=%q", name, found, source)
+//	}))
+func WithTrace(trace func(name string, found bool, source string)) Option {
+	return func(l *Loader) {
+		l.trace = trace
+	}
+}
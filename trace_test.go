@@ -0,0 +1,35 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTrace(t *testing.T) {
+	type config struct {
+		Host string `env:"HOST,alias=HOSTNAME"`
+		Port int
+		Name string `default:"app"`
+	}
+	var traces []string
+	l := NewWithLookup("APP_", MapLookup(map[string]string{"APP_HOSTNAME": "localhost"}), nil,
+		WithSources(MapSource("file", map[string]string{"APP_PORT": "80"})),
+		WithTrace(func(name string, found bool, source string) {
+			traces = append(traces, fmt.Sprintf("%v %v %q", name, found, source))
+		}))
+	var cfg config
+	assert.Nil(t, l.Load(&cfg))
+	assert.Equal(t, config{Host: "localhost", Port: 80, Name: "app"}, cfg)
+	assert.Equal(t, []string{
+		`APP_HOST false ""`,
+		`APP_HOSTNAME true "lookup"`,
+		`APP_PORT true "file"`,
+		`APP_NAME false ""`,
+	}, traces)
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Transformer transforms a raw variable value before it is parsed and assigned to a struct field.
+// Transformers can be used for cross-cutting pre-processing, such as trimming, expansion, or decryption.
+type Transformer func(value string) (string, error)
+
+// TrimSpace is a Transformer that removes leading and trailing white space. It is registered as "trim".
+func TrimSpace(value string) (string, error) {
+	return strings.TrimSpace(value), nil
+}
+
+// builtinTransformers lists the named transformers available to every loader.
+var builtinTransformers = map[string]Transformer{
+	"trim": TrimSpace,
+}
+
+// WithTransformers appends transformers to the pipeline applied to every variable value, in the given order.
+func WithTransformers(transformers ...Transformer) Option {
+	return func(l *Loader) {
+		l.transformers = append(l.transformers, transformers...)
+	}
+}
+
+// WithNamedTransformer registers a transformer under the given name, so that it can be applied to individual fields
+// by listing the name in a "transform" tag, e.g. `transform:"trim,decrypt"`.
+func WithNamedTransformer(name string, transformer Transformer) Option {
+	return func(l *Loader) {
+		if l.namedTransformers == nil {
+			l.namedTransformers = map[string]Transformer{}
+		}
+		l.namedTransformers[name] = transformer
+	}
+}
+
+// transform applies the global transformers followed by the transformers named in the field's "transform" tag,
+// then expands the references to variables if the field is tagged with the "expand" option.
+func (l *Loader) transform(f *fieldInfo, value string) (string, error) {
+	var err error
+	for _, t := range l.transformers {
+		if value, err = t(value); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range f.transforms {
+		t, ok := l.namedTransformers[name]
+		if !ok {
+			if t, ok = builtinTransformers[name]; !ok {
+				return "", fmt.Errorf("unknown transformer %q", name)
+			}
+		}
+		if value, err = t(value); err != nil {
+			return "", fmt.Errorf("transformer %q: %w", name, err)
+		}
+	}
+	return l.expand(f, value), nil
+}
+
+// expand replaces the references to variables in a value of a field tagged with the "expand" option, such as
+// ${HOME} or $USER, with their values, looked up by their full names. Variables that are not set are replaced with
+// empty strings. Values of other fields are returned unchanged.
+func (l *Loader) expand(f *fieldInfo, value string) string {
+	if !f.expand {
+		return value
+	}
+	return os.Expand(value, func(name string) string {
+		if value, ok := l.lookup(name); ok {
+			return value
+		}
+		value, _ := l.lookupFolded(name)
+		return value
+	})
+}
+
+// defaultValue returns the value of the "default" tag of a field, expanded and decoded as the values of its
+// variable are.
+func (l *Loader) defaultValue(f *fieldInfo) (string, error) {
+	return f.decode(l.expand(f, f.def))
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimSpace(t *testing.T) {
+	value, err := TrimSpace("  abc \n")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", value)
+}
+
+func TestLoader_transform(t *testing.T) {
+	lookup := MapLookup(map[string]string{
+		"HOST": " Localhost ",
+		"PORT": " 8080 ",
+		"NAME": "enc:xyz",
+	})
+	upper := func(value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}
+	decrypt := func(value string) (string, error) {
+		if !strings.HasPrefix(value, "ENC:") {
+			return "", errors.New("not encrypted")
+		}
+		return strings.TrimPrefix(value, "ENC:"), nil
+	}
+
+	var cfg struct {
+		Host string `transform:"trim"`
+		Port int    `transform:"trim"`
+		Name string `transform:"decrypt"`
+	}
+	l := NewWithLookup("", lookup, nil, WithTransformers(upper), WithNamedTransformer("decrypt", decrypt))
+	if assert.Nil(t, l.Load(&cfg)) {
+		assert.Equal(t, "LOCALHOST", cfg.Host)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, "XYZ", cfg.Name)
+	}
+
+	l = NewWithLookup("", lookup, nil)
+	assert.EqualError(t, l.Load(&cfg), `$NAME: unknown transformer "decrypt"`)
+
+	l = NewWithLookup("", MapLookup(map[string]string{"NAME": "xyz"}), nil, WithNamedTransformer("decrypt", decrypt))
+	assert.EqualError(t, l.Load(&cfg), `$NAME: transformer "decrypt": not encrypted`)
+
+	l = NewWithLookup("", lookup, nil, WithTransformers(decrypt))
+	assert.EqualError(t, l.Load(&cfg), "$HOST: not encrypted")
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoadTree reads the variables under a prefix into a nested map using the package-level loader.
+// For more details, please refer to Loader.LoadTree().
+func LoadTree(prefix string) (map[string]interface{}, error) {
+	return loader.LoadTree(prefix)
+}
+
+// LoadTree reads every variable whose name starts with the loader prefix followed by the given prefix, and
+// reconstructs a nested map from the rest of the names, split at underscores and turned into lower case.
+// It is meant for dynamic configurations, such as those of plugins, for which no struct exists at compile time.
+// For example, with the loader prefix "APP_", LoadTree("PLUGINS_") reads APP_PLUGINS_CACHE_TTL=60 into:
+//
+//	map[string]interface{}{"cache": map[string]interface{}{"ttl": int64(60)}}
+//
+// The names of the variables are taken from the process environment, and their values are read with the loader's
+// lookup function and transformed by its global transformers. Values are typed on a best-effort basis: "true" and
+// "false" become booleans, integers become int64, other numbers become float64, JSON arrays and objects are decoded,
+// and anything else is kept as a string. An error is returned if a name is both a value and the parent of other
+// values, e.g. APP_PLUGINS_CACHE and APP_PLUGINS_CACHE_TTL.
+func (l *Loader) LoadTree(prefix string) (map[string]interface{}, error) {
+	prefix = l.prefix + prefix
+	names := l.collectNames(prefix)
+
+	tree := map[string]interface{}{}
+	// leaves maps the dot-separated paths of the values in the tree to their variable names
+	leaves := map[string]string{}
+	for _, name := range names {
+		value, ok := l.lookup(name)
+		if !ok {
+			continue
+		}
+		value, err := l.transform(&fieldInfo{}, value)
+		if err != nil {
+			return nil, fmt.Errorf("$%v: %w", name, err)
+		}
+
+		var path []string
+		for _, segment := range strings.Split(strings.ToLower(strings.TrimPrefix(name, prefix)), "_") {
+			if segment != "" {
+				path = append(path, segment)
+			}
+		}
+		if len(path) == 0 {
+			continue
+		}
+		node := tree
+		for i, segment := range path[:len(path)-1] {
+			if other, ok := leaves[strings.Join(path[:i+1], ".")]; ok {
+				return nil, fmt.Errorf("$%v conflicts with $%v", name, other)
+			}
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[segment] = child
+			}
+			node = child
+		}
+		key, leaf := path[len(path)-1], strings.Join(path, ".")
+		if other, ok := leaves[leaf]; ok {
+			return nil, fmt.Errorf("$%v conflicts with $%v", name, other)
+		}
+		if _, ok := node[key]; ok {
+			return nil, fmt.Errorf("$%v conflicts with the variables under it", name)
+		}
+		node[key] = treeValue(value)
+		leaves[leaf] = name
+		if l.log != nil && l.logMode == LogFields {
+			l.log("set %v with $%v", leaf, name)
+		}
+	}
+	return tree, nil
+}
+
+// treeValue converts a variable value into a boolean, a number, a decoded JSON array or object, or a string.
+func treeValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+		return v
+	}
+	if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+		var v interface{}
+		if json.Unmarshal([]byte(value), &v) == nil {
+			return v
+		}
+	}
+	return value
+}
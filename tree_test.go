@@ -0,0 +1,89 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_LoadTree(t *testing.T) {
+	t.Setenv("TREE_PLUGINS_CACHE_TTL", "60")
+	t.Setenv("TREE_PLUGINS_CACHE_ENABLED", "true")
+	t.Setenv("TREE_PLUGINS_CACHE_RATIO", "0.5")
+	t.Setenv("TREE_PLUGINS_REDIS_HOSTS", `["a","b"]`)
+	t.Setenv("TREE_PLUGINS_REDIS_URL", " redis://localhost ")
+	t.Setenv("TREE_PLUGINS_REDIS__DB", "NaN")
+	t.Setenv("TREE_OTHER", "x")
+
+	logger := &myLogger{}
+	l := New("TREE_", logger.Log, WithTransformers(TrimSpace))
+	tree, err := l.LoadTree("PLUGINS_")
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]interface{}{
+			"cache": map[string]interface{}{
+				"ttl":     int64(60),
+				"enabled": true,
+				"ratio":   0.5,
+			},
+			"redis": map[string]interface{}{
+				"hosts": []interface{}{"a", "b"},
+				"url":   "redis://localhost",
+				"db":    "NaN",
+			},
+		}, tree)
+		assert.Contains(t, logger.logs, "set cache.ttl with $TREE_PLUGINS_CACHE_TTL")
+	}
+
+	tree, err = l.LoadTree("MISSING_")
+	if assert.Nil(t, err) {
+		assert.Empty(t, tree)
+	}
+
+	t.Setenv("TREE_PLUGINS_CACHE", "on")
+	_, err = l.LoadTree("PLUGINS_")
+	assert.EqualError(t, err, "$TREE_PLUGINS_CACHE_ENABLED conflicts with $TREE_PLUGINS_CACHE")
+	os.Unsetenv("TREE_PLUGINS_CACHE")
+
+	t.Setenv("TREE_PLUGINS_redis", "x")
+	_, err = l.LoadTree("PLUGINS_")
+	assert.EqualError(t, err, "$TREE_PLUGINS_redis conflicts with the variables under it")
+	os.Unsetenv("TREE_PLUGINS_redis")
+
+	l = New("TREE_", nil, WithTransformers(func(value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}))
+	tree, err = l.LoadTree("OTHER")
+	if assert.Nil(t, err) {
+		assert.Empty(t, tree)
+	}
+	tree, err = l.LoadTree("")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "X", tree["other"])
+	}
+}
+
+func Test_treeValue(t *testing.T) {
+	tests := []struct {
+		tag      string
+		value    string
+		expected interface{}
+	}{
+		{"t1", "true", true},
+		{"t2", "TRUE", "TRUE"},
+		{"t3", "-12", int64(-12)},
+		{"t4", "1e3", float64(1000)},
+		{"t5", "Inf", "Inf"},
+		{"t6", `{"a":1}`, map[string]interface{}{"a": float64(1)}},
+		{"t7", "[1,", "[1,"},
+		{"t8", "", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, treeValue(test.value), test.tag)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+type (
+	// VaultConfig configures a Vault lookup.
+	VaultConfig struct {
+		// Address is the address of the Vault server. Defaults to $VAULT_ADDR.
+		Address string
+		// Namespace is the Vault Enterprise namespace. Defaults to $VAULT_NAMESPACE.
+		Namespace string
+		// Mount is the mount path of the KV version 2 secrets engine. Defaults to "secret".
+		Mount string
+		// Path is the path of the secret (relative to the mount) whose keys are looked up.
+		Path string
+		// Key maps a variable name to a key in the secret. Defaults to using the variable name as is.
+		Key func(name string) string
+		// Auth obtains a client token. Defaults to VaultToken with $VAULT_TOKEN.
+		Auth VaultAuth
+		// Client is the HTTP client used to talk to Vault. Defaults to http.DefaultClient.
+		Client *http.Client
+	}
+
+	// VaultAuth obtains a client token for the given Vault lookup.
+	VaultAuth func(v *Vault) (string, error)
+
+	// Vault looks up values from a secret stored in a HashiCorp Vault KV version 2 secrets engine.
+	Vault struct {
+		config VaultConfig
+		token  string
+		mu     sync.RWMutex
+		data   map[string]string
+	}
+)
+
+// DefaultVaultKubernetesTokenPath is the path of the service account token used by VaultKubernetes by default.
+const DefaultVaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// NewVault creates a Vault lookup. It authenticates against Vault and reads the configured secret,
+// so that any configuration or connectivity problem is reported immediately.
+//
+// Use the Lookup method as a LookupFunc, for example with WithSecretLookup:
+//
+//	vault, err := env.NewVault(env.VaultConfig{Path: "myapp"})
+//	...
+//	loader := env.New("APP_", log.Printf, env.WithSecretLookup(vault.Lookup))
+func NewVault(config VaultConfig) (*Vault, error) {
+	if config.Address == "" {
+		config.Address = os.Getenv("VAULT_ADDR")
+	}
+	if config.Address == "" {
+		return nil, errors.New("vault: address is not specified")
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	if config.Namespace == "" {
+		config.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if config.Mount == "" {
+		config.Mount = "secret"
+	}
+	if config.Auth == nil {
+		config.Auth = VaultToken(os.Getenv("VAULT_TOKEN"))
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	v := &Vault{config: config}
+	token, err := config.Auth(v)
+	if err != nil {
+		return nil, err
+	}
+	v.token = token
+	if err := v.Refresh(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// VaultToken authenticates with the given client token.
+func VaultToken(token string) VaultAuth {
+	return func(*Vault) (string, error) {
+		if token == "" {
+			return "", errors.New("vault: token is not specified")
+		}
+		return token, nil
+	}
+}
+
+// VaultAppRole authenticates with the AppRole auth method mounted at the given path (defaults to "approle").
+func VaultAppRole(mount, roleID, secretID string) VaultAuth {
+	if mount == "" {
+		mount = "approle"
+	}
+	return func(v *Vault) (string, error) {
+		return v.Login(mount, map[string]interface{}{"role_id": roleID, "secret_id": secretID})
+	}
+}
+
+// VaultKubernetes authenticates with the Kubernetes auth method mounted at the given path (defaults to "kubernetes").
+// The service account token is read from tokenPath, which defaults to DefaultVaultKubernetesTokenPath.
+func VaultKubernetes(mount, role, tokenPath string) VaultAuth {
+	if mount == "" {
+		mount = "kubernetes"
+	}
+	if tokenPath == "" {
+		tokenPath = DefaultVaultKubernetesTokenPath
+	}
+	return func(v *Vault) (string, error) {
+		jwt, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return "", fmt.Errorf("vault: %w", err)
+		}
+		return v.Login(mount, map[string]interface{}{"role": role, "jwt": strings.TrimSpace(string(jwt))})
+	}
+}
+
+// Login logs in using the auth method mounted at the given path and returns the client token.
+// It can be used to implement a VaultAuth for auth methods not provided by this package.
+func (v *Vault) Login(mount string, data map[string]interface{}) (string, error) {
+	var result struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := v.do(http.MethodPost, "auth/"+strings.Trim(mount, "/")+"/login", data, &result); err != nil {
+		return "", err
+	}
+	if result.Auth.ClientToken == "" {
+		return "", errors.New("vault: login did not return a client token")
+	}
+	return result.Auth.ClientToken, nil
+}
+
+// Refresh reads the secret from Vault again, replacing the values returned by Lookup.
+func (v *Vault) Refresh() error {
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	path := strings.Trim(v.config.Mount, "/") + "/data/" + strings.Trim(v.config.Path, "/")
+	if err := v.do(http.MethodGet, path, nil, &result); err != nil {
+		return err
+	}
+
+	data, err := stringValues(result.Data.Data)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+
+	v.mu.Lock()
+	v.data = data
+	v.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the value of the secret key corresponding to the given variable name.
+// It can be used as a LookupFunc.
+func (v *Vault) Lookup(name string) (string, bool) {
+	if v.config.Key != nil {
+		name = v.config.Key(name)
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.data[name]
+	return value, ok
+}
+
+// do sends a request to the Vault HTTP API and decodes the JSON response into result.
+func (v *Vault) do(method, path string, data interface{}, result interface{}) error {
+	var body io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("vault: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, v.config.Address+"/v1/"+path, body)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := v.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		if len(e.Errors) > 0 {
+			return fmt.Errorf("vault: %v %v: %v", method, path, strings.Join(e.Errors, "; "))
+		}
+		return fmt.Errorf("vault: %v %v: %v", method, path, res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login", "/v1/auth/kubernetes/login":
+			var data map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&data)
+			if data["secret_id"] == "s1" || data["jwt"] == "jwt1" {
+				_, _ = w.Write([]byte(`{"auth":{"client_token":"t1"}}`))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid credentials"]}`))
+		case "/v1/secret/data/myapp":
+			if r.Header.Get("X-Vault-Token") != "t1" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			assert.Equal(t, "ns1", r.Header.Get("X-Vault-Namespace"))
+			_, _ = w.Write([]byte(`{"data":{"data":{"APP_PASSWORD":"xyz","APP_PORT":8080},"metadata":{"version":1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestNewVault(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	v, err := NewVault(VaultConfig{Address: server.URL, Namespace: "ns1", Path: "myapp", Auth: VaultToken("t1")})
+	if assert.Nil(t, err) {
+		value, ok := v.Lookup("APP_PASSWORD")
+		assert.True(t, ok)
+		assert.Equal(t, "xyz", value)
+		value, ok = v.Lookup("APP_PORT")
+		assert.True(t, ok)
+		assert.Equal(t, "8080", value)
+		_, ok = v.Lookup("APP_HOST")
+		assert.False(t, ok)
+	}
+
+	v, err = NewVault(VaultConfig{Address: server.URL, Namespace: "ns1", Path: "myapp", Auth: VaultAppRole("", "r1", "s1")})
+	if assert.Nil(t, err) {
+		_, ok := v.Lookup("APP_PASSWORD")
+		assert.True(t, ok)
+	}
+	_, err = NewVault(VaultConfig{Address: server.URL, Path: "myapp", Auth: VaultAppRole("", "r1", "s2")})
+	assert.EqualError(t, err, "vault: POST auth/approle/login: invalid credentials")
+
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(jwtPath, []byte("jwt1\n"), 0600))
+	v, err = NewVault(VaultConfig{
+		Address:   server.URL,
+		Namespace: "ns1",
+		Path:      "myapp",
+		Key:       func(name string) string { return "APP_" + name },
+		Auth:      VaultKubernetes("", "myapp", jwtPath),
+	})
+	if assert.Nil(t, err) {
+		value, _ := v.Lookup("PASSWORD")
+		assert.Equal(t, "xyz", value)
+	}
+	_, err = NewVault(VaultConfig{Address: server.URL, Path: "myapp", Auth: VaultKubernetes("", "myapp", jwtPath+"x")})
+	assert.NotNil(t, err)
+
+	_, err = NewVault(VaultConfig{Address: server.URL, Path: "myapp", Auth: VaultToken("t2")})
+	assert.EqualError(t, err, "vault: GET secret/data/myapp: permission denied")
+	_, err = NewVault(VaultConfig{Address: server.URL, Path: "myapp", Auth: VaultToken("")})
+	assert.NotNil(t, err)
+	_, err = NewVault(VaultConfig{Address: "", Path: "myapp"})
+	assert.NotNil(t, err)
+}
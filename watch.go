@@ -0,0 +1,317 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// Watcher keeps a configuration struct up to date by loading it periodically or whenever files change.
+	// It is created by Loader.Watch or Loader.WatchFiles.
+	Watcher struct {
+		loader   Loader
+		log      LogFunc
+		template reflect.Value
+		fields   []*fieldInfo
+		refresh  func() error
+		current  Store[reflect.Value]
+		events   chan ConfigChange
+		stop     chan struct{}
+		stopOnce sync.Once
+		done     chan struct{}
+
+		// mu guards the replacement of the current copy, so that it cannot happen after the watcher is frozen,
+		// along with the stores created by NewWatchedStore, which are updated with it.
+		mu     sync.Mutex
+		frozen bool
+		report *Report
+		stores []func(reflect.Value)
+	}
+
+	// ConfigChange describes a reload of a watched configuration struct.
+	ConfigChange struct {
+		// Config is a pointer to the newly loaded copy of the struct. It is nil if the reload failed.
+		Config interface{}
+		// Changes lists the fields whose values changed. Left holds the old value and Right the new one.
+		Changes []FieldDiff
+		// Err is the error that occurred when reloading. The current configuration is kept in this case.
+		Err error
+	}
+
+	// changeHandlers holds the callbacks registered with Loader.OnChange, keyed by field path.
+	changeHandlers struct {
+		mu       sync.RWMutex
+		handlers map[string][]func(FieldDiff)
+	}
+)
+
+// OnChange registers a callback that is called when the value of the field with the given dot-separated path
+// (e.g. "Server.Port") changes between two reloads of a struct watched by Watch. The callback receives the
+// change and is called from the goroutine reloading the struct, before the change is sent to Watcher.Events.
+// Multiple callbacks can be registered for the same field, and they are called in the order they are registered.
+func (l *Loader) OnChange(path string, fn func(FieldDiff)) {
+	l.changeHandlers.mu.Lock()
+	defer l.changeHandlers.mu.Unlock()
+	if l.changeHandlers.handlers == nil {
+		l.changeHandlers.handlers = map[string][]func(FieldDiff){}
+	}
+	l.changeHandlers.handlers[path] = append(l.changeHandlers.handlers[path], fn)
+}
+
+// notify calls the callbacks registered for the fields of the given changes.
+func (h *changeHandlers) notify(changes []FieldDiff) {
+	for _, change := range changes {
+		h.mu.RLock()
+		handlers := h.handlers[change.Field]
+		h.mu.RUnlock()
+		for _, fn := range handlers {
+			fn(change)
+		}
+	}
+}
+
+// Watch loads a copy of the given struct and reloads it at the given interval until ctx is canceled.
+// The struct must be specified as a pointer. It is used as a template and is not modified: every reload starts
+// from a copy of it, so the values it holds act as defaults.
+//
+// After each reload, the new copy replaces the current one returned by Watcher.Current if any field changed,
+// the callbacks registered with OnChange for the changed fields are called, and a ConfigChange is sent
+// to the Watcher.Events channel. Failed reloads are sent as well.
+// Reloading never waits for the events to be received: an event that is still pending when the next one is sent
+// is dropped in favor of the latter, so a reader of the channel always receives the most recent change.
+//
+// An error is returned if the interval is not positive or the initial load fails.
+func (l *Loader) Watch(ctx context.Context, structPtr interface{}, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("the reload interval must be positive, got %v", interval)
+	}
+	w, err := l.newWatcher(structPtr, nil)
+	if err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(interval)
+	go w.run(ctx, ticker.C, ticker.Stop)
+	return w, nil
+}
+
+// newWatcher creates a watcher of the given struct and loads the initial copy. If refresh is not nil, it is called
+// before every load, and the load fails with its error.
+func (l *Loader) newWatcher(structPtr interface{}, refresh func() error) (*Watcher, error) {
+	value := reflect.ValueOf(structPtr)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrStructPointer
+	}
+
+	fields, err := l.getFields(value.Elem().Type())
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		loader:   *l,
+		log:      l.log,
+		template: cloneValue(value.Elem()),
+		fields:   fields,
+		refresh:  refresh,
+		events:   make(chan ConfigChange, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	config, report, err := w.load(&w.loader)
+	if err != nil {
+		return nil, err
+	}
+	w.current.Swap(config)
+	w.report = report
+	// only the initial load is logged, while reloads log the changes
+	w.loader.log = nil
+	return w, nil
+}
+
+// Current returns a pointer to the most recently loaded copy of the struct.
+// The returned struct is shared and must not be modified.
+func (w *Watcher) Current() interface{} {
+	return w.current.Get().Interface()
+}
+
+// Events returns the channel receiving the result of each reload that changed a field or failed. Events that are
+// not received before the next one are dropped (see Loader.Watch).
+// The channel is closed when the context passed to Loader.Watch or Loader.WatchFiles is canceled or the watcher
+// is frozen.
+func (w *Watcher) Events() <-chan ConfigChange {
+	return w.events
+}
+
+// Freeze stops reloading the struct and keeps the current copy for good, for example during a graceful shutdown,
+// so that requests being drained never observe a configuration change. It returns the report of the load that
+// produced the current copy.
+//
+// The current copy cannot be replaced once Freeze is called, even by a reload in progress. Freeze then waits until
+// the reloading goroutine exits, including any OnChange callbacks it is running, or until ctx is done, in which
+// case ctx.Err() is returned along with the report. Freeze can be called multiple times and from any goroutine,
+// such as one handling a termination signal.
+func (w *Watcher) Freeze(ctx context.Context) (*Report, error) {
+	w.mu.Lock()
+	w.frozen = true
+	report := w.report
+	w.mu.Unlock()
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	select {
+	case <-w.done:
+		return report, nil
+	case <-ctx.Done():
+		return report, ctx.Err()
+	}
+}
+
+// run reloads the struct whenever a value is received from reloads, until ctx is canceled or the watcher is frozen.
+// It calls release when it exits.
+func (w *Watcher) run(ctx context.Context, reloads <-chan time.Time, release func()) {
+	defer close(w.done)
+	defer close(w.events)
+	defer release()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-reloads:
+		}
+
+		var change ConfigChange
+		config, report, changes, err := w.reload(ctx)
+		if err != nil {
+			change.Err = err
+		} else {
+			change.Changes = changes
+			if len(change.Changes) == 0 {
+				continue
+			}
+			w.mu.Lock()
+			if w.frozen {
+				w.mu.Unlock()
+				return
+			}
+			w.current.Swap(config)
+			w.report = report
+			for _, store := range w.stores {
+				store(config)
+			}
+			w.mu.Unlock()
+			change.Config = config.Interface()
+			if w.log != nil {
+				for _, d := range change.Changes {
+					w.log("changed %v", d)
+				}
+			}
+			w.loader.changeHandlers.notify(change.Changes)
+		}
+
+		w.send(change)
+	}
+}
+
+// send sends an event without blocking, replacing the pending event if it has not been received yet. It is only
+// called by run, which is the only sender.
+func (w *Watcher) send(change ConfigChange) {
+	select {
+	case w.events <- change:
+		return
+	default:
+	}
+	select {
+	case <-w.events:
+	default:
+	}
+	w.events <- change
+}
+
+// reload loads a new copy of the template struct like load and returns the changes from the current copy as well.
+// The reload is recorded in the statistics of the loader, and a span of it is created if the loader is traced
+// (see WithTracerProvider).
+func (w *Watcher) reload(ctx context.Context) (reflect.Value, *Report, []FieldDiff, error) {
+	loader := w.loader
+	var span trace.Span
+	if loader.tracer != nil {
+		loader.spanContext, span = loader.tracer.Start(ctx, "env.Reload")
+	}
+	config, report, err := w.load(&loader)
+	w.loader.stats.recordReload(err)
+	var changes []FieldDiff
+	if err == nil {
+		changes = w.loader.diff(w.fields, w.current.Get().Elem(), config.Elem())
+	}
+	if span != nil {
+		span.SetAttributes(attribute.Int("env.changes", len(changes)))
+		endSpan(span, err)
+	}
+	return config, report, changes, err
+}
+
+// load loads a new copy of the template struct with the given loader and returns a pointer to it along with the load
+// report.
+func (w *Watcher) load(loader *Loader) (reflect.Value, *Report, error) {
+	if w.refresh != nil {
+		if err := w.refresh(); err != nil {
+			return reflect.Value{}, nil, err
+		}
+	}
+	config := reflect.New(w.template.Type())
+	config.Elem().Set(cloneValue(w.template))
+	report, err := loader.LoadReport(config.Interface())
+	return config, report, err
+}
+
+// cloneValue returns a deep copy of a value, so that loading the copy does not modify the original.
+// Pointers, slices, maps and exported struct fields are copied recursively.
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+		return c
+	}
+	return v
+}
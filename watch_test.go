@@ -0,0 +1,204 @@
+// Copyright 2019 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader_Watch(t *testing.T) {
+	var mu sync.Mutex
+	vars := map[string]string{"APP_HOST": "localhost", "APP_PASSWORD": "xyz"}
+	lookup := func(name string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := vars[name]
+		return value, ok
+	}
+	set := func(name, value string) {
+		mu.Lock()
+		defer mu.Unlock()
+		vars[name] = value
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type config struct {
+		Host     string
+		Port     int
+		Password string    `env:",secret"`
+		Nested   *Embedded `prefix:"NESTED_"`
+	}
+	template := config{Port: 80, Nested: &Embedded{Port: 90}}
+	w, err := NewWithLookup("APP_", lookup, nil).Watch(ctx, &template, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	cfg := w.Current().(*config)
+	assert.Equal(t, "localhost", cfg.Host)
+	assert.Equal(t, 80, cfg.Port)
+	assert.Equal(t, config{Port: 80, Nested: &Embedded{Port: 90}}, template)
+
+	set("APP_PORT", "8080")
+	set("APP_PASSWORD", "abc")
+	change := <-w.Events()
+	assert.Nil(t, change.Err)
+	assert.Equal(t, []FieldDiff{
+		{Field: "Port", Var: "APP_PORT", Left: "80", Right: "8080"},
+		{Field: "Password", Var: "APP_PASSWORD", Secret: true, Left: "***", Right: "***"},
+	}, change.Changes)
+	cfg = change.Config.(*config)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "abc", cfg.Password)
+	assert.Equal(t, 90, cfg.Nested.Port)
+	assert.Equal(t, 90, template.Nested.Port)
+	assert.Equal(t, cfg, w.Current())
+
+	set("APP_PORT", "x")
+	change = <-w.Events()
+	assert.NotNil(t, change.Err)
+	assert.Nil(t, change.Config)
+	assert.Equal(t, cfg, w.Current())
+
+	cancel()
+	for range w.Events() {
+	}
+
+	_, err = NewWithLookup("APP_", lookup, nil).Watch(ctx, &template, time.Millisecond)
+	assert.NotNil(t, err)
+	_, err = NewWithLookup("APP_", lookup, nil).Watch(ctx, template, time.Millisecond)
+	assert.Equal(t, ErrStructPointer, err)
+	_, err = NewWithLookup("APP_", lookup, nil).Watch(ctx, &template, 0)
+	assert.EqualError(t, err, "the reload interval must be positive, got 0s")
+}
+
+func TestLoader_OnChange(t *testing.T) {
+	var mu sync.Mutex
+	vars := map[string]string{"APP_NESTED_PORT": "80"}
+	lookup := func(name string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := vars[name]
+		return value, ok
+	}
+
+	var changes []string
+	l := NewWithLookup("APP_", lookup, nil)
+	l.OnChange("Nested.Port", func(d FieldDiff) {
+		changes = append(changes, "port "+d.Left+" "+d.Right)
+	})
+	l.OnChange("Nested.URL", func(d FieldDiff) {
+		changes = append(changes, "url "+d.Right)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := l.Watch(ctx, &Config4{}, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	l.OnChange("Nested.Port", func(d FieldDiff) {
+		changes = append(changes, "port2 "+d.Right)
+	})
+
+	mu.Lock()
+	vars["APP_NESTED_PORT"] = "8080"
+	mu.Unlock()
+	<-w.Events()
+	assert.Equal(t, []string{"port 80 8080", "port2 8080"}, changes)
+}
+
+func TestWatcher_Freeze(t *testing.T) {
+	var mu sync.Mutex
+	vars := map[string]string{"APP_HOST": "localhost"}
+	lookup := func(name string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := vars[name]
+		return value, ok
+	}
+	set := func(name, value string) {
+		mu.Lock()
+		defer mu.Unlock()
+		vars[name] = value
+	}
+
+	type config struct {
+		Host string
+		Port int
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := NewWithLookup("APP_", lookup, nil).Watch(ctx, &config{}, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	set("APP_PORT", "8080")
+	<-w.Events()
+
+	report, err := w.Freeze(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Host", "Port"}, report.Overridden())
+	cfg := w.Current().(*config)
+	assert.Equal(t, 8080, cfg.Port)
+
+	// the frozen copy is never replaced and the events channel is closed
+	set("APP_PORT", "9090")
+	time.Sleep(10 * time.Millisecond)
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+	assert.Same(t, cfg, w.Current())
+
+	report2, err := w.Freeze(context.Background())
+	assert.Nil(t, err)
+	assert.Same(t, report, report2)
+
+	// Freeze gives up waiting for a blocked callback when its context is done
+	l := NewWithLookup("APP_", lookup, nil)
+	release := make(chan struct{})
+	defer close(release)
+	l.OnChange("Port", func(FieldDiff) {
+		<-release
+	})
+	w, err = l.Watch(ctx, &config{}, time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	set("APP_PORT", "8080")
+	time.Sleep(10 * time.Millisecond)
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+	report, err = w.Freeze(timeout)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, []string{"Host", "Port"}, report.Overridden())
+	assert.Equal(t, 8080, w.Current().(*config).Port)
+}
+
+func Test_cloneValue(t *testing.T) {
+	type inner struct {
+		Tags []string
+	}
+	type config struct {
+		Nested *inner
+		Labels map[string]int
+		Nil    *inner
+		hidden *inner
+	}
+	hidden := &inner{}
+	v := config{Nested: &inner{Tags: []string{"a"}}, Labels: map[string]int{"a": 1}, hidden: hidden}
+	c := cloneValue(reflect.ValueOf(v)).Interface().(config)
+	assert.Equal(t, v, c)
+	c.Nested.Tags[0] = "b"
+	c.Labels["a"] = 2
+	assert.Equal(t, "a", v.Nested.Tags[0])
+	assert.Equal(t, 1, v.Labels["a"])
+	assert.True(t, c.hidden == hidden)
+}